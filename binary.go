@@ -0,0 +1,125 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding"
+	"encoding/binary"
+	"net"
+)
+
+const (
+	binaryMagic   = "NRDX"
+	binaryVersion = 1
+	// binaryException is set in key length of exception entries, which have no value part
+	binaryException = 0x80
+)
+
+// MarshalBinary encodes all entries of the tree, every value should be []byte, string or implement encoding.BinaryMarshaler.
+func (tree *Tree) MarshalBinary() ([]byte, error) {
+	return tree.MarshalBinaryWith(func(val interface{}) ([]byte, error) {
+		switch v := val.(type) {
+		case []byte:
+			return v, nil
+		case string:
+			return []byte(v), nil
+		case encoding.BinaryMarshaler:
+			return v.MarshalBinary()
+		}
+		return nil, ErrBadFormat
+	})
+}
+
+// MarshalBinaryWith encodes all entries of the tree using enc to encode values. Exception entries are encoded with a flag instead of value.
+func (tree *Tree) MarshalBinaryWith(enc func(val interface{}) ([]byte, error)) ([]byte, error) {
+	// Len counts entries walk skips, so header is written once all entries are encoded
+	var body []byte
+	var count uint64
+	err := tree.walklive(func(key net.IP, bits int, n *node) error {
+		if n.value == exclusion {
+			body = append(body, byte(len(key))|binaryException, byte(bits))
+			body = append(body, key[:(bits+7)/8]...)
+			count++
+			return nil
+		}
+		val, err := enc(n.value)
+		if err != nil {
+			return err
+		}
+		body = append(body, byte(len(key)), byte(bits))
+		body = append(body, key[:(bits+7)/8]...)
+		body = binary.AppendUvarint(body, uint64(len(val)))
+		body = append(body, val...)
+		count++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	buf := append([]byte(binaryMagic), binaryVersion)
+	buf = binary.AppendUvarint(buf, count)
+	return append(buf, body...), nil
+}
+
+// UnmarshalBinary replaces content of the tree with entries produced by MarshalBinary. Values are stored as []byte, use UnmarshalBinaryWith to decode them.
+func (tree *Tree) UnmarshalBinary(data []byte) error {
+	return tree.UnmarshalBinaryWith(data, func(val []byte) (interface{}, error) {
+		return append([]byte(nil), val...), nil
+	})
+}
+
+// UnmarshalBinaryWith replaces content of the tree with entries produced by MarshalBinary using dec to decode values.
+// Tree is left untouched if data could not be decoded, its settings and hooks are kept.
+func (tree *Tree) UnmarshalBinaryWith(data []byte, dec func(val []byte) (interface{}, error)) error {
+	if len(data) < len(binaryMagic)+1 || string(data[:len(binaryMagic)]) != binaryMagic || data[len(binaryMagic)] != binaryVersion {
+		return ErrBadFormat
+	}
+	data = data[len(binaryMagic)+1:]
+	count, n := binary.Uvarint(data)
+	if n <= 0 {
+		return ErrBadFormat
+	}
+	data = data[n:]
+
+	decoded := NewTree(0)
+	for ; count > 0; count-- {
+		if len(data) < 2 {
+			return ErrBadFormat
+		}
+		keylen, bits := int(data[0]&^binaryException), int(data[1])
+		exception := data[0]&binaryException != 0
+		if (keylen != net.IPv4len && keylen != net.IPv6len) || bits > keylen*8 || len(data) < 2+(bits+7)/8 {
+			return ErrBadFormat
+		}
+		key := make(net.IP, keylen)
+		copy(key, data[2:2+(bits+7)/8])
+		data = data[2+(bits+7)/8:]
+		var val interface{} = exclusion
+		if exception {
+			decoded.excludes = true
+		} else {
+			vallen, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < vallen {
+				return ErrBadFormat
+			}
+			var err error
+			if val, err = dec(data[n : n+int(vallen)]); err != nil {
+				return err
+			}
+			data = data[n+int(vallen):]
+		}
+
+		node := decoded.locate(key, bits)
+		if node.value != nil {
+			return ErrBadFormat
+		}
+		decoded.setvalue(node, val)
+	}
+	if len(data) != 0 {
+		return ErrBadFormat
+	}
+	tree.adopt(decoded)
+	return nil
+}
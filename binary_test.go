@@ -0,0 +1,145 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net/netip"
+	"strconv"
+	"testing"
+)
+
+func TestBinary(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "a")
+	tr.AddCIDR("10.1.0.0/16", "b")
+	tr.AddCIDR("10.1.2.3", "c")
+	tr.AddCIDR("dead::/16", "d")
+	tr.AddCIDR("0.0.0.0/0", "e")
+
+	data, err := tr.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Tree
+	if err = restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != tr.Len() {
+		t.Errorf("Wrong number of entries, expected %d, got %d", tr.Len(), restored.Len())
+	}
+	for ip, val := range map[string]string{"10.2.0.1": "a", "10.1.0.1": "b", "10.1.2.3": "c", "dead::1": "d", "11.0.0.1": "e"} {
+		inf, err := restored.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(inf.([]byte)) != val {
+			t.Errorf("Wrong value for %s, expected %s, got %s", ip, val, inf)
+		}
+	}
+	cidrs, restoredCIDRs := tr.ListCIDRs(), restored.ListCIDRs()
+	for i := range cidrs {
+		if cidrs[i] != restoredCIDRs[i] {
+			t.Errorf("Wrong CIDR, expected %s, got %s", cidrs[i], restoredCIDRs[i])
+		}
+	}
+
+	for i := range data {
+		if restored.UnmarshalBinary(data[:i]) == nil {
+			t.Errorf("Truncated data of %d bytes should not be accepted", i)
+		}
+	}
+	if restored.Len() != tr.Len() {
+		t.Error("Failed decoding should not modify the tree")
+	}
+
+	tr.AddCIDR("11.0.0.0/8", 1)
+	if _, err = tr.MarshalBinary(); err != ErrBadFormat {
+		t.Errorf("Should have gotten ErrBadFormat, instead got err: %v", err)
+	}
+}
+
+func TestBinaryWith(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("2001:db8::/32", 2)
+
+	data, err := tr.MarshalBinaryWith(func(val interface{}) ([]byte, error) {
+		return []byte(strconv.Itoa(val.(int))), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewTree(0)
+	err = restored.UnmarshalBinaryWith(data, func(val []byte) (interface{}, error) {
+		return strconv.Atoi(string(val))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inf, err := restored.FindCIDR("2001:db8::1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	inf, err = restored.FindCIDR("10.0.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+}
+
+func TestBinaryExclusion(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "a")
+	tr.AddCIDR("10.1.2.0/24", "b")
+	tr.ExcludeCIDR("10.1.0.0/16")
+
+	data, err := tr.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Tree
+	if err = restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 3 {
+		t.Errorf("Wrong number of entries, expected 3, got %d", restored.Len())
+	}
+	if inf, _ := restored.FindCIDR("10.1.2.3"); string(inf.([]byte)) != "b" {
+		t.Errorf("Wrong value, expected b, got %s", inf)
+	}
+	if inf, _ := restored.FindCIDR("10.1.0.1"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %s", inf)
+	}
+}
+
+func TestBinaryKeepsSettings(t *testing.T) {
+	src := NewTree(0)
+	src.AddCIDR("10.0.0.0/8", "a")
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTree(0)
+	tr.RejectHostBits(true)
+	var inserts int
+	tr.OnInsert(func(netip.Prefix, interface{}, interface{}) { inserts++ })
+	if err = tr.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.AddCIDR("10.1.2.3/24", "b"); !errors.Is(err, ErrHostBits) {
+		t.Errorf("Should have gotten ErrHostBits, instead got err: %v", err)
+	}
+	tr.AddCIDR("10.1.2.0/24", "b")
+	if inserts != 1 || tr.Len() != 2 {
+		t.Errorf("Hooks should be kept, got %d inserts and %d entries", inserts, tr.Len())
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"net"
+	"sort"
+	"sync"
+)
+
+type buildentry struct {
+	key   net.IP
+	bits  int
+	value interface{}
+}
+
+// BuildTree creates Tree holding all entries. Entries are sorted and split by their first bits into up to parallelism parts,
+// parts are built concurrently and then attached to the resulting tree. Returns ErrNodeBusy if some prefix is repeated.
+func BuildTree(entries []Entry, parallelism int) (*Tree, error) {
+	parsed := make([]buildentry, len(entries))
+	for i, e := range entries {
+		if e.Prefix == nil {
+			return nil, wrap("parse", []byte(e.Prefix.String()), ErrBadIP)
+		}
+		bits, size := e.Prefix.Mask.Size()
+		key := e.Prefix.IP.Mask(e.Prefix.Mask)
+		if key == nil || size == 0 {
+			return nil, wrap("parse", []byte(e.Prefix.String()), ErrBadIP)
+		}
+		if len(key) == net.IPv6len && bits >= 96 && key.To4() != nil {
+			// IPv4-mapped prefix goes to IPv4 root as key6 does for other inserts
+			key, bits = key[12:], bits-96
+		}
+		parsed[i] = buildentry{key, bits, e.Value}
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		if c := bytes.Compare(parsed[i].key, parsed[j].key); c != 0 {
+			return c < 0
+		}
+		return parsed[i].bits < parsed[j].bits
+	})
+
+	// every part is subtree under one path of depth bits
+	var depth int
+	for depth < 8 && 1<<uint(depth) < parallelism {
+		depth++
+	}
+	tree := NewTree(0)
+	parts := make([][]buildentry, 1<<uint(depth))
+	for _, e := range parsed {
+		if e.bits < depth {
+			if err := tree.build(e); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		p := e.key[0] >> uint(8-depth)
+		parts[p] = append(parts[p], e)
+	}
+	if depth == 0 {
+		for _, e := range parts[0] {
+			if err := tree.build(e); err != nil {
+				return nil, err
+			}
+		}
+		return tree, nil
+	}
+
+	subtrees := make([]*Tree, len(parts))
+	errs := make([]error, len(parts))
+	var wg sync.WaitGroup
+	for p := range parts {
+		if len(parts[p]) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			subtrees[p] = NewTree(0)
+			for _, e := range parts[p] {
+				if errs[p] = subtrees[p].build(e); errs[p] != nil {
+					return
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	for p, sub := range subtrees {
+		if errs[p] != nil {
+			return nil, errs[p]
+		}
+		if sub == nil {
+			continue
+		}
+		for _, key := range []net.IP{make(net.IP, net.IPv4len), make(net.IP, net.IPv6len)} {
+			key[0] = byte(p << uint(8-depth))
+			tree.graft(sub.lookup(key, depth), key, depth)
+		}
+	}
+	return tree, nil
+}
+
+func (tree *Tree) build(e buildentry) error {
+	node := tree.locate(e.key, e.bits)
+	if node.value != nil {
+		return wrap("add", []byte(newprefix(e.key, e.bits).String()), ErrNodeBusy)
+	}
+	tree.setvalue(node, e.value)
+	return nil
+}
+
+// graft moves value and children of src (taken from another tree) to the empty node located at depth bits on the path of key.
+func (tree *Tree) graft(src *node, key net.IP, bits int) {
+	if src == nil {
+		return
+	}
+	dst := tree.locate(key, bits)
+	dst.left, dst.right = src.left, src.right
+	if dst.left != nil {
+		dst.left.parent = dst
+	}
+	if dst.right != nil {
+		dst.right.parent = dst
+	}
+	dst.value = src.value
+	tree.resize(dst, src.size)
+}
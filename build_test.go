@@ -0,0 +1,80 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestBuildTree(t *testing.T) {
+	var entries []Entry
+	for i := 0; i < 256; i++ {
+		_, ipnet, _ := net.ParseCIDR(fmt.Sprintf("%d.%d.0.0/16", i, 255-i))
+		entries = append(entries, Entry{Prefix: ipnet, Value: i})
+	}
+	for _, cidr := range []string{"0.0.0.0/0", "64.0.0.0/2", "dead::/16", "10.0.0.0/8"} {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		entries = append(entries, Entry{Prefix: ipnet, Value: cidr})
+	}
+
+	for _, parallelism := range []int{1, 4, 16} {
+		tr, err := BuildTree(entries, parallelism)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tr.Len() != len(entries) {
+			t.Errorf("Wrong length, expected %d, got %d", len(entries), tr.Len())
+		}
+		for ip, val := range map[string]interface{}{"10.245.1.1": 10, "10.1.1.1": "10.0.0.0/8", "65.1.1.1": "64.0.0.0/2", "200.55.0.1": 200, "200.56.0.1": "0.0.0.0/0", "dead::1": "dead::/16"} {
+			inf, err := tr.FindCIDR(ip)
+			if err != nil {
+				t.Error(err)
+			}
+			if inf != val {
+				t.Errorf("Wrong value for %s with parallelism %d, expected %v, got %v", ip, parallelism, val, inf)
+			}
+		}
+		if err := tr.DeleteCIDR("10.245.0.0/16"); err != nil {
+			t.Error(err)
+		}
+		if tr.Len() != len(entries)-1 {
+			t.Errorf("Wrong length, expected %d, got %d", len(entries)-1, tr.Len())
+		}
+	}
+
+	_, err := BuildTree(append(entries, entries[0]), 4)
+	if !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	_, err = BuildTree([]Entry{{}}, 4)
+	if !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
+
+func TestBuildTreeMapped(t *testing.T) {
+	var entries []Entry
+	for _, cidr := range []string{"::ffff:1.2.3.0/120", "::ffff:0:0/96", "dead::/16"} {
+		_, ipnet, _ := net.ParseCIDR(cidr)
+		entries = append(entries, Entry{Prefix: ipnet, Value: cidr})
+	}
+	for _, parallelism := range []int{1, 4} {
+		tr, err := BuildTree(entries, parallelism)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for ip, val := range map[string]interface{}{"1.2.3.4": "::ffff:1.2.3.0/120", "5.6.7.8": "::ffff:0:0/96", "::ffff:1.2.3.4": "::ffff:1.2.3.0/120", "dead::1": "dead::/16"} {
+			if inf, _ := tr.FindCIDR(ip); inf != val {
+				t.Errorf("Wrong value for %s with parallelism %d, expected %v, got %v", ip, parallelism, val, inf)
+			}
+		}
+		if err := tr.AddCIDR("1.2.3.0/24", 1); !errors.Is(err, ErrNodeBusy) {
+			t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+		}
+	}
+}
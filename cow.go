@@ -0,0 +1,278 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// cownode is never modified after it is published, changes copy the whole path from the root.
+type cownode struct {
+	left, right *cownode
+	value       interface{}
+}
+
+type cowroot struct {
+	v4, v6  *cownode
+	count   int
+	version uint64
+}
+
+// COWTree is copy-on-write radix tree for IP/mask. Lookups never take locks and see tree either before or after every modification,
+// modifications copy path from the root to changed node and are serialized by mutex. It is suitable for read mostly workloads, zero value is empty tree.
+type COWTree struct {
+	mu   sync.Mutex
+	root atomic.Pointer[cowroot]
+}
+
+// NewCOWTree creates empty COWTree.
+func NewCOWTree() *COWTree {
+	return new(COWTree)
+}
+
+// AddCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR or if value already exists.
+func (tree *COWTree) AddCIDR(cidr string, val interface{}) error {
+	return tree.AddCIDRb([]byte(cidr), val)
+}
+
+func (tree *COWTree) AddCIDRb(cidr []byte, val interface{}) error {
+	return tree.modify(cidr, func(old interface{}) (interface{}, error) {
+		if old != nil {
+			return nil, wrap("add", cidr, ErrNodeBusy)
+		}
+		return val, nil
+	})
+}
+
+// SetCIDR sets value associated with IP/mask in the tree, overwriting existing one.
+func (tree *COWTree) SetCIDR(cidr string, val interface{}) error {
+	return tree.SetCIDRb([]byte(cidr), val)
+}
+
+func (tree *COWTree) SetCIDRb(cidr []byte, val interface{}) error {
+	return tree.modify(cidr, func(old interface{}) (interface{}, error) {
+		return val, nil
+	})
+}
+
+// DeleteCIDR removes value associated with IP/mask from the tree.
+func (tree *COWTree) DeleteCIDR(cidr string) error {
+	return tree.DeleteCIDRb([]byte(cidr))
+}
+
+func (tree *COWTree) DeleteCIDRb(cidr []byte) error {
+	return tree.modify(cidr, func(old interface{}) (interface{}, error) {
+		if old == nil {
+			return nil, wrap("delete", cidr, ErrNotFound)
+		}
+		return nil, nil
+	})
+}
+
+// FindCIDR traverses tree to proper Node and returns previously saved information in longest covered IP.
+func (tree *COWTree) FindCIDR(cidr string) (interface{}, error) {
+	return tree.FindCIDRb([]byte(cidr))
+}
+
+func (tree *COWTree) FindCIDRb(cidr []byte) (interface{}, error) {
+	return tree.root.Load().find(cidr)
+}
+
+// Len returns number of entries stored in the tree.
+func (tree *COWTree) Len() int {
+	if r := tree.root.Load(); r != nil {
+		return r.count
+	}
+	return 0
+}
+
+// Version returns number of modifications made to the tree.
+func (tree *COWTree) Version() uint64 {
+	if r := tree.root.Load(); r != nil {
+		return r.version
+	}
+	return 0
+}
+
+// Snapshot returns immutable view of the tree in its current version, it stays valid and consistent while tree is modified.
+func (tree *COWTree) Snapshot() *COWSnapshot {
+	r := tree.root.Load()
+	if r == nil {
+		r = new(cowroot)
+	}
+	return &COWSnapshot{r}
+}
+
+// Clone returns independent copy of the tree in O(1), both trees share all nodes and copy paths only when they are modified.
+func (tree *COWTree) Clone() *COWTree {
+	c := new(COWTree)
+	c.root.Store(tree.root.Load())
+	return c
+}
+
+// COWSnapshot is immutable view of COWTree, it is safe for concurrent use.
+type COWSnapshot struct {
+	root *cowroot
+}
+
+// Version returns version of the tree snapshot was taken at.
+func (s *COWSnapshot) Version() uint64 {
+	return s.root.version
+}
+
+// Len returns number of entries in snapshot.
+func (s *COWSnapshot) Len() int {
+	return s.root.count
+}
+
+// FindCIDR traverses snapshot and returns information saved in longest covered IP.
+func (s *COWSnapshot) FindCIDR(cidr string) (interface{}, error) {
+	return s.root.find([]byte(cidr))
+}
+
+// Walk calls fn for every prefix in snapshot, IPv4 prefixes first. Walk stops and returns the error if fn returns one, except for SkipSubtree and Stop.
+func (s *COWSnapshot) Walk(fn func(prefix *net.IPNet, val interface{}) error) error {
+	var err error
+	for _, n := range []*cownode{s.root.v4, s.root.v6} {
+		if n == nil {
+			continue
+		}
+		key := make(net.IP, net.IPv4len)
+		if n == s.root.v6 {
+			key = make(net.IP, net.IPv6len)
+		}
+		if err = n.walk(key, 0, fn); err != nil {
+			break
+		}
+	}
+	if err == Stop {
+		return nil
+	}
+	return err
+}
+
+func (r *cowroot) find(cidr []byte) (interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, nil
+	}
+	n := r.v4
+	if len(key) == net.IPv6len {
+		n = r.v6
+	}
+	return n.find(key, masklen(mask)), nil
+}
+
+// modify replaces value at IP/mask with one returned by fn and publishes new root, tree is not changed if fn fails.
+func (tree *COWTree) modify(cidr []byte, fn func(old interface{}) (interface{}, error)) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+
+	var r cowroot
+	if old := tree.root.Load(); old != nil {
+		r = *old
+	}
+	root := &r.v4
+	if len(key) == net.IPv6len {
+		root = &r.v6
+	}
+	n, delta, err := (*root).update(key, masklen(mask), 0, fn)
+	if err != nil {
+		return err
+	}
+	*root = n
+	r.count += delta
+	r.version++
+	tree.root.Store(&r)
+	return nil
+}
+
+func (n *cownode) find(key net.IP, bits int) (value interface{}) {
+	for d := 0; n != nil; d++ {
+		if n.value != nil {
+			value = n.value
+		}
+		if d == bits {
+			break
+		}
+		if bitset(key, d) {
+			n = n.right
+		} else {
+			n = n.left
+		}
+	}
+	return value
+}
+
+// update returns copy of n with value at depth bits on the path of key replaced by fn, change in number of values,
+// nodes left without value and children are dropped.
+func (n *cownode) update(key net.IP, bits, d int, fn func(old interface{}) (interface{}, error)) (*cownode, int, error) {
+	c := new(cownode)
+	if n != nil {
+		*c = *n
+	}
+	var delta int
+	if d == bits {
+		val, err := fn(c.value)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch {
+		case c.value == nil && val != nil:
+			delta = 1
+		case c.value != nil && val == nil:
+			delta = -1
+		}
+		c.value = val
+	} else {
+		var err error
+		if bitset(key, d) {
+			c.right, delta, err = c.right.update(key, bits, d+1, fn)
+		} else {
+			c.left, delta, err = c.left.update(key, bits, d+1, fn)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if c.value == nil && c.left == nil && c.right == nil && d > 0 {
+		return nil, delta, nil
+	}
+	return c, delta, nil
+}
+
+func (n *cownode) walk(key net.IP, d int, fn func(prefix *net.IPNet, val interface{}) error) error {
+	if n.value != nil {
+		e := newentry(key, d, n.value)
+		if err := fn(e.Prefix, e.Value); err == SkipSubtree {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if n.left != nil {
+		if err := n.left.walk(key, d+1, fn); err != nil {
+			return err
+		}
+	}
+	if n.right != nil {
+		key[d>>3] |= startbyte >> uint(d&7)
+		err := n.right.walk(key, d+1, fn)
+		key[d>>3] &^= startbyte >> uint(d&7)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
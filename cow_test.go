@@ -0,0 +1,170 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCOWTree(t *testing.T) {
+	tr := NewCOWTree()
+	if err := tr.AddCIDR("1.2.3.0/24", 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("1.2.3.0/25", 2); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("dead::/16", 3); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("1.2.3.0/24", 4); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	for ip, val := range map[string]interface{}{"1.2.3.1": 2, "1.2.3.129": 1, "1.2.4.1": nil, "dead::1": 3, "1.2.3.0/24": 1} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", tr.Len())
+	}
+
+	if err := tr.SetCIDR("1.2.3.0/24", 5); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteCIDR("1.2.3.0/25"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteCIDR("1.2.3.0/25"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	inf, err := tr.FindCIDR("1.2.3.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 5 {
+		t.Errorf("Wrong value, expected 5, got %v", inf)
+	}
+	if tr.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", tr.Len())
+	}
+
+	tr.DeleteCIDR("1.2.3.0/24")
+	tr.DeleteCIDR("dead::/16")
+	r := tr.root.Load()
+	if r.v4.left != nil || r.v4.right != nil || r.v6.left != nil || r.v6.right != nil {
+		t.Error("Tree should have been trimmed down to the roots")
+	}
+
+	var zero COWTree
+	if inf, err := zero.FindCIDR("1.1.1.1"); inf != nil || err != nil {
+		t.Errorf("Zero tree should be empty, got %v, %v", inf, err)
+	}
+}
+
+func TestCOWTreeConcurrent(t *testing.T) {
+	tr := NewCOWTree()
+	tr.AddCIDR("10.0.0.0/8", 0)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				tr.SetCIDR(fmt.Sprintf("10.%d.%d.0/24", w, i), i)
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if inf, err := tr.FindCIDR("10.200.0.1"); err != nil || inf.(int) != 0 {
+					t.Errorf("Wrong value, expected 0, got %v, %v", inf, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if tr.Len() != 401 {
+		t.Errorf("Wrong length, expected 401, got %d", tr.Len())
+	}
+}
+
+func TestCOWSnapshot(t *testing.T) {
+	tr := NewCOWTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("dead::/16", 2)
+	s := tr.Snapshot()
+
+	tr.SetCIDR("10.0.0.0/8", 3)
+	tr.AddCIDR("10.1.0.0/16", 4)
+	tr.DeleteCIDR("dead::/16")
+
+	if s.Version() != 2 || tr.Version() != 5 {
+		t.Errorf("Wrong versions, expected 2 and 5, got %d and %d", s.Version(), tr.Version())
+	}
+	if s.Len() != 2 || tr.Len() != 2 {
+		t.Errorf("Wrong lengths, expected 2 and 2, got %d and %d", s.Len(), tr.Len())
+	}
+	for ip, val := range map[string]interface{}{"10.1.0.1": 1, "dead::1": 2} {
+		inf, err := s.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong snapshot value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	var prefixes []string
+	s.Walk(func(prefix *net.IPNet, val interface{}) error {
+		prefixes = append(prefixes, fmt.Sprintf("%s=%v", prefix, val))
+		return nil
+	})
+	if strings.Join(prefixes, " ") != "10.0.0.0/8=1 dead::/16=2" {
+		t.Errorf("Wrong snapshot entries, got %v", prefixes)
+	}
+	if NewCOWTree().Snapshot().Len() != 0 {
+		t.Error("Snapshot of empty tree should be empty")
+	}
+}
+
+func TestCOWClone(t *testing.T) {
+	tr := NewCOWTree()
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+
+	c := tr.Clone()
+	c.SetCIDR("10.1.0.0/16", 3)
+	c.DeleteCIDR("10.0.0.0/8")
+	c.AddCIDR("dead::/16", 4)
+
+	for ip, val := range map[string]interface{}{"10.1.1.1": 2, "10.2.1.1": 1, "dead::1": nil} {
+		inf, _ := tr.FindCIDR(ip)
+		if inf != val {
+			t.Errorf("Wrong value in original for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	for ip, val := range map[string]interface{}{"10.1.1.1": 3, "10.2.1.1": nil, "dead::1": 4} {
+		inf, _ := c.FindCIDR(ip)
+		if inf != val {
+			t.Errorf("Wrong value in clone for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Len() != 2 || c.Len() != 2 {
+		t.Errorf("Wrong length, expected 2 and 2, got %d and %d", tr.Len(), c.Len())
+	}
+	if NewCOWTree().Clone().Len() != 0 {
+		t.Error("Clone of empty tree should be empty")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVOptions controls how LoadCSV reads its input, zero value reads comma separated lines without comments.
+type CSVOptions struct {
+	// Comma is field delimiter, ',' is used if it is zero.
+	Comma rune
+	// Comment marks lines to be ignored if they begin with it, no lines are ignored if it is zero.
+	Comment rune
+	// Overwrite makes loader replace existing values instead of failing with ErrNodeBusy.
+	Overwrite bool
+	// Value converts fields following CIDR on a line into value to store. By default second field is stored as string, or true if there is no such field.
+	Value func(fields []string) (interface{}, error)
+}
+
+// LoadCSV adds entries from lines of "cidr,value" format and returns number of entries added.
+// Loading stops at first bad line, entries added before it stay in the tree.
+func (tree *Tree) LoadCSV(r io.Reader, opts *CSVOptions) (int, error) {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.Comment = opts.Comment
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	value := opts.Value
+	if value == nil {
+		value = func(fields []string) (interface{}, error) {
+			if len(fields) == 0 {
+				return true, nil
+			}
+			return fields[0], nil
+		}
+	}
+
+	var count int
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		line, _ := cr.FieldPos(0)
+		val, err := value(record[1:])
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		cidr := strings.TrimSpace(record[0])
+		if opts.Overwrite {
+			err = tree.SetCIDR(cidr, val)
+		} else {
+			err = tree.AddCIDR(cidr, val)
+		}
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		count++
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoadCSV(t *testing.T) {
+	tr := NewTree(0)
+	n, err := tr.LoadCSV(strings.NewReader("10.0.0.0/8,corp\n10.1.0.0/16, lab\ndead::/16\n"), nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 3 {
+		t.Errorf("Wrong number of entries loaded, expected 3, got %d", n)
+	}
+	for ip, val := range map[string]interface{}{"10.2.0.1": "corp", "10.1.0.1": "lab", "dead::1": true} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	n, err = tr.LoadCSV(strings.NewReader("11.0.0.0/8,a\n10.0.0.0/8,b\n12.0.0.0/8,c\n"), nil)
+	if !errors.Is(err, ErrNodeBusy) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Should have gotten ErrNodeBusy on line 2, instead got err: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Wrong number of entries loaded, expected 1, got %d", n)
+	}
+}
+
+func TestLoadCSVOptions(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 0)
+	input := "# asn feed\n10.0.0.0/8;100\n192.168.0.0/16;200;private\n"
+	n, err := tr.LoadCSV(strings.NewReader(input), &CSVOptions{
+		Comma:     ';',
+		Comment:   '#',
+		Overwrite: true,
+		Value: func(fields []string) (interface{}, error) {
+			return strconv.Atoi(fields[0])
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 2 {
+		t.Errorf("Wrong number of entries loaded, expected 2, got %d", n)
+	}
+	inf, err := tr.FindCIDR("10.0.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 100 {
+		t.Errorf("Wrong value, expected 100, got %v", inf)
+	}
+
+	_, err = tr.LoadCSV(strings.NewReader("10.0.0.0/8;x\n"), &CSVOptions{
+		Comma: ';',
+		Value: func(fields []string) (interface{}, error) {
+			return strconv.Atoi(fields[0])
+		},
+	})
+	if err == nil {
+		t.Error("Should have gotten error for bad value")
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"time"
+)
+
+// Detail describes entry matched by LookupDetail together with its metadata.
+type Detail struct {
+	Entry
+	// Stored is time value was stored, zero if tree did not track details then
+	Stored time.Time
+	// LastMatched is time of the last lookup matched by the entry, zero if there was none
+	LastMatched time.Time
+	// Matches is number of lookups matched by the entry
+	Matches uint64
+}
+
+// TrackDetails turns on (or off) recording of time every value is stored and matched last time, together with counting its matches as CountHits does.
+// Lookups update metadata atomically, so they could still run concurrently under read lock.
+func (tree *Tree) TrackDetails(enable bool) {
+	tree.details = enable
+}
+
+// LookupDetail returns entry holding longest prefix covering IP/mask with its metadata, or nil if nothing covers it. The lookup itself is not counted as a match.
+func (tree *Tree) LookupDetail(cidr string) (*Detail, error) {
+	return tree.LookupDetailb([]byte(cidr))
+}
+
+func (tree *Tree) LookupDetailb(cidr []byte) (*Detail, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		found *node
+		depth int
+	)
+	bits := masklen(mask)
+	node := tree.rootof(key)
+	for d := 0; node != nil; d++ {
+		if node.live() && tree.better(node, found) {
+			found, depth = node, d
+		}
+		if d == bits {
+			break
+		}
+		node = node.child(key, d)
+	}
+	if found == nil || found.value == exclusion {
+		return nil, nil
+	}
+	detail := &Detail{Entry: newentry(key, depth, found.value), Matches: found.hits.Load()}
+	if found.stored != 0 {
+		detail.Stored = time.Unix(0, found.stored)
+	}
+	if matched := found.matched.Load(); matched != 0 {
+		detail.LastMatched = time.Unix(0, matched)
+	}
+	return detail, nil
+}
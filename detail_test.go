@@ -0,0 +1,49 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLookupDetail(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "old")
+	tr.TrackDetails(true)
+	before := time.Now()
+	tr.AddCIDR("10.1.0.0/16", "new")
+
+	d, err := tr.LookupDetail("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if d == nil || d.Value != "new" || d.Prefix.String() != "10.1.0.0/16" {
+		t.Fatalf("Wrong detail, expected 10.1.0.0/16 => new, got %v", d)
+	}
+	if d.Stored.Before(before) || d.Stored.After(time.Now()) || !d.LastMatched.IsZero() || d.Matches != 0 {
+		t.Errorf("Wrong metadata of unmatched entry, got %+v", d)
+	}
+
+	tr.FindCIDR("10.1.2.3")
+	tr.FindCIDR("10.1.3.4")
+	tr.Contains("10.1.3.4")
+	d, _ = tr.LookupDetail("10.1.2.3")
+	if d.Matches != 2 || d.LastMatched.Before(d.Stored) {
+		t.Errorf("Wrong metadata of matched entry, got %+v", d)
+	}
+
+	d, _ = tr.LookupDetail("10.2.0.1")
+	if d == nil || d.Value != "old" || !d.Stored.IsZero() {
+		t.Errorf("Entry stored before tracking should have no time, got %+v", d)
+	}
+	if d, _ = tr.LookupDetail("11.0.0.1"); d != nil {
+		t.Errorf("Wrong detail, expected nil, got %+v", d)
+	}
+	if _, err = tr.LookupDetail("11.0.0.256"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
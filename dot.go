@@ -0,0 +1,91 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// DumpDOT writes internal structure of the tree in Graphviz DOT format. Nodes holding values are highlighted and labeled with their prefix and value, edges are labeled with bit they stand for.
+func (tree *Tree) DumpDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph nradix {")
+	fmt.Fprintln(bw, "\tnode [shape=point];")
+	var id int
+	var dump func(n *node, key net.IP, d int) int
+	dump = func(n *node, key net.IP, d int) int {
+		self := id
+		id++
+		switch {
+		case n.value != nil:
+			label := fmt.Sprintf("%s\n%v", newentry(key, d, nil).Prefix, n.value)
+			fmt.Fprintf(bw, "\tn%d [shape=box, style=filled, fillcolor=lightblue, label=\"%s\"];\n", self, dotEscaper.Replace(label))
+		case n.parent == nil && len(key) == net.IPv4len:
+			fmt.Fprintf(bw, "\tn%d [shape=box, label=\"IPv4 root\"];\n", self)
+		case n.parent == nil:
+			fmt.Fprintf(bw, "\tn%d [shape=box, label=\"IPv6 root\"];\n", self)
+		}
+		if d == len(key)*8 {
+			return self
+		}
+		if n.left != nil {
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"0\"];\n", self, dump(n.left, key, d+1))
+		}
+		if n.right != nil {
+			key[d>>3] |= startbyte >> uint(d&7)
+			child := dump(n.right, key, d+1)
+			key[d>>3] &^= startbyte >> uint(d&7)
+			fmt.Fprintf(bw, "\tn%d -> n%d [label=\"1\"];\n", self, child)
+		}
+		return self
+	}
+	dump(tree.root, make(net.IP, net.IPv4len), 0)
+	dump(tree.root6, make(net.IP, net.IPv6len), 0)
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// Dump writes the tree as indented hierarchy of prefixes with their values, each prefix is nested under the longest stored one covering it.
+// If empty is set, structural nodes without values are written too, each of them nested under its parent.
+func (tree *Tree) Dump(w io.Writer, empty bool) error {
+	bw := bufio.NewWriter(w)
+	var dump func(n *node, key net.IP, d, indent int)
+	dump = func(n *node, key net.IP, d, indent int) {
+		if n.value != nil || empty && n.parent != nil {
+			fmt.Fprintf(bw, "%s%s", strings.Repeat("  ", indent), newentry(key, d, nil).Prefix)
+			if n.value != nil {
+				fmt.Fprintf(bw, " %v", n.value)
+			}
+			fmt.Fprintln(bw)
+			indent++
+		}
+		if n.left != nil {
+			dump(n.left, key, d+1, indent)
+		}
+		if n.right != nil {
+			key[d>>3] |= startbyte >> uint(d&7)
+			dump(n.right, key, d+1, indent)
+			key[d>>3] &^= startbyte >> uint(d&7)
+		}
+	}
+	fmt.Fprintln(bw, "IPv4")
+	dump(tree.root, make(net.IP, net.IPv4len), 0, 1)
+	fmt.Fprintln(bw, "IPv6")
+	dump(tree.root6, make(net.IP, net.IPv6len), 0, 1)
+	return bw.Flush()
+}
+
+// String returns Dump of the tree without structural nodes.
+func (tree *Tree) String() string {
+	var b strings.Builder
+	tree.Dump(&b, false)
+	return b.String()
+}
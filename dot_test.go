@@ -0,0 +1,83 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDumpDOT(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("128.0.0.0/2", `say "hi"`)
+	tr.AddCIDR("192.0.0.0/2", 2)
+
+	var buf bytes.Buffer
+	if err := tr.DumpDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph nradix {
+	node [shape=point];
+	n0 [shape=box, label="IPv4 root"];
+	n2 [shape=box, style=filled, fillcolor=lightblue, label="128.0.0.0/2\nsay \"hi\""];
+	n1 -> n2 [label="0"];
+	n3 [shape=box, style=filled, fillcolor=lightblue, label="192.0.0.0/2\n2"];
+	n1 -> n3 [label="1"];
+	n0 -> n1 [label="1"];
+	n4 [shape=box, label="IPv6 root"];
+}
+`
+	if buf.String() != expected {
+		t.Errorf("Wrong DOT output, expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	NewTree(0).DumpDOT(&buf)
+	if !strings.Contains(buf.String(), `n0 [shape=box, label="IPv4 root"];`) || !strings.Contains(buf.String(), `n1 [shape=box, label="IPv6 root"];`) {
+		t.Errorf("Empty tree should have root nodes, got\n%s", buf.String())
+	}
+}
+
+func TestDump(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/24", "three")
+	tr.AddCIDR("10.2.0.0/16", 4)
+	tr.AddCIDR("dead::/16", 5)
+	tr.ExcludeCIDR("10.1.3.0/24")
+
+	expected := `IPv4
+  10.0.0.0/8 1
+    10.1.0.0/16 2
+      10.1.2.0/24 three
+      10.1.3.0/24 excluded
+    10.2.0.0/16 4
+IPv6
+  dead::/16 5
+`
+	if tr.String() != expected {
+		t.Errorf("Wrong dump, expected\n%s\ngot\n%s", expected, tr.String())
+	}
+
+	tr = NewTree(0)
+	tr.AddCIDR("128.0.0.0/2", 1)
+	tr.AddCIDR("192.0.0.0/3", 2)
+	var buf bytes.Buffer
+	if err := tr.Dump(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	expected = `IPv4
+  128.0.0.0/1
+    128.0.0.0/2 1
+    192.0.0.0/2
+      192.0.0.0/3 2
+IPv6
+`
+	if buf.String() != expected {
+		t.Errorf("Wrong dump, expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
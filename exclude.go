@@ -0,0 +1,94 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"net/netip"
+)
+
+type excluded struct{}
+
+func (excluded) String() string {
+	return "excluded"
+}
+
+// exclusion is value of exception entries.
+var exclusion interface{} = excluded{}
+
+// ExcludeCIDR stores exception entry for IP/mask, so lookups inside it find nothing even if broader entry covers it. More specific entries inside the exception are still found.
+// Exception is removed by DeleteCIDR. Walks (and so Merge) skip exceptions but Len counts them. Gaps, Coverage and Subtract treat excepted space as uncovered.
+// Exceptions are kept by text, binary, gob and JSON encodings, WriteIndex and NewStrideTable.
+func (tree *Tree) ExcludeCIDR(cidr string) error {
+	return tree.ExcludeCIDRb([]byte(cidr))
+}
+
+func (tree *Tree) ExcludeCIDRb(cidr []byte) error {
+	if err := tree.checkhost(cidr); err != nil {
+		return err
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	node := tree.locate(key, masklen(mask))
+	if node.live() {
+		return wrap("exclude", cidr, ErrNodeBusy)
+	}
+	tree.excludes = true
+	tree.setvalue(node, exclusion)
+	tree.evict()
+	return nil
+}
+
+// excluded reports whether lookup of key/bits ends at exception entry.
+func (tree *Tree) excluded(key net.IP, bits int) bool {
+	var found *node
+	node := tree.rootof(key)
+	for d := 0; node != nil; d++ {
+		if node.live() && tree.better(node, found) {
+			found = node
+		}
+		if d == bits {
+			break
+		}
+		node = node.child(key, d)
+	}
+	return found != nil && found.value == exclusion
+}
+
+// excluded32 is excluded for IPv4 key and mask.
+func (tree *Tree) excluded32(key, mask uint32) bool {
+	var found *node
+	bit := startbit
+	node := tree.root
+	for node != nil {
+		if node.live() && tree.better(node, found) {
+			found = node
+		}
+		if key&bit != 0 {
+			node = node.right
+		} else {
+			node = node.left
+		}
+		if mask&bit == 0 {
+			break
+		}
+		bit >>= 1
+	}
+	return found != nil && found.value == exclusion
+}
+
+// exceptions returns prefixes of all exception entries in the same order as Walk.
+func (tree *Tree) exceptions() []netip.Prefix {
+	var list []netip.Prefix
+	tree.walklive(func(key net.IP, bits int, n *node) error {
+		if n.value == exclusion {
+			list = append(list, newprefix(key, bits))
+		}
+		return nil
+	})
+	return list
+}
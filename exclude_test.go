@@ -0,0 +1,202 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+func TestExclude(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "all")
+	tr.AddCIDR("10.1.2.0/24", "inside")
+	tr.AddCIDR("dead::/16", "all6")
+	if err := tr.ExcludeCIDR("10.1.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.ExcludeCIDR("dead:beef::/32"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.ExcludeCIDR("10.1.2.0/24"); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+
+	for ip, val := range map[string]interface{}{"10.0.0.1": "all", "10.1.0.1": nil, "10.1.2.3": "inside", "10.2.0.1": "all",
+		"dead::1": "all6", "dead:beef::1": nil, "10.1.0.0/16": nil, "10.0.0.0/8": "all"} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+		if contains := tr.Contains(ip); contains != (val != nil) {
+			t.Errorf("Wrong containment of %s, expected %v, got %v", ip, val != nil, contains)
+		}
+		if _, ok, _ := tr.GetCIDR(ip); ok != (val != nil) {
+			t.Errorf("Wrong GetCIDR result for %s, expected %v, got %v", ip, val != nil, ok)
+		}
+	}
+	if tr.ContainsIP(net.ParseIP("10.1.0.1")) || tr.ContainsIP(net.ParseIP("dead:beef::1")) {
+		t.Error("Excluded IP should not be contained")
+	}
+	if inf := tr.FindIPv4(0x0a010001); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if _, err := tr.ExactMatchCIDR("10.1.0.0/16"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if cidrs := tr.ListCIDRs(); len(cidrs) != 3 {
+		t.Errorf("Walk should skip exceptions, got %v", cidrs)
+	}
+
+	if err := tr.DeleteCIDR("10.1.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.0.1"); inf != "all" {
+		t.Errorf("Wrong value, expected all, got %v", inf)
+	}
+}
+
+func TestExcludeValues(t *testing.T) {
+	tr := NewTree(0)
+	var inserted []interface{}
+	tr.OnInsert(func(prefix netip.Prefix, old, new interface{}) {
+		inserted = append(inserted, old, new)
+	})
+	tr.AddCIDR("10.0.0.0/8", "all")
+	tr.ExcludeCIDR("10.1.0.0/16")
+	tr.AddCIDR("10.1.2.0/24", "inside")
+	if len(inserted) != 4 || inserted[0] != nil || inserted[2] != nil {
+		t.Errorf("Exception should not be reported, got %v", inserted)
+	}
+	tr.DeleteCIDR("10.1.2.0/24")
+	tr.ExcludeCIDR("10.2.0.0/16")
+
+	if inf, _ := tr.FindShortestCIDR("10.1.0.1"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	tr.AddCIDR("10.1.2.0/24", "inside")
+	if inf, _ := tr.FindShortestCIDR("10.1.2.1"); inf != "all" {
+		t.Errorf("Wrong value, expected all, got %v", inf)
+	}
+	tr.DeleteCIDR("10.0.0.0/8")
+	if inf, _ := tr.FindShortestCIDR("10.1.2.1"); inf != "inside" {
+		t.Errorf("Wrong value, expected inside, got %v", inf)
+	}
+
+	if _, err := tr.DeleteCIDRValue("10.1.0.0/16"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	tr.UpdateCIDR("10.1.0.0/16", func(old interface{}, exists bool) (interface{}, bool) {
+		if exists || old != nil {
+			t.Errorf("Exception should not be passed to fn, got %v", old)
+		}
+		return nil, false
+	})
+	tr.UpsertCIDR("10.2.0.0/16", "upserted", func(old, new interface{}) interface{} {
+		t.Errorf("Exception should not be merged, got %v", old)
+		return new
+	})
+	if inf, _ := tr.FindCIDR("10.2.0.1"); inf != "upserted" {
+		t.Errorf("Wrong value, expected upserted, got %v", inf)
+	}
+
+	other := NewTree(0)
+	other.AddCIDR("10.1.0.0/16", "other")
+	other.AddCIDR("11.0.0.0/8", "other")
+	other.ExcludeCIDR("11.1.0.0/16")
+	tr.AddCIDR("11.1.0.0/16", "mine")
+	check := func(prefix netip.Prefix, a, b interface{}) interface{} {
+		if a == exclusion || b == exclusion {
+			t.Errorf("Exception should not be passed for %s", prefix)
+		}
+		return b
+	}
+	tr.Intersect(other, check)
+	added, removed, changed := tr.Diff(other, nil)
+	for _, e := range append(append(added, removed...), changed...) {
+		if e.Value == exclusion {
+			t.Errorf("Exception should not be listed for %s", e.Prefix)
+		}
+	}
+	tr.Merge(other, check)
+	if inf, _ := tr.FindCIDR("10.1.0.1"); inf != "other" {
+		t.Errorf("Wrong value, expected other, got %v", inf)
+	}
+	for _, v := range inserted {
+		if v == exclusion {
+			t.Errorf("Exception should not be reported, got %v", inserted)
+		}
+	}
+}
+
+func TestExcludeSerialized(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "all")
+	tr.ExcludeCIDR("10.1.0.0/16")
+	tr.AddCIDR("10.1.2.0/24", "inside")
+	tr.AddCIDR("dead::/16", "all6")
+	tr.ExcludeCIDR("dead:beef::/32")
+	check := func(name string, find func(ip string) interface{}) {
+		for ip, val := range map[string]interface{}{"10.0.0.1": "all", "10.1.0.1": nil, "10.1.2.3": "inside", "dead::1": "all6", "dead:beef::1": nil} {
+			if inf := find(ip); inf != val {
+				t.Errorf("%s: wrong value for %s, expected %v, got %v", name, ip, val, inf)
+			}
+		}
+	}
+	decoded := func(name string, data []byte, err error, unmarshal func(tr *Tree, data []byte) error) {
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		restored := NewTree(0)
+		if err = unmarshal(restored, data); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		check(name, func(ip string) interface{} {
+			inf, _ := restored.FindCIDR(ip)
+			if b, ok := inf.([]byte); ok {
+				return string(b)
+			}
+			return inf
+		})
+	}
+
+	data, err := tr.MarshalText()
+	decoded("text", data, err, (*Tree).UnmarshalText)
+	data, err = tr.MarshalBinary()
+	decoded("binary", data, err, (*Tree).UnmarshalBinary)
+	data, err = tr.GobEncode()
+	decoded("gob", data, err, (*Tree).GobDecode)
+	data, err = tr.MarshalJSON()
+	decoded("json", data, err, (*Tree).UnmarshalJSON)
+
+	var buf bytes.Buffer
+	if err := tr.WriteIndex(&buf, func(val interface{}) ([]byte, error) { return []byte(val.(string)), nil }); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := NewIndex(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("index", func(ip string) interface{} {
+		if b, _ := idx.FindCIDR(ip); b != nil {
+			return string(b)
+		}
+		return nil
+	})
+	st, err := NewStrideTable(tr, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("stride", func(ip string) interface{} {
+		inf, _ := st.Find(ip)
+		return inf
+	})
+}
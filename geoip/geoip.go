@@ -0,0 +1,136 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+// Package geoip loads MaxMind GeoLite2 Country and City blocks CSV files into nradix.Tree.
+package geoip
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/asergeyev/nradix"
+)
+
+var ErrBadHeader = errors.New("Not a GeoLite2 blocks CSV")
+
+// Block is value stored in the tree for every network of GeoLite2 blocks file. Location fields are only filled from City files.
+type Block struct {
+	GeonameID                   uint32
+	RegisteredCountryGeonameID  uint32
+	RepresentedCountryGeonameID uint32
+	IsAnonymousProxy            bool
+	IsSatelliteProvider         bool
+	IsAnycast                   bool
+
+	PostalCode     string
+	Latitude       float64
+	Longitude      float64
+	AccuracyRadius uint16
+}
+
+// Load reads GeoLite2-Country-Blocks-IPv4/IPv6 or GeoLite2-City-Blocks-IPv4/IPv6 CSV (with header line) and adds every network to the tree with *Block value.
+// Returns number of networks added.
+func Load(tree *nradix.Tree, r io.Reader) (int, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return 0, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	network, ok := columns["network"]
+	if !ok {
+		return 0, ErrBadHeader
+	}
+
+	var count int
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		line, _ := cr.FieldPos(0)
+		block, err := parseBlock(record, columns)
+		if err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		if err = tree.AddCIDR(record[network], block); err != nil {
+			return count, fmt.Errorf("line %d: %w", line, err)
+		}
+		count++
+	}
+}
+
+// Lookup returns block of the most specific network covering IP or nil if there is none.
+func Lookup(tree *nradix.Tree, ip string) (*Block, error) {
+	val, err := tree.FindCIDR(ip)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := val.(*Block)
+	return block, nil
+}
+
+func parseBlock(record []string, columns map[string]int) (*Block, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+	id := func(name string) (uint32, error) {
+		s := field(name)
+		if s == "" {
+			return 0, nil
+		}
+		v, err := strconv.ParseUint(s, 10, 32)
+		return uint32(v), err
+	}
+	float := func(name string) (float64, error) {
+		s := field(name)
+		if s == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(s, 64)
+	}
+
+	var (
+		b   Block
+		err error
+	)
+	if b.GeonameID, err = id("geoname_id"); err != nil {
+		return nil, err
+	}
+	if b.RegisteredCountryGeonameID, err = id("registered_country_geoname_id"); err != nil {
+		return nil, err
+	}
+	if b.RepresentedCountryGeonameID, err = id("represented_country_geoname_id"); err != nil {
+		return nil, err
+	}
+	b.IsAnonymousProxy = field("is_anonymous_proxy") == "1"
+	b.IsSatelliteProvider = field("is_satellite_provider") == "1"
+	b.IsAnycast = field("is_anycast") == "1"
+	b.PostalCode = field("postal_code")
+	if b.Latitude, err = float("latitude"); err != nil {
+		return nil, err
+	}
+	if b.Longitude, err = float("longitude"); err != nil {
+		return nil, err
+	}
+	radius, err := id("accuracy_radius")
+	if err != nil {
+		return nil, err
+	}
+	b.AccuracyRadius = uint16(radius)
+	return &b, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package geoip
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/asergeyev/nradix"
+)
+
+const countryBlocks = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider,is_anycast
+1.0.0.0/24,2077456,2077456,,0,0,
+1.0.1.0/24,1814991,1814991,,0,0,1
+2001:200::/32,1861060,1861060,,0,0,
+`
+
+const cityBlocks = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider,postal_code,latitude,longitude,accuracy_radius,is_anycast
+81.2.69.142/31,2643743,2635167,,0,0,"EC4A",51.5142,-0.0931,10,
+`
+
+func TestLoadCountry(t *testing.T) {
+	tr := nradix.NewTree(0)
+	n, err := Load(tr, strings.NewReader(countryBlocks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Wrong number of networks, expected 3, got %d", n)
+	}
+
+	b, err := Lookup(tr, "1.0.1.77")
+	if err != nil {
+		t.Error(err)
+	}
+	if b == nil || b.GeonameID != 1814991 || !b.IsAnycast {
+		t.Errorf("Wrong block, got %+v", b)
+	}
+	b, err = Lookup(tr, "2001:200::1")
+	if err != nil {
+		t.Error(err)
+	}
+	if b == nil || b.GeonameID != 1861060 || b.IsAnycast {
+		t.Errorf("Wrong block, got %+v", b)
+	}
+	b, err = Lookup(tr, "8.8.8.8")
+	if err != nil {
+		t.Error(err)
+	}
+	if b != nil {
+		t.Errorf("Wrong block, expected nil, got %+v", b)
+	}
+}
+
+func TestLoadCity(t *testing.T) {
+	tr := nradix.NewTree(0)
+	if _, err := Load(tr, strings.NewReader(cityBlocks)); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Lookup(tr, "81.2.69.143")
+	if err != nil {
+		t.Error(err)
+	}
+	if b == nil || b.PostalCode != "EC4A" || b.Latitude != 51.5142 || b.Longitude != -0.0931 || b.AccuracyRadius != 10 || b.RegisteredCountryGeonameID != 2635167 {
+		t.Errorf("Wrong block, got %+v", b)
+	}
+}
+
+func TestLoadBad(t *testing.T) {
+	tr := nradix.NewTree(0)
+	if _, err := Load(tr, strings.NewReader("cidr,value\n1.0.0.0/24,1\n")); err != ErrBadHeader {
+		t.Errorf("Should have gotten ErrBadHeader, instead got err: %v", err)
+	}
+	_, err := Load(tr, strings.NewReader("network,geoname_id\n1.0.0.0/24,x\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Should have gotten error on line 2, instead got err: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/gob"
+	"net"
+)
+
+type gobEntry struct {
+	Key       []byte
+	Bits      int
+	Value     interface{}
+	Exception bool
+}
+
+// GobEncode encodes all entries of the tree with gob, so concrete types of values should be registered with gob.Register.
+func (tree *Tree) GobEncode() ([]byte, error) {
+	var entries []gobEntry
+	tree.walklive(func(key net.IP, bits int, n *node) error {
+		e := gobEntry{Key: append([]byte(nil), key...), Bits: bits, Value: n.value}
+		if n.value == exclusion {
+			e.Value, e.Exception = nil, true
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entries); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode replaces content of the tree with entries produced by GobEncode. Tree is left untouched if data could not be decoded, its settings and hooks are kept.
+func (tree *Tree) GobDecode(data []byte) error {
+	var entries []gobEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+		return err
+	}
+	decoded := NewTree(0)
+	for _, e := range entries {
+		if (len(e.Key) != net.IPv4len && len(e.Key) != net.IPv6len) || e.Bits < 0 || e.Bits > len(e.Key)*8 {
+			return ErrBadFormat
+		}
+		node := decoded.locate(e.Key, e.Bits)
+		if node.value != nil {
+			return ErrBadFormat
+		}
+		if e.Exception {
+			decoded.excludes = true
+			e.Value = exclusion
+		}
+		decoded.setvalue(node, e.Value)
+	}
+	tree.adopt(decoded)
+	return nil
+}
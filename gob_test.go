@@ -0,0 +1,67 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+)
+
+func TestGob(t *testing.T) {
+	type state struct {
+		Name  string
+		Rules *Tree
+	}
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", "two")
+	tr.AddCIDR("dead::/16", 3.5)
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(state{"acl", tr}); err != nil {
+		t.Fatal(err)
+	}
+	var restored state
+	if err := gob.NewDecoder(&buf).Decode(&restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Name != "acl" || restored.Rules.Len() != 3 {
+		t.Fatalf("Wrong state restored: %v with %d entries", restored.Name, restored.Rules.Len())
+	}
+	for ip, val := range map[string]interface{}{"10.2.0.1": 1, "10.1.0.1": "two", "dead::1": 3.5} {
+		inf, err := restored.Rules.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	if err := restored.Rules.GobDecode([]byte("garbage")); err == nil {
+		t.Error("Should have gotten error for bad input")
+	}
+}
+
+func TestGobKeepsSettings(t *testing.T) {
+	src := NewTree(0)
+	src.AddCIDR("10.0.0.0/8", "a")
+	data, err := src.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewTree(0)
+	tr.SetMaxEntries(1)
+	tr.AddCIDR("11.0.0.0/8", "b")
+	if err = tr.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	tr.AddCIDR("12.0.0.0/8", "c")
+	if tr.Len() != 1 {
+		t.Errorf("Entries limit should be kept, got %d entries", tr.Len())
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"time"
+)
+
+// CountHits turns on (or off) counting of lookups matched by every entry. Counters are updated atomically, so lookups could still run concurrently under read lock.
+func (tree *Tree) CountHits(enable bool) {
+	tree.counthits = enable
+}
+
+// HitsCIDR returns number of lookups matched by entry stored exactly at IP/mask since it was added or counters were reset. Returns ErrNotFound if there is no such entry.
+func (tree *Tree) HitsCIDR(cidr string) (uint64, error) {
+	return tree.HitsCIDRb([]byte(cidr))
+}
+
+func (tree *Tree) HitsCIDRb(cidr []byte) (uint64, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return 0, err
+	}
+	node := tree.lookup(key, masklen(mask))
+	if node == nil || !node.held() {
+		return 0, wrap("find", cidr, ErrNotFound)
+	}
+	return node.hits.Load(), nil
+}
+
+// WalkHits calls fn for every entry with non-zero hit counter in the same order as Walk.
+func (tree *Tree) WalkHits(fn func(prefix *net.IPNet, hits uint64) error) error {
+	err := tree.walk(func(key net.IP, bits int, n *node) error {
+		if hits := n.hits.Load(); hits != 0 {
+			return fn(newentry(key, bits, nil).Prefix, hits)
+		}
+		return nil
+	})
+	if err == Stop {
+		return nil
+	}
+	return err
+}
+
+// ResetHits sets hit counters of all entries to zero.
+func (tree *Tree) ResetHits() {
+	tree.walk(func(key net.IP, bits int, n *node) error {
+		n.hits.Store(0)
+		return nil
+	})
+}
+
+func (tree *Tree) hit(n *node) {
+	if (tree.counthits || tree.details) && n.value != nil {
+		n.hits.Add(1)
+	}
+	if tree.details {
+		n.matched.Store(time.Now().UnixNano())
+	}
+	tree.touch(n)
+}
@@ -0,0 +1,128 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHits(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	tr.FindCIDR("10.1.1.1")
+	hits, err := tr.HitsCIDR("10.1.0.0/16")
+	if err != nil {
+		t.Error(err)
+	}
+	if hits != 0 {
+		t.Errorf("Hits should not be counted by default, got %d", hits)
+	}
+
+	tr.CountHits(true)
+	tr.FindCIDR("10.1.1.1")
+	tr.FindCIDR("10.1.2.1")
+	tr.FindCIDR("10.2.1.1")
+	tr.FindCIDR("11.0.0.1")
+	tr.FindCIDR("dead::1")
+	tr.FindCIDRMatch("10.1.3.1")
+	for cidr, expected := range map[string]uint64{"10.0.0.0/8": 1, "10.1.0.0/16": 3, "dead::/16": 1} {
+		hits, err := tr.HitsCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if hits != expected {
+			t.Errorf("Wrong hits for %s, expected %d, got %d", cidr, expected, hits)
+		}
+	}
+	if _, err := tr.HitsCIDR("10.2.0.0/16"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+
+	var walked int
+	tr.WalkHits(func(prefix *net.IPNet, hits uint64) error {
+		walked++
+		return nil
+	})
+	if walked != 3 {
+		t.Errorf("Wrong number of entries with hits, expected 3, got %d", walked)
+	}
+
+	tr.ResetHits()
+	if hits, _ := tr.HitsCIDR("10.1.0.0/16"); hits != 0 {
+		t.Errorf("Hits should be reset, got %d", hits)
+	}
+
+	tr.DeleteCIDR("dead::/16")
+	tr.AddCIDR("dead::/16", 4)
+	if hits, _ := tr.HitsCIDR("dead::/16"); hits != 0 {
+		t.Errorf("Hits of re-added entry should start from zero, got %d", hits)
+	}
+}
+
+func TestHitsShortest(t *testing.T) {
+	tr := NewTree(0)
+	tr.CountHits(true)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	tr.FindShortestCIDR("10.1.1.1")
+	tr.Contains("10.1.1.1")
+	tr.Contains("dead::1")
+	tr.ContainsIP(net.ParseIP("10.2.0.1"))
+	tr.ContainsIP(net.ParseIP("dead::1"))
+	for cidr, expected := range map[string]uint64{"10.0.0.0/8": 3, "10.1.0.0/16": 0, "dead::/16": 2} {
+		hits, err := tr.HitsCIDR(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if hits != expected {
+			t.Errorf("Wrong hits for %s, expected %d, got %d", cidr, expected, hits)
+		}
+	}
+}
+
+func TestHitsConcurrent(t *testing.T) {
+	st := NewSafeTree(0)
+	st.AddCIDR("10.0.0.0/8", 1)
+	st.CountHits(true)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				st.FindCIDR("10.1.1.1")
+			}
+		}()
+	}
+	wg.Wait()
+	hits, err := st.HitsCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if hits != 800 {
+		t.Errorf("Wrong hits, expected 800, got %d", hits)
+	}
+}
+
+func TestHitsDead(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.ExcludeCIDR("10.1.0.0/16")
+	tr.SetCIDRTTL("10.2.0.0/16", 2, -time.Second)
+	for _, cidr := range []string{"10.1.0.0/16", "10.2.0.0/16"} {
+		if _, err := tr.HitsCIDR(cidr); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Should have gotten ErrNotFound for %s, instead got err: %v", cidr, err)
+		}
+	}
+}
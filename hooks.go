@@ -0,0 +1,92 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net/netip"
+)
+
+type hooks struct {
+	insert func(prefix netip.Prefix, old, new interface{})
+	delete func(prefix netip.Prefix, old interface{})
+	evict  func(prefix netip.Prefix, old interface{})
+}
+
+// OnInsert registers fn to be called when value is stored in the tree, old is nil if there was no entry and replaced value otherwise. Nil fn removes the hook.
+// Hooks are called while the tree is being modified, so they must not access it.
+func (tree *Tree) OnInsert(fn func(prefix netip.Prefix, old, new interface{})) {
+	tree.sethooks(func(h *hooks) { h.insert = fn })
+}
+
+// OnDelete registers fn to be called for every entry removed from the tree (including removals of whole ranges and Reset). Nil fn removes the hook.
+func (tree *Tree) OnDelete(fn func(prefix netip.Prefix, old interface{})) {
+	tree.sethooks(func(h *hooks) { h.delete = fn })
+}
+
+// OnEvict registers fn to be called for every entry removed by the tree itself, because of entries limit or expiration. Nil fn removes the hook.
+func (tree *Tree) OnEvict(fn func(prefix netip.Prefix, old interface{})) {
+	tree.sethooks(func(h *hooks) { h.evict = fn })
+}
+
+func (tree *Tree) sethooks(set func(h *hooks)) {
+	h := &hooks{}
+	if tree.hooks != nil {
+		*h = *tree.hooks
+	}
+	set(h)
+	if h.insert == nil && h.delete == nil && h.evict == nil {
+		h = nil
+	}
+	tree.hooks = h
+}
+
+// changed reports val about to be stored in n to hooks. Exceptions are not values, so they are not reported, and old value is nil if it expired.
+func (tree *Tree) changed(n *node, val interface{}) {
+	switch {
+	case val == exclusion:
+	case val != nil && tree.hooks.insert != nil:
+		var old interface{}
+		if n.held() {
+			old = n.value
+		}
+		tree.hooks.insert(tree.prefixof(n), old, val)
+	case val == nil && n.value != nil:
+		tree.removed(n)
+	}
+}
+
+// removed reports value of n about to be removed to OnDelete hook.
+func (tree *Tree) removed(n *node) {
+	if tree.hooks != nil && tree.hooks.delete != nil && n.value != nil && n.value != exclusion {
+		tree.hooks.delete(tree.prefixof(n), n.value)
+	}
+}
+
+// evicted reports value of n about to be evicted to OnEvict hook.
+func (tree *Tree) evicted(n *node) {
+	if tree.hooks != nil && tree.hooks.evict != nil && n.value != nil && n.value != exclusion {
+		tree.hooks.evict(tree.prefixof(n), n.value)
+	}
+}
+
+// prefixof returns prefix n is located at, restoring it from links to parents.
+func (tree *Tree) prefixof(n *node) netip.Prefix {
+	bits := 0
+	for p := n; p.parent != nil; p = p.parent {
+		bits++
+	}
+	var key [16]byte
+	d := bits
+	for ; n.parent != nil; n = n.parent {
+		d--
+		if n.parent.right == n {
+			key[d>>3] |= startbyte >> uint(d&7)
+		}
+	}
+	if n == tree.root {
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte(key[:4])), bits)
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(key), bits)
+}
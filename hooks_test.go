@@ -0,0 +1,67 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	tr := NewTree(0)
+	var events []string
+	tr.OnInsert(func(prefix netip.Prefix, old, new interface{}) {
+		events = append(events, fmt.Sprintf("insert %s %v->%v", prefix, old, new))
+	})
+	tr.OnDelete(func(prefix netip.Prefix, old interface{}) {
+		events = append(events, fmt.Sprintf("delete %s %v", prefix, old))
+	})
+	tr.OnEvict(func(prefix netip.Prefix, old interface{}) {
+		events = append(events, fmt.Sprintf("evict %s %v", prefix, old))
+	})
+
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.SetCIDR("10.0.0.0/8", 2)
+	tr.AddCIDR("10.0.0.0/8", 3)
+	tr.AddCIDR("10.1.2.128/25", 4)
+	tr.AddCIDR("dead:beef::/32", 5)
+	tr.DeleteCIDR("dead:beef::/32")
+	tr.DeleteWholeRangeCIDR("10.0.0.0/8")
+	tr.AddCIDRTTL("0.0.0.0/0", 6, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	tr.Expire()
+	tr.SetMaxEntries(1)
+	tr.AddCIDR("255.255.255.255/32", 7)
+	tr.AddCIDR("::/0", 8)
+	tr.OnEvict(nil)
+	tr.AddCIDR("::1/128", 9)
+
+	expected := []string{
+		"insert 10.0.0.0/8 <nil>->1",
+		"insert 10.0.0.0/8 1->2",
+		"insert 10.1.2.128/25 <nil>->4",
+		"insert dead:beef::/32 <nil>->5",
+		"delete dead:beef::/32 5",
+		"delete 10.1.2.128/25 4",
+		"delete 10.0.0.0/8 2",
+		"insert 0.0.0.0/0 <nil>->6",
+		"evict 0.0.0.0/0 6",
+		"insert 255.255.255.255/32 <nil>->7",
+		"insert ::/0 <nil>->8",
+		"evict 255.255.255.255/32 7",
+		"insert ::1/128 <nil>->9",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Wrong events, expected\n%v\ngot\n%v", expected, events)
+	}
+
+	tr.OnInsert(nil)
+	tr.OnDelete(nil)
+	if tr.hooks != nil {
+		t.Error("Hooks should be dropped once all of them are removed")
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"net"
+)
+
+// RejectHostBits turns on (or off) rejecting of prefixes with host bits set ("10.1.2.3/24") by inserts with ErrHostBits. By default they are normalized, so "10.1.2.3/24" is stored as 10.1.2.0/24.
+// Tree keeps prefixes as paths of their network bits, so storing them as-is is not possible. Lookups and deletes are not affected.
+func (tree *Tree) RejectHostBits(enable bool) {
+	tree.strict = enable
+}
+
+// checkhost returns error if the tree rejects host bits and cidr has them set. Bad input is left for the parser to report.
+func (tree *Tree) checkhost(cidr []byte) error {
+	if tree.strict && hostbits(cidr) {
+		return wrap("parse", cidr, ErrHostBits)
+	}
+	return nil
+}
+
+// checkhostnet is checkhost for network given as net.IPNet.
+func (tree *Tree) checkhostnet(ipnet *net.IPNet) error {
+	if tree.strict && ipnet != nil && !ipnet.IP.Equal(ipnet.IP.Mask(ipnet.Mask)) {
+		return wrap("parse", []byte(ipnet.String()), ErrHostBits)
+	}
+	return nil
+}
+
+// hostbits reports whether valid cidr has bits set after its prefix length.
+func hostbits(cidr []byte) bool {
+	if isv4(cidr) {
+		ip, mask, err := scancidr4(cidr)
+		return err == nil && ip&^mask != 0
+	}
+	p := bytes.IndexByte(cidr, '/')
+	if p < 0 {
+		return false
+	}
+	hi, lo, _, err := scankey6(cidr[:p])
+	if err != nil {
+		return false
+	}
+	mhi, mlo, _, err := scankey6(cidr)
+	return err == nil && (hi != mhi || lo != mlo)
+}
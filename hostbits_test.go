@@ -0,0 +1,72 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestRejectHostBits(t *testing.T) {
+	tr := NewTree(0)
+	if err := tr.AddCIDR("10.1.2.3/24", 1); err != nil {
+		t.Error(err)
+	}
+	if inf, err := tr.ExactMatchCIDR("10.1.2.0/24"); err != nil || inf != 1 {
+		t.Errorf("Prefix should be normalized by default, got %v, err: %v", inf, err)
+	}
+
+	tr.RejectHostBits(true)
+	for _, cidr := range []string{"10.1.3.1/24", "10.1.3.0 255.255.0.0", "dead:beef::1/32", "dead::%eth0/15", "::ffff:10.1.3.1/120"} {
+		if err := tr.SetCIDR(cidr, 2); !errors.Is(err, ErrHostBits) {
+			t.Errorf("Should have gotten ErrHostBits for %q, instead got err: %v", cidr, err)
+		}
+	}
+	for _, err := range []error{
+		tr.AddCIDR("10.1.3.1/24", 2),
+		tr.AddCIDRTTL("10.1.3.1/24", 2, time.Hour),
+		tr.AddCIDRPriority("10.1.3.1/24", 2, 1),
+		tr.ExcludeCIDR("10.1.3.1/24"),
+		tr.SetTag("10.1.3.1/24", "tag", 2),
+		tr.AppendCIDR("10.1.3.1/24", 2),
+		tr.AddPrefix(netip.MustParsePrefix("10.1.3.1/24"), 2),
+		tr.AddBatch([]BatchEntry{{"10.1.4.0/24", 2}, {"10.1.3.1/24", 2}}),
+		tr.AddIPNet(&net.IPNet{IP: net.IP{10, 1, 3, 1}, Mask: net.CIDRMask(24, 32)}, 2),
+		tr.SetIPNet(&net.IPNet{IP: net.ParseIP("dead:beef::1"), Mask: net.CIDRMask(32, 128)}, 2),
+		tr.Add16([16]byte{0xde, 0xad, 0xbe, 0xef, 15: 1}, 32, 2),
+		tr.Begin().AddCIDR("10.1.3.1/24", 2),
+	} {
+		if !errors.Is(err, ErrHostBits) {
+			t.Errorf("Should have gotten ErrHostBits, instead got err: %v", err)
+		}
+	}
+	if tr.Len() != 1 {
+		t.Errorf("Wrong length, expected 1, got %d", tr.Len())
+	}
+
+	for _, cidr := range []string{"10.1.3.0/24", "10.1.4.1", "10.1.5.0 255.255.255.0", "dead:beef::/32", "dead::1", "::ffff:10.1.6.0/120"} {
+		if err := tr.AddCIDR(cidr, 3); err != nil {
+			t.Errorf("Should have added %q, instead got err: %v", cidr, err)
+		}
+	}
+	if err := tr.AddIPNet(&net.IPNet{IP: net.IP{10, 1, 7, 0}, Mask: net.CIDRMask(24, 32)}, 3); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddBatch([]BatchEntry{{"10.1.8.0/24", 3}}); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("10.1.3.256/24", 3); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.3.1/24"); inf != 3 {
+		t.Errorf("Lookups should not be affected, got %v", inf)
+	}
+	if err := tr.DeleteCIDR("10.1.3.1/24"); err != nil {
+		t.Errorf("Deletes should not be affected, got err: %v", err)
+	}
+}
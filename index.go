@@ -0,0 +1,183 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+)
+
+// Index file layout, all numbers are little endian uint32:
+//
+//	header   "NRIX", version, number of nodes, number of values
+//	nodes    left, right, value for every node; 0 means no child or no value (value index is shifted by one),
+//	         indexException marks exception, node 0 is root for IPv4 prefixes, node 1 is root for IPv6 prefixes
+//	offsets  start of every value in blob plus end of the last one
+//	blob     encoded values
+const (
+	indexMagic   = "NRIX"
+	indexVersion = 1
+	indexHeader  = 16
+	indexNode    = 12
+	// indexException is value index of exception entries, it is past any value, so lookup ending there finds nothing
+	indexException = 0xffffffff
+)
+
+// WriteIndex writes tree in read-only index format which could be queried by Index without decoding (e.g. from memory mapped file).
+// Every value is encoded by enc, values could be shared by returning the same bytes.
+func (tree *Tree) WriteIndex(w io.Writer, enc func(val interface{}) ([]byte, error)) error {
+	type inode struct{ left, right, value uint32 }
+	nodes := []inode{{}, {}}
+	var values [][]byte
+	err := tree.walklive(func(key net.IP, bits int, n *node) error {
+		var val []byte
+		if n.value != exclusion {
+			var err error
+			if val, err = enc(n.value); err != nil {
+				return err
+			}
+		}
+		var i uint32 // IPv4 root
+		if len(key) == net.IPv6len {
+			i = 1
+		}
+		for d := 0; d < bits; d++ {
+			child := &nodes[i].left
+			if bitset(key, d) {
+				child = &nodes[i].right
+			}
+			if *child == 0 {
+				*child = uint32(len(nodes))
+				nodes = append(nodes, inode{})
+			}
+			i = *child
+		}
+		if n.value == exclusion {
+			nodes[i].value = indexException
+			return nil
+		}
+		values = append(values, val)
+		nodes[i].value = uint32(len(values))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	var buf [indexNode]byte
+	bw.WriteString(indexMagic)
+	binary.LittleEndian.PutUint32(buf[0:], indexVersion)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(nodes)))
+	binary.LittleEndian.PutUint32(buf[8:], uint32(len(values)))
+	bw.Write(buf[:12])
+	for _, n := range nodes {
+		binary.LittleEndian.PutUint32(buf[0:], n.left)
+		binary.LittleEndian.PutUint32(buf[4:], n.right)
+		binary.LittleEndian.PutUint32(buf[8:], n.value)
+		bw.Write(buf[:12])
+	}
+	var offset uint32
+	for _, val := range values {
+		binary.LittleEndian.PutUint32(buf[0:], offset)
+		bw.Write(buf[:4])
+		offset += uint32(len(val))
+	}
+	binary.LittleEndian.PutUint32(buf[0:], offset)
+	bw.Write(buf[:4])
+	for _, val := range values {
+		bw.Write(val)
+	}
+	return bw.Flush()
+}
+
+// Index is read-only tree written by WriteIndex. It is queried directly on its data, so it is safe for concurrent use and loads instantly.
+type Index struct {
+	data    []byte
+	nodes   []byte
+	offsets []byte
+	blob    []byte
+	closer  func() error
+}
+
+// NewIndex checks data written by WriteIndex and returns Index using it. Data should not be modified while Index is used.
+func NewIndex(data []byte) (*Index, error) {
+	if len(data) < indexHeader || string(data[:4]) != indexMagic || binary.LittleEndian.Uint32(data[4:]) != indexVersion {
+		return nil, ErrBadFormat
+	}
+	nodes := uint64(binary.LittleEndian.Uint32(data[8:]))
+	values := uint64(binary.LittleEndian.Uint32(data[12:]))
+	size := uint64(indexHeader) + nodes*indexNode + (values+1)*4
+	if nodes < 2 || uint64(len(data)) < size {
+		return nil, ErrBadFormat
+	}
+	idx := &Index{
+		data:    data,
+		nodes:   data[indexHeader : indexHeader+nodes*indexNode],
+		offsets: data[indexHeader+nodes*indexNode : size],
+		blob:    data[size:],
+	}
+	if uint64(binary.LittleEndian.Uint32(idx.offsets[values*4:])) != uint64(len(idx.blob)) {
+		return nil, ErrBadFormat
+	}
+	return idx, nil
+}
+
+// FindCIDR traverses index and returns encoded value of longest prefix covering IP/mask or nil if there is none.
+// Returned slice points into index data and should not be modified.
+func (idx *Index) FindCIDR(cidr string) ([]byte, error) {
+	return idx.FindCIDRb([]byte(cidr))
+}
+
+func (idx *Index) FindCIDRb(cidr []byte) ([]byte, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	bits := masklen(mask)
+	count := uint32(len(idx.nodes) / indexNode)
+	var i, found uint32 // IPv4 root
+	if len(key) == net.IPv6len {
+		i = 1
+	}
+	for d := 0; ; d++ {
+		n := idx.nodes[i*indexNode : i*indexNode+indexNode]
+		if v := binary.LittleEndian.Uint32(n[8:]); v != 0 {
+			found = v
+		}
+		if d == bits {
+			break
+		}
+		if bitset(key, d) {
+			i = binary.LittleEndian.Uint32(n[4:])
+		} else {
+			i = binary.LittleEndian.Uint32(n[0:])
+		}
+		if i == 0 || i >= count {
+			break
+		}
+	}
+	if found == 0 || uint64(found)*4+4 > uint64(len(idx.offsets)) {
+		return nil, nil
+	}
+	start := binary.LittleEndian.Uint32(idx.offsets[(found-1)*4:])
+	end := binary.LittleEndian.Uint32(idx.offsets[found*4:])
+	if start > end || int(end) > len(idx.blob) {
+		return nil, ErrBadFormat
+	}
+	return idx.blob[start:end:end], nil
+}
+
+// Close releases data of index opened by OpenIndex, index should not be used after that.
+func (idx *Index) Close() error {
+	if idx.closer == nil {
+		return nil
+	}
+	err := idx.closer()
+	idx.closer, idx.data, idx.nodes, idx.offsets, idx.blob = nil, nil, nil, nil, nil
+	return err
+}
@@ -0,0 +1,18 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+//go:build !unix
+
+package nradix
+
+import "os"
+
+// OpenIndex reads index file written by WriteIndex, memory mapping is only used on unix systems.
+func OpenIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewIndex(data)
+}
@@ -0,0 +1,58 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "a")
+	tr.AddCIDR("10.1.0.0/16", "b")
+	tr.AddCIDR("10.1.2.3", "c")
+	tr.AddCIDR("2001:db8::/32", "d")
+	tr.AddCIDR("0.0.0.0/0", "e")
+
+	var buf bytes.Buffer
+	err := tr.WriteIndex(&buf, func(val interface{}) ([]byte, error) {
+		return []byte(fmt.Sprint(val)), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tree.idx")
+	if err = os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := OpenIndex(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idx.Close()
+
+	for ip, val := range map[string]string{"10.2.0.1": "a", "10.1.0.1": "b", "10.1.2.3": "c", "10.1.2.0/24": "b", "2001:db8::1": "d", "11.0.0.1": "e", "dead::1": ""} {
+		v, err := idx.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if string(v) != val {
+			t.Errorf("Wrong value for %s, expected %q, got %q", ip, val, v)
+		}
+	}
+
+	for i := 0; i < indexHeader+2*indexNode; i++ {
+		if _, err = NewIndex(buf.Bytes()[:i]); err != ErrBadFormat {
+			t.Errorf("Truncated data of %d bytes should not be accepted", i)
+		}
+	}
+	if _, err = NewIndex(buf.Bytes()[:buf.Len()-1]); err != ErrBadFormat {
+		t.Error("Truncated values should not be accepted")
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+//go:build unix
+
+package nradix
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenIndex memory maps index file written by WriteIndex, Close should be called to unmap it.
+func OpenIndex(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() == 0 {
+		return nil, ErrBadFormat
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(st.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := NewIndex(data)
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	idx.closer = func() error { return syscall.Munmap(data) }
+	return idx, nil
+}
@@ -0,0 +1,48 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Interner keeps single copy of every distinct value, so trees mapping lots of prefixes to a few values (country codes, ASNs)
+// hold one boxed value instead of one per prefix. Values are never removed from Interner, it is safe for concurrent use and could be shared by many trees.
+type Interner struct {
+	mu     sync.Mutex
+	values map[interface{}]interface{}
+}
+
+// NewInterner creates empty Interner.
+func NewInterner() *Interner {
+	return &Interner{values: make(map[interface{}]interface{})}
+}
+
+// Intern returns previously interned value equal to val or val itself if there is none. Values which could not be compared (slices, maps) are returned as is.
+func (in *Interner) Intern(val interface{}) interface{} {
+	if val == nil || !reflect.ValueOf(val).Comparable() {
+		return val
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if v, ok := in.values[val]; ok {
+		return v
+	}
+	in.values[val] = val
+	return val
+}
+
+// Len returns number of distinct values interned.
+func (in *Interner) Len() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return len(in.values)
+}
+
+// SetInterner makes tree pass every value it stores through in, nil turns interning off. Values stored before are not changed.
+func (tree *Tree) SetInterner(in *Interner) {
+	tree.interner = in
+}
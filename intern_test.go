@@ -0,0 +1,46 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestInterner(t *testing.T) {
+	in := NewInterner()
+	a := in.Intern(strings.Repeat("x", 3)).(string)
+	b := in.Intern(strings.Repeat("x", 3)).(string)
+	if unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Error("Equal values should be interned to the same one")
+	}
+	if s := in.Intern([]int{1}); len(s.([]int)) != 1 {
+		t.Errorf("Uncomparable value should be returned as is, got %v", s)
+	}
+	if in.Len() != 1 {
+		t.Errorf("Wrong number of interned values, expected 1, got %d", in.Len())
+	}
+
+	tr := NewTree(0)
+	tr.SetInterner(in)
+	for i := 0; i < 100; i++ {
+		tr.AddCIDR(fmt.Sprintf("10.%d.0.0/16", i), fmt.Sprintf("AS%d", i%3))
+	}
+	tr.AddCIDR("dead::/16", strings.Repeat("x", 3))
+	if in.Len() != 4 {
+		t.Errorf("Wrong number of interned values, expected 4, got %d", in.Len())
+	}
+	first, _ := tr.FindCIDR("10.0.0.1")
+	other, _ := tr.FindCIDR("10.99.0.1")
+	if first != "AS0" || unsafe.StringData(first.(string)) != unsafe.StringData(other.(string)) {
+		t.Errorf("Tree should store interned values, got %v and %v", first, other)
+	}
+	inf, _ := tr.FindCIDR("dead::1")
+	if unsafe.StringData(inf.(string)) != unsafe.StringData(a) {
+		t.Error("Tree should reuse values interned before")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// AddIPNet adds value associated with network to the tree. Will return error for invalid network or if value already exists.
+func (tree *Tree) AddIPNet(ipnet *net.IPNet, val interface{}) error {
+	if err := tree.checkhostnet(ipnet); err != nil {
+		return err
+	}
+	if err := tree.checkredundant("add", []byte(ipnet.String()), val, false); err != nil {
+		return err
+	}
+	root, hi, lo, bits, err := tree.ipnetkey(ipnet)
+	if err != nil {
+		return wrap("parse", []byte(ipnet.String()), err)
+	}
+	return wrap("add", []byte(ipnet.String()), tree.insertkey(root, hi, lo, bits, val, false))
+}
+
+// SetIPNet sets value associated with network in the tree, overwriting existing one.
+func (tree *Tree) SetIPNet(ipnet *net.IPNet, val interface{}) error {
+	if err := tree.checkhostnet(ipnet); err != nil {
+		return err
+	}
+	if err := tree.checkredundant("set", []byte(ipnet.String()), val, true); err != nil {
+		return err
+	}
+	root, hi, lo, bits, err := tree.ipnetkey(ipnet)
+	if err != nil {
+		return wrap("parse", []byte(ipnet.String()), err)
+	}
+	return wrap("set", []byte(ipnet.String()), tree.insertkey(root, hi, lo, bits, val, true))
+}
+
+// DeleteIPNet removes value associated with network from the tree.
+func (tree *Tree) DeleteIPNet(ipnet *net.IPNet) error {
+	root, hi, lo, bits, err := tree.ipnetkey(ipnet)
+	if err != nil {
+		return wrap("parse", []byte(ipnet.String()), err)
+	}
+	return wrap("delete", []byte(ipnet.String()), tree.deletekey(root, hi, lo, bits, false))
+}
+
+// FindIPNet returns previously saved information in longest prefix covering network.
+func (tree *Tree) FindIPNet(ipnet *net.IPNet) (interface{}, error) {
+	root, hi, lo, bits, err := tree.ipnetkey(ipnet)
+	if err != nil {
+		return nil, wrap("parse", []byte(ipnet.String()), err)
+	}
+	return tree.findkey(root, hi, lo, bits), nil
+}
+
+// FindIP returns previously saved information in longest prefix covering IP, IPv4 could be given in 4 or 16 bytes form.
+func (tree *Tree) FindIP(ip net.IP) (interface{}, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if len(ip) != net.IPv6len {
+		return nil, wrap("parse", []byte(ip.String()), ErrBadIP)
+	}
+	hi, lo := loadkey(ip)
+	return tree.findkey(tree.rootof(ip), hi, lo, len(ip)*8), nil
+}
+
+// ipnetkey returns root of network family, its address loaded as by loadkey and prefix length.
+func (tree *Tree) ipnetkey(ipnet *net.IPNet) (*node, uint64, uint64, int, error) {
+	if ipnet == nil {
+		return nil, 0, 0, 0, ErrBadIP
+	}
+	bits, size := ipnet.Mask.Size()
+	ip := ipnet.IP.To16()
+	if size == 8*net.IPv4len {
+		ip = ipnet.IP.To4()
+	}
+	if ip == nil || size == 0 {
+		return nil, 0, 0, 0, ErrBadIP
+	}
+	hi, lo := loadkey(ip.Mask(ipnet.Mask))
+	if len(ip) == net.IPv6len {
+		root, hi, lo, bits := tree.key6(hi, lo, bits)
+		return root, hi, lo, bits, nil
+	}
+	return tree.root, hi, lo, bits, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIPNet(t *testing.T) {
+	tr := NewTree(0)
+	_, ipnet, _ := net.ParseCIDR("10.0.0.0/8")
+	if err := tr.AddIPNet(ipnet, 1); err != nil {
+		t.Error(err)
+	}
+	// IPv4 in 16 bytes form
+	if err := tr.AddIPNet(&net.IPNet{IP: net.ParseIP("10.1.2.3"), Mask: net.CIDRMask(16, 32)}, 2); err != nil {
+		t.Error(err)
+	}
+	_, ipnet, _ = net.ParseCIDR("dead::/16")
+	if err := tr.AddIPNet(ipnet, 3); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddIPNet(ipnet, 4); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if err := tr.AddIPNet(&net.IPNet{IP: net.ParseIP("dead::"), Mask: net.IPMask{0xff, 0, 0xff, 0}}, 4); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+	if err := tr.AddIPNet(nil, 4); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+
+	for _, tc := range []struct {
+		ip  net.IP
+		val interface{}
+	}{{net.ParseIP("10.1.2.3"), 2}, {net.IP{10, 2, 0, 1}, 1}, {net.ParseIP("11.0.0.1"), nil}, {net.ParseIP("dead::1"), 3}} {
+		inf, err := tr.FindIP(tc.ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != tc.val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", tc.ip, tc.val, inf)
+		}
+	}
+	if _, err := tr.FindIP(net.IP{1, 2}); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+	_, ipnet, _ = net.ParseCIDR("10.1.0.0/15")
+	inf, err := tr.FindIPNet(ipnet)
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+
+	_, ipnet, _ = net.ParseCIDR("10.1.0.0/16")
+	if err := tr.SetIPNet(ipnet, 5); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.0.1"); inf.(int) != 5 {
+		t.Errorf("Wrong value, expected 5, got %v", inf)
+	}
+	if err := tr.DeleteIPNet(ipnet); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteIPNet(ipnet); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if tr.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", tr.Len())
+	}
+}
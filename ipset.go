@@ -0,0 +1,78 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+)
+
+// SetFormat selects syntax used by ExportIPSet.
+type SetFormat int
+
+const (
+	// FormatIPSet produces input for "ipset restore".
+	FormatIPSet SetFormat = iota
+	// FormatNFTables produces input for "nft -f".
+	FormatNFTables
+)
+
+// IPSetOptions controls ExportIPSet output, zero value produces ipset elements of all entries.
+type IPSetOptions struct {
+	Format SetFormat
+	// Set6 names set for IPv6 prefixes as sets are family specific, set name with "6" appended is used if it is empty.
+	Set6 string
+	// Table is nftables family and table holding sets, "inet filter" is used if it is empty.
+	Table string
+	// Create adds commands creating sets before their elements.
+	Create bool
+	// Filter selects entries to export, all entries are exported if it is nil.
+	Filter func(prefix netip.Prefix, val interface{}) bool
+}
+
+// ExportIPSet writes prefixes stored in the tree as ipset or nftables set elements, IPv4 prefixes go to setName set and IPv6 ones to set named by opts.Set6.
+func (tree *Tree) ExportIPSet(w io.Writer, setName string, opts *IPSetOptions) error {
+	if opts == nil {
+		opts = &IPSetOptions{}
+	}
+	set6 := opts.Set6
+	if set6 == "" {
+		set6 = setName + "6"
+	}
+	table := opts.Table
+	if table == "" {
+		table = "inet filter"
+	}
+
+	bw := bufio.NewWriter(w)
+	if opts.Create {
+		switch opts.Format {
+		case FormatNFTables:
+			fmt.Fprintf(bw, "add set %s %s { type ipv4_addr; flags interval; auto-merge; }\n", table, setName)
+			fmt.Fprintf(bw, "add set %s %s { type ipv6_addr; flags interval; auto-merge; }\n", table, set6)
+		default:
+			fmt.Fprintf(bw, "create %s hash:net family inet -exist\n", setName)
+			fmt.Fprintf(bw, "create %s hash:net family inet6 -exist\n", set6)
+		}
+	}
+	for p, v := range tree.Sorted() {
+		if opts.Filter != nil && !opts.Filter(p, v) {
+			continue
+		}
+		name := setName
+		if p.Addr().Is6() {
+			name = set6
+		}
+		switch opts.Format {
+		case FormatNFTables:
+			fmt.Fprintf(bw, "add element %s %s { %s }\n", table, name, p)
+		default:
+			fmt.Fprintf(bw, "add %s %s\n", name, p)
+		}
+	}
+	return bw.Flush()
+}
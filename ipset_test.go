@@ -0,0 +1,53 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+)
+
+func TestExportIPSet(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "deny")
+	tr.AddCIDR("192.168.1.1", "deny")
+	tr.AddCIDR("2001:db8::/32", "deny")
+	tr.AddCIDR("172.16.0.0/12", "allow")
+
+	var buf bytes.Buffer
+	err := tr.ExportIPSet(&buf, "blocked", &IPSetOptions{
+		Create: true,
+		Filter: func(prefix netip.Prefix, val interface{}) bool {
+			return val == "deny"
+		},
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	expected := `create blocked hash:net family inet -exist
+create blocked6 hash:net family inet6 -exist
+add blocked 10.0.0.0/8
+add blocked 192.168.1.1/32
+add blocked6 2001:db8::/32
+`
+	if buf.String() != expected {
+		t.Errorf("Wrong ipset output, expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	err = tr.ExportIPSet(&buf, "allowed", &IPSetOptions{Format: FormatNFTables, Set6: "allowed_v6", Table: "inet fw"})
+	if err != nil {
+		t.Error(err)
+	}
+	expected = `add element inet fw allowed { 10.0.0.0/8 }
+add element inet fw allowed { 172.16.0.0/12 }
+add element inet fw allowed { 192.168.1.1/32 }
+add element inet fw allowed_v6 { 2001:db8::/32 }
+`
+	if buf.String() != expected {
+		t.Errorf("Wrong nftables output, expected\n%s\ngot\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+)
+
+// MarshalJSON encodes tree as JSON object mapping CIDRs to values.
+func (tree *Tree) MarshalJSON() ([]byte, error) {
+	return tree.MarshalJSONWith(func(val interface{}) (interface{}, error) {
+		return val, nil
+	})
+}
+
+// MarshalJSONWith encodes tree as JSON object mapping CIDRs to values, enc converts every value to what is passed to json.Marshal.
+// Exception entries are encoded as "!cidr" keys with null value.
+func (tree *Tree) MarshalJSONWith(enc func(val interface{}) (interface{}, error)) ([]byte, error) {
+	buf := []byte{'{'}
+	err := tree.walklive(func(key net.IP, bits int, n *node) error {
+		prefix := newentry(key, bits, nil).Prefix.String()
+		data := []byte("null")
+		if n.value == exclusion {
+			prefix = "!" + prefix
+		} else {
+			val, err := enc(n.value)
+			if err != nil {
+				return err
+			}
+			if data, err = json.Marshal(val); err != nil {
+				return err
+			}
+		}
+		if len(buf) > 1 {
+			buf = append(buf, ',')
+		}
+		cidr, _ := json.Marshal(prefix)
+		buf = append(buf, cidr...)
+		buf = append(buf, ':')
+		buf = append(buf, data...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, '}'), nil
+}
+
+// UnmarshalJSON replaces content of the tree with JSON object mapping CIDRs to values. Values are decoded as by json.Unmarshal into interface{}.
+func (tree *Tree) UnmarshalJSON(data []byte) error {
+	return tree.UnmarshalJSONWith(data, func(raw json.RawMessage) (interface{}, error) {
+		var val interface{}
+		err := json.Unmarshal(raw, &val)
+		return val, err
+	})
+}
+
+// UnmarshalJSONWith replaces content of the tree with JSON object mapping CIDRs to values using dec to decode values.
+// Keys of "!cidr" form are stored as exceptions. Tree is left untouched if data could not be decoded, its settings and hooks are kept and entries are checked as by AddCIDR.
+func (tree *Tree) UnmarshalJSONWith(data []byte, dec func(raw json.RawMessage) (interface{}, error)) error {
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	decoded := NewTree(0)
+	decoded.strict = tree.strict
+	for cidr, raw := range entries {
+		if strings.HasPrefix(cidr, "!") {
+			if err := decoded.ExcludeCIDR(cidr[1:]); err != nil {
+				return err
+			}
+			continue
+		}
+		val, err := dec(raw)
+		if err != nil {
+			return err
+		}
+		if err = decoded.AddCIDR(cidr, val); err != nil {
+			return err
+		}
+	}
+	// object keys come in no particular order, so redundancy is checked once all entries are known
+	if tree.redundant != nil {
+		if r := decoded.Redundant(tree.redundant); len(r) > 0 {
+			return wrap("add", []byte(r[0].Prefix.String()), ErrRedundant)
+		}
+	}
+	tree.adopt(decoded)
+	return nil
+}
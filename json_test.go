@@ -0,0 +1,121 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "a")
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", true)
+
+	data, err := json.Marshal(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"10.0.0.0/8":"a","10.1.0.0/16":2,"dead::/16":true}`
+	if string(data) != expected {
+		t.Errorf("Wrong JSON, expected %s, got %s", expected, data)
+	}
+
+	restored := NewTree(0)
+	if err = json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	for ip, val := range map[string]interface{}{"10.2.0.1": "a", "10.1.0.1": 2.0, "dead::1": true} {
+		inf, err := restored.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	if err = json.Unmarshal([]byte(`{"10.0.0.0/8":1,"bad":2}`), restored); err == nil {
+		t.Error("Should have gotten error for bad CIDR")
+	}
+	if restored.Len() != 3 {
+		t.Error("Failed decoding should not modify the tree")
+	}
+
+	if err = json.Unmarshal([]byte(`{}`), restored); err != nil || restored.Len() != 0 {
+		t.Errorf("Empty object should produce empty tree, got %v with %d entries", err, restored.Len())
+	}
+	data, err = json.Marshal(restored)
+	if err != nil || string(data) != "{}" {
+		t.Errorf("Wrong JSON for empty tree, got %s, %v", data, err)
+	}
+}
+
+func TestJSONWith(t *testing.T) {
+	type rule struct {
+		Action string `json:"action"`
+	}
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", &rule{"deny"})
+
+	data, err := tr.MarshalJSONWith(func(val interface{}) (interface{}, error) {
+		return val.(*rule).Action, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"10.0.0.0/8":"deny"}` {
+		t.Errorf("Wrong JSON, got %s", data)
+	}
+
+	err = tr.UnmarshalJSONWith([]byte(`{"10.0.0.0/8":{"action":"allow"}}`), func(raw json.RawMessage) (interface{}, error) {
+		r := new(rule)
+		err := json.Unmarshal(raw, r)
+		return r, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inf, err := tr.FindCIDR("10.0.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(*rule).Action != "allow" {
+		t.Errorf("Wrong value, expected allow, got %v", inf)
+	}
+}
+
+func TestJSONKeepsSettings(t *testing.T) {
+	tr := NewTree(0)
+	tr.RejectHostBits(true)
+	if err := tr.UnmarshalJSON([]byte(`{"10.0.0.0/8": "a"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.AddCIDR("10.1.2.3/24", "b"); !errors.Is(err, ErrHostBits) {
+		t.Errorf("Should have gotten ErrHostBits, instead got err: %v", err)
+	}
+	if err := tr.UnmarshalJSON([]byte(`{"10.1.2.3/24": "b"}`)); !errors.Is(err, ErrHostBits) {
+		t.Errorf("Should have gotten ErrHostBits, instead got err: %v", err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.2.3"); inf != "a" {
+		t.Errorf("Wrong value, expected a, got %v", inf)
+	}
+}
+
+func TestJSONRedundantOrder(t *testing.T) {
+	tr := NewTree(0)
+	tr.RejectRedundant(true, nil)
+	for i := 0; i < 50; i++ {
+		if err := tr.UnmarshalJSON([]byte(`{"10.1.2.0/24": "a", "10.1.0.0/16": "b", "10.0.0.0/8": "a", "11.0.0.0/8": "c"}`)); err != nil {
+			t.Fatalf("Run %d: %v", i, err)
+		}
+		err := tr.UnmarshalJSON([]byte(`{"10.1.2.0/24": "a", "10.1.0.0/16": "a", "10.0.0.0/8": "b"}`))
+		if !errors.Is(err, ErrRedundant) || err.Error() != `nradix: add "10.1.2.0/24": Prefix is covered by equal value` {
+			t.Fatalf("Run %d: should have gotten ErrRedundant for 10.1.2.0/24, instead got err: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// KeyTree is radix tree for prefixes of arbitrary bit strings (MAC OUIs, labels, custom IDs) rather than IP addresses.
+// Key is compared bit by bit from the most significant bit of its first byte, keys of different length share the tree.
+type KeyTree struct {
+	tree *Tree
+	// keylen is length of the longest key stored so far
+	keylen int
+}
+
+// NewKeyTree creates KeyTree, preallocate has the same meaning as for NewTree.
+func NewKeyTree(preallocate int) *KeyTree {
+	return &KeyTree{tree: NewTree(preallocate)}
+}
+
+// AddKey adds value associated with first bits of key to the tree. Will return error if key is shorter than bits or if value already exists.
+func (kt *KeyTree) AddKey(key []byte, bits int, val interface{}) error {
+	return kt.insert(key, bits, val, false)
+}
+
+// SetKey sets value associated with first bits of key, overwriting existing one.
+func (kt *KeyTree) SetKey(key []byte, bits int, val interface{}) error {
+	return kt.insert(key, bits, val, true)
+}
+
+// DeleteKey removes value associated with first bits of key from the tree.
+func (kt *KeyTree) DeleteKey(key []byte, bits int) error {
+	if bits < 0 || bits > len(key)*8 {
+		return wrap("parse", key, ErrBadIP)
+	}
+	n := kt.tree.root6
+	for d := 0; n != nil && d < bits; d++ {
+		n = n.child(key, d)
+	}
+	if n == nil || n.value == nil {
+		return wrap("delete", key, ErrNotFound)
+	}
+	kt.tree.setvalue(n, nil)
+	kt.tree.trim(n)
+	return nil
+}
+
+// FindKey returns value associated with the longest stored prefix of first bits of key.
+func (kt *KeyTree) FindKey(key []byte, bits int) (interface{}, error) {
+	if bits < 0 || bits > len(key)*8 {
+		return nil, wrap("parse", key, ErrBadIP)
+	}
+	var value interface{}
+	n := kt.tree.root6
+	for d := 0; n != nil; d++ {
+		if n.value != nil {
+			value = n.value
+		}
+		if d == bits {
+			break
+		}
+		n = n.child(key, d)
+	}
+	return value, nil
+}
+
+// Len returns number of entries stored in the tree.
+func (kt *KeyTree) Len() int {
+	return kt.tree.Len()
+}
+
+// Walk calls fn for every prefix stored in the tree, shorter and lower prefixes first. Key passed to fn has all bits after prefix cleared and is only valid during the call.
+// Walk stops and returns the error if fn returns one, except for SkipSubtree and Stop.
+func (kt *KeyTree) Walk(fn func(key []byte, bits int, val interface{}) error) error {
+	err := walk(kt.tree.root6, make(net.IP, kt.keylen), 0, func(key net.IP, bits int, n *node) error {
+		return fn(key[:(bits+7)/8], bits, n.value)
+	})
+	if err == Stop {
+		return nil
+	}
+	return err
+}
+
+func (kt *KeyTree) insert(key []byte, bits int, val interface{}, overwrite bool) error {
+	if bits < 0 || bits > len(key)*8 {
+		return wrap("parse", key, ErrBadIP)
+	}
+	n := kt.tree.root6
+	for d := 0; d < bits; d++ {
+		next := n.child(key, d)
+		if next == nil {
+			next = kt.tree.newnode()
+			next.parent = n
+			if bitset(key, d) {
+				n.right = next
+			} else {
+				n.left = next
+			}
+		}
+		n = next
+	}
+	if n.value != nil && !overwrite {
+		return wrap("add", key, ErrNodeBusy)
+	}
+	kt.tree.setvalue(n, val)
+	if l := (bits + 7) / 8; l > kt.keylen {
+		kt.keylen = l
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKeyTree(t *testing.T) {
+	kt := NewKeyTree(0)
+	// MAC address OUIs
+	if err := kt.AddKey([]byte{0x00, 0x1b, 0x63}, 24, "Apple"); err != nil {
+		t.Error(err)
+	}
+	if err := kt.AddKey([]byte{0x00, 0x1b, 0x63, 0x80}, 25, "Apple lab"); err != nil {
+		t.Error(err)
+	}
+	if err := kt.AddKey([]byte{0x52, 0x54, 0x00}, 24, "QEMU"); err != nil {
+		t.Error(err)
+	}
+	if err := kt.AddKey([]byte{0x52, 0x54, 0x00}, 24, "KVM"); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if err := kt.AddKey([]byte{0x52}, 9, "bad"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+
+	for mac, val := range map[[6]byte]interface{}{{0x00, 0x1b, 0x63, 0x01, 0x02, 0x03}: "Apple", {0x00, 0x1b, 0x63, 0x81, 0x02, 0x03}: "Apple lab",
+		{0x52, 0x54, 0x00, 0x12, 0x34, 0x56}: "QEMU", {0x52, 0x55, 0x00, 0x12, 0x34, 0x56}: nil} {
+		inf, err := kt.FindKey(mac[:], 48)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %x, expected %v, got %v", mac, val, inf)
+		}
+	}
+	if _, err := kt.FindKey([]byte{0}, 16); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+
+	var walked []string
+	kt.Walk(func(key []byte, bits int, val interface{}) error {
+		walked = append(walked, fmt.Sprintf("%x/%d", key, bits))
+		return nil
+	})
+	if fmt.Sprint(walked) != "[001b63/24 001b6380/25 525400/24]" {
+		t.Errorf("Wrong walk, got %v", walked)
+	}
+
+	if err := kt.DeleteKey([]byte{0x00, 0x1b, 0x63}, 24); err != nil {
+		t.Error(err)
+	}
+	if err := kt.DeleteKey([]byte{0x00, 0x1b, 0x63}, 24); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if kt.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", kt.Len())
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// Entry describes prefix stored in the tree together with its value.
+type Entry struct {
+	Prefix *net.IPNet
+	Bits   int
+	Value  interface{}
+}
+
+// FindCIDRMatch works like FindCIDR but also reports which stored prefix was matched. Returns nil if nothing covers given IP/mask.
+func (tree *Tree) FindCIDRMatch(cidr string) (*Entry, error) {
+	return tree.FindCIDRMatchb([]byte(cidr))
+}
+
+func (tree *Tree) FindCIDRMatchb(cidr []byte) (*Entry, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	node, depth := tree.match(key, masklen(mask))
+	if node == nil {
+		return nil, nil
+	}
+	e := newentry(key, depth, node.value)
+	return &e, nil
+}
+
+// GetCIDR works like FindCIDR but reports separately whether any stored prefix covers IP/mask, so stored typed nil pointers can be told apart from a miss (plain nil is never stored).
+// Bad input is only reported by err, never returned as the value.
+func (tree *Tree) GetCIDR(cidr string) (interface{}, bool, error) {
+	return tree.GetCIDRb([]byte(cidr))
+}
+
+func (tree *Tree) GetCIDRb(cidr []byte) (interface{}, bool, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, false, err
+	}
+	node, _ := tree.match(key, masklen(mask))
+	if node == nil {
+		return nil, false, nil
+	}
+	return node.value, true, nil
+}
+
+// SupernetsCIDR returns all stored prefixes covering IP/mask (including the exact one), ordered from shortest to longest.
+func (tree *Tree) SupernetsCIDR(cidr string) ([]Entry, error) {
+	return tree.SupernetsCIDRb([]byte(cidr))
+}
+
+func (tree *Tree) SupernetsCIDRb(cidr []byte) ([]Entry, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	bits := masklen(mask)
+	node := tree.rootof(key)
+	for d := 0; node != nil; d++ {
+		if node.live() && node.value != exclusion {
+			entries = append(entries, newentry(key, d, node.value))
+		}
+		if d == bits {
+			break
+		}
+		node = node.child(key, d)
+	}
+	return entries, nil
+}
+
+// Ancestors returns chain of stored prefixes covering IP from the root down to the match, the last one is what FindCIDR would return.
+// Unlike SupernetsCIDR, prefixes below the match (skipped by priority matching) are not included, and nothing is returned if IP is inside an exception.
+func (tree *Tree) Ancestors(ip string) ([]Entry, error) {
+	key, mask, err := parsecidr([]byte(ip))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		entries []Entry
+		found   *node
+		matched int
+	)
+	bits := masklen(mask)
+	node := tree.rootof(key)
+	for d := 0; node != nil; d++ {
+		if node.held() {
+			entries = append(entries, newentry(key, d, node.value))
+		}
+		if node.live() && tree.better(node, found) {
+			found, matched = node, len(entries)
+		}
+		if d == bits {
+			break
+		}
+		node = node.child(key, d)
+	}
+	if found == nil || found.value == exclusion {
+		return nil, nil
+	}
+	return entries[:matched], nil
+}
+
+// FindCIDRMaxLen works like FindCIDR but ignores stored prefixes longer than maxBits.
+func (tree *Tree) FindCIDRMaxLen(cidr string, maxBits int) (interface{}, error) {
+	return tree.FindCIDRMaxLenb([]byte(cidr), maxBits)
+}
+
+func (tree *Tree) FindCIDRMaxLenb(cidr []byte, maxBits int) (interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if maxBits < 0 {
+		return nil, wrap("parse", cidr, ErrBadIP)
+	}
+	bits := masklen(mask)
+	if bits > maxBits {
+		bits = maxBits
+	}
+	node, _ := tree.match(key, bits)
+	if node == nil {
+		return nil, nil
+	}
+	return node.value, nil
+}
+
+// FindAllValues traverses tree like FindCIDR and returns all values stored in longest covered IP by AppendCIDR (or single value stored by AddCIDR).
+func (tree *Tree) FindAllValues(cidr string) ([]interface{}, error) {
+	return tree.FindAllValuesb([]byte(cidr))
+}
+
+func (tree *Tree) FindAllValuesb(cidr []byte) ([]interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	node, _ := tree.match(key, masklen(mask))
+	if node == nil {
+		return nil, nil
+	}
+	if values, ok := node.value.([]interface{}); ok {
+		return append([]interface{}(nil), values...), nil
+	}
+	return []interface{}{node.value}, nil
+}
+
+// FindShortestCIDR traverses tree and returns previously saved information in shortest (least specific) prefix covering IP.
+func (tree *Tree) FindShortestCIDR(cidr string) (interface{}, error) {
+	return tree.FindShortestCIDRb([]byte(cidr))
+}
+
+func (tree *Tree) FindShortestCIDRb(cidr []byte) (interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	node, _ := tree.first(key, masklen(mask))
+	if node == nil {
+		return nil, nil
+	}
+	return node.value, nil
+}
+
+// Contains reports whether IP (or IP/mask) is covered by any prefix stored in the tree. Invalid input is never contained.
+func (tree *Tree) Contains(ip string) bool {
+	return tree.Containsb([]byte(ip))
+}
+
+func (tree *Tree) Containsb(ip []byte) bool {
+	if isv4(ip) {
+		key, mask, err := parsecidr4(ip)
+		if err != nil {
+			return false
+		}
+		return tree.contains32(key, mask)
+	}
+	key, mask, err := parsecidr(ip)
+	if err != nil {
+		return false
+	}
+	node, _ := tree.first(key, masklen(mask))
+	return node != nil
+}
+
+// ContainsIP reports whether IP is covered by any prefix stored in the tree.
+func (tree *Tree) ContainsIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		return tree.contains32(uint32(ip4[0])<<24|uint32(ip4[1])<<16|uint32(ip4[2])<<8|uint32(ip4[3]), 0xffffffff)
+	}
+	if len(ip) != net.IPv6len {
+		return false
+	}
+	node, _ := tree.first(ip, 128)
+	return node != nil
+}
+
+// ExactMatchCIDR returns value stored exactly at given IP/mask, ignoring any covering prefixes. Returns ErrNotFound if there is no such entry.
+func (tree *Tree) ExactMatchCIDR(cidr string) (interface{}, error) {
+	return tree.ExactMatchCIDRb([]byte(cidr))
+}
+
+func (tree *Tree) ExactMatchCIDRb(cidr []byte) (interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	node := tree.lookup(key, masklen(mask))
+	if node == nil || !node.held() {
+		return nil, wrap("find", cidr, ErrNotFound)
+	}
+	return node.value, nil
+}
+
+// lookup returns node located exactly at depth bits on the path of key or nil if there is none.
+func (tree *Tree) lookup(key net.IP, bits int) *node {
+	node := tree.rootof(key)
+	for d := 0; node != nil && d < bits; d++ {
+		node = node.child(key, d)
+	}
+	return node
+}
+
+// match traverses tree along the key as deep as mask allows and returns deepest node holding a value and its depth.
+func (tree *Tree) match(key net.IP, bits int) (found *node, depth int) {
+	node := tree.rootof(key)
+	for d := 0; node != nil; d++ {
+		if node.live() && tree.better(node, found) {
+			found, depth = node, d
+		}
+		if d == bits {
+			break
+		}
+		node = node.child(key, d)
+	}
+	if found != nil && found.value == exclusion {
+		return nil, 0
+	}
+	if found != nil {
+		tree.hit(found)
+	}
+	return found, depth
+}
+
+// first traverses tree along the key and stops at the first node holding a value, returning it and its depth.
+func (tree *Tree) first(key net.IP, bits int) (*node, int) {
+	node := tree.rootof(key)
+	for d := 0; node != nil; d++ {
+		if node.held() {
+			if tree.excludes && tree.excluded(key, bits) {
+				return nil, 0
+			}
+			tree.hit(node)
+			return node, d
+		}
+		if d == bits {
+			break
+		}
+		node = node.child(key, d)
+	}
+	return nil, 0
+}
+
+func (tree *Tree) contains32(key, mask uint32) bool {
+	bit := startbit
+	node := tree.root
+	for node != nil {
+		if node.live() {
+			if tree.excludes && tree.excluded32(key, mask) {
+				return false
+			}
+			tree.hit(node)
+			return true
+		}
+		if key&bit != 0 {
+			node = node.right
+		} else {
+			node = node.left
+		}
+		if mask&bit == 0 {
+			break
+		}
+		bit >>= 1
+	}
+	return false
+}
+
+// child returns next node on the path of key when standing at depth d.
+func (n *node) child(key net.IP, d int) *node {
+	if key[d>>3]&(startbyte>>uint(d&7)) != 0 {
+		return n.right
+	}
+	return n.left
+}
+
+// parsecidr parses both IPv4 and IPv6 CIDR into key and mask of the same length (4 or 16 bytes). IPv4-mapped prefixes are returned as IPv4 ones, see key6.
+func parsecidr(cidr []byte) (net.IP, net.IPMask, error) {
+	if isv4(cidr) {
+		ip, mask, err := parsecidr4(cidr)
+		if err != nil {
+			return nil, nil, err
+		}
+		return net.IP{byte(ip >> 24), byte(ip >> 16), byte(ip >> 8), byte(ip)},
+			net.IPMask{byte(mask >> 24), byte(mask >> 16), byte(mask >> 8), byte(mask)}, nil
+	}
+	ip, mask, err := parsecidr6(cidr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ip) != len(mask) {
+		return nil, nil, wrap("parse", cidr, ErrBadIP)
+	}
+	if bits := masklen(mask); bits >= 96 && ip.To4() != nil {
+		return ip[12:], mask[12:], nil
+	}
+	return ip, mask, nil
+}
+
+// masklen returns number of leading bits set in mask.
+func masklen(mask net.IPMask) (bits int) {
+	for _, b := range mask {
+		for bit := startbyte; bit != 0 && b&bit != 0; bit >>= 1 {
+			bits++
+		}
+		if b != 0xff {
+			break
+		}
+	}
+	return bits
+}
+
+func newentry(key net.IP, bits int, value interface{}) Entry {
+	mask := net.CIDRMask(bits, len(key)*8)
+	return Entry{Prefix: &net.IPNet{IP: key.Mask(mask), Mask: mask}, Bits: bits, Value: value}
+}
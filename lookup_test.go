@@ -0,0 +1,347 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestFindMatch(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	m, err := tr.FindCIDRMatch("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if m == nil || m.Value.(int) != 2 || m.Bits != 16 || m.Prefix.String() != "10.1.0.0/16" {
+		t.Errorf("Wrong match, expected 10.1.0.0/16 => 2, got %v", m)
+	}
+
+	m, err = tr.FindCIDRMatch("10.2.0.0/16")
+	if err != nil {
+		t.Error(err)
+	}
+	if m == nil || m.Value.(int) != 1 || m.Bits != 8 || m.Prefix.String() != "10.0.0.0/8" {
+		t.Errorf("Wrong match, expected 10.0.0.0/8 => 1, got %v", m)
+	}
+
+	m, err = tr.FindCIDRMatch("dead:beef::1")
+	if err != nil {
+		t.Error(err)
+	}
+	if m == nil || m.Value.(int) != 3 || m.Prefix.String() != "dead::/16" {
+		t.Errorf("Wrong match, expected dead::/16 => 3, got %v", m)
+	}
+
+	m, err = tr.FindCIDRMatch("11.0.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if m != nil {
+		t.Errorf("Wrong match, expected nil, got %v", m)
+	}
+
+	_, err = tr.FindCIDRMatch("10.0.0.256")
+	if !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
+
+func TestExactMatch(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+
+	inf, err := tr.ExactMatchCIDR("10.1.0.0/16")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	// covered, but not stored
+	_, err = tr.ExactMatchCIDR("10.1.2.0/24")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+
+	// structural node without value
+	_, err = tr.ExactMatchCIDR("10.0.0.0/12")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+
+	inf, err = tr.ExactMatchCIDR("10.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+}
+
+func TestFindShortest(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/24", 3)
+
+	inf, err := tr.FindShortestCIDR("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+
+	tr.DeleteCIDR("10.0.0.0/8")
+	inf, err = tr.FindShortestCIDR("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	inf, err = tr.FindShortestCIDR("10.2.0.0")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+}
+
+func TestContains(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.1.0.0/16", 1)
+	tr.AddCIDR("dead::/16", 2)
+
+	if !tr.Contains("10.1.2.3") {
+		t.Error("10.1.2.3 should be contained")
+	}
+	if !tr.Contains("10.1.2.0/24") {
+		t.Error("10.1.2.0/24 should be contained")
+	}
+	if tr.Contains("10.0.0.0/8") {
+		t.Error("10.0.0.0/8 should not be contained")
+	}
+	if tr.Contains("10.2.0.1") {
+		t.Error("10.2.0.1 should not be contained")
+	}
+	if tr.Contains("10.1.0.256") {
+		t.Error("invalid IP should not be contained")
+	}
+	if !tr.Contains("dead:beef::1") {
+		t.Error("dead:beef::1 should be contained")
+	}
+	if !tr.ContainsIP(net.ParseIP("10.1.255.255")) {
+		t.Error("10.1.255.255 should be contained")
+	}
+	if !tr.ContainsIP(net.IP{10, 1, 0, 1}) {
+		t.Error("10.1.0.1 should be contained")
+	}
+	if !tr.ContainsIP(net.ParseIP("dead::1")) {
+		t.Error("dead::1 should be contained")
+	}
+	if tr.ContainsIP(net.ParseIP("beef::1")) {
+		t.Error("beef::1 should not be contained")
+	}
+	if tr.ContainsIP(nil) {
+		t.Error("nil IP should not be contained")
+	}
+}
+
+func TestGet(t *testing.T) {
+	tr := NewTree(0)
+	var p *int
+	tr.AddCIDR("10.1.0.0/16", p)
+
+	inf, ok, err := tr.GetCIDR("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if !ok || inf.(*int) != nil {
+		t.Errorf("Wrong value, expected stored nil pointer, got %v, %v", inf, ok)
+	}
+
+	inf, ok, err = tr.GetCIDR("10.2.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if ok || inf != nil {
+		t.Errorf("Wrong value, expected miss, got %v, %v", inf, ok)
+	}
+
+	_, _, err = tr.GetCIDR("bad")
+	if err == nil {
+		t.Error("Should have gotten error for bad input")
+	}
+}
+
+func TestSupernets(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/24", 3)
+	tr.AddCIDR("10.1.3.0/24", 4)
+
+	entries, err := tr.SupernetsCIDR("10.1.2.0/24")
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"}
+	if len(entries) != len(expected) {
+		t.Fatalf("Wrong number of supernets, expected %d, got %d", len(expected), len(entries))
+	}
+	for i, e := range entries {
+		if e.Prefix.String() != expected[i] || e.Value.(int) != i+1 {
+			t.Errorf("Wrong supernet, expected %s => %d, got %s => %v", expected[i], i+1, e.Prefix, e.Value)
+		}
+	}
+
+	entries, err = tr.SupernetsCIDR("11.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no supernets, got %v", entries)
+	}
+}
+
+func TestAncestors(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("0.0.0.0/0", 0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.2.0/24", 2)
+
+	entries, err := tr.Ancestors("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"0.0.0.0/0", "10.0.0.0/8", "10.1.2.0/24"}
+	if len(entries) != len(expected) {
+		t.Fatalf("Wrong number of ancestors, expected %d, got %d", len(expected), len(entries))
+	}
+	for i, e := range entries {
+		if e.Prefix.String() != expected[i] || e.Bits != masklen(e.Prefix.Mask) {
+			t.Errorf("Wrong ancestor, expected %s, got %s/%d", expected[i], e.Prefix, e.Bits)
+		}
+	}
+	inf, _ := tr.FindCIDR("10.1.2.3")
+	if entries[len(entries)-1].Value != inf {
+		t.Errorf("Last ancestor should match FindCIDR, got %v and %v", entries[len(entries)-1].Value, inf)
+	}
+
+	entries, err = tr.Ancestors("11.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 1 || entries[0].Prefix.String() != "0.0.0.0/0" {
+		t.Errorf("Wrong ancestors, got %v", entries)
+	}
+}
+
+func TestAncestorsPriority(t *testing.T) {
+	tr := NewTree(0)
+	tr.MatchByPriority(true)
+	tr.AddCIDRPriority("10.0.0.0/8", "deny", 10)
+	tr.AddCIDRPriority("10.1.0.0/16", "allow", 1)
+	tr.ExcludeCIDR("10.1.2.0/24")
+
+	entries, err := tr.Ancestors("10.1.3.4")
+	if err != nil {
+		t.Error(err)
+	}
+	inf, _ := tr.FindCIDR("10.1.3.4")
+	if len(entries) != 1 || entries[0].Value != inf {
+		t.Errorf("Last ancestor should match FindCIDR %v, got %v", inf, entries)
+	}
+
+	tr.MatchByPriority(false)
+	entries, _ = tr.Ancestors("10.1.2.3")
+	if len(entries) != 0 {
+		t.Errorf("Expected no ancestors inside exception, got %v", entries)
+	}
+	entries, _ = tr.Ancestors("10.1.3.4")
+	if len(entries) != 2 || entries[1].Value != "allow" {
+		t.Errorf("Wrong ancestors, got %v", entries)
+	}
+}
+
+func TestFindMaxLen(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/25", 3)
+
+	inf, err := tr.FindCIDRMaxLen("10.1.2.3", 24)
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	inf, err = tr.FindCIDRMaxLen("10.1.2.3", 32)
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+	inf, err = tr.FindCIDRMaxLen("10.1.2.3", 8)
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	inf, err = tr.FindCIDRMaxLen("10.1.2.3", 7)
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	_, err = tr.FindCIDRMaxLen("10.1.2.3", -1)
+	if !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
+
+func TestGetInput(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.1.0.0/16", 1)
+	tr.AddCIDR("dead::/16", 2)
+
+	for _, c := range []struct {
+		ip  string
+		val interface{}
+		ok  bool
+	}{{"10.1.2.3", 1, true}, {"10.2.0.1", nil, false}, {"dead::1", 2, true}, {"::ffff:10.1.0.1", 1, true}} {
+		val, ok, err := tr.GetCIDR(c.ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if val != c.val || ok != c.ok {
+			t.Errorf("Wrong lookup of %s, expected %v, %v, got %v, %v", c.ip, c.val, c.ok, val, ok)
+		}
+	}
+	for _, ip := range []string{"10.1.0.256", "dead::/129", "bad", ""} {
+		val, ok, err := tr.GetCIDR(ip)
+		if err == nil || val != nil || ok {
+			t.Errorf("Wrong lookup of %q, expected error only, got %v, %v, %v", ip, val, ok, err)
+		}
+		if val, err := tr.FindCIDR(ip); err == nil || val != nil {
+			t.Errorf("Wrong FindCIDR of %q, expected error only, got %v, %v", ip, val, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"container/heap"
+	"sync/atomic"
+)
+
+// SetMaxEntries limits number of entries stored in the tree, zero removes the limit. When an insert exceeds the limit, least recently matched (or stored) entries are evicted
+// until the tree is back within it. Lookups only update atomic counters, so they could still run concurrently under read lock. Exception entries count against the limit but are never evicted.
+// Entries stored before the limit was set count as least recently used. Deaggregate may exceed the limit until next insert.
+func (tree *Tree) SetMaxEntries(max int) {
+	tree.maxentries = max
+	if max <= 0 {
+		tree.lru = nil
+	}
+	tree.evict()
+}
+
+// touch marks value of n as the most recently used one.
+func (tree *Tree) touch(n *node) {
+	if tree.maxentries > 0 {
+		n.used.Store(atomic.AddUint64(&tree.clock, 1))
+	}
+}
+
+// lruentry is node queued for eviction with its use time when it was queued or last checked.
+type lruentry struct {
+	node *node
+	used uint64
+}
+
+// lruheap orders queued nodes by use time. Lookups under read lock can not fix it, so time of an entry is never later than actual one and is refreshed when entry gets to the top.
+// Removed and reused nodes are left in the heap and dropped or refreshed when they get to the top.
+type lruheap []lruentry
+
+func (h lruheap) Len() int            { return len(h) }
+func (h lruheap) Less(i, j int) bool  { return h[i].used < h[j].used }
+func (h lruheap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *lruheap) Push(x interface{}) { *h = append(*h, x.(lruentry)) }
+func (h *lruheap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+// queue adds n just stored to the eviction heap if it is kept, heap is dropped to be rebuilt by evict once stale entries outnumber live ones.
+func (tree *Tree) queue(n *node) {
+	if tree.lru == nil || n.value == exclusion {
+		return
+	}
+	if len(*tree.lru) > 2*tree.Len()+16 {
+		tree.lru = nil
+		return
+	}
+	heap.Push(tree.lru, lruentry{n, n.used.Load()})
+}
+
+// evict removes least recently used entries if there are more of them than limit allows.
+func (tree *Tree) evict() {
+	if tree.maxentries <= 0 || tree.Len() <= tree.maxentries {
+		return
+	}
+	if tree.lru == nil {
+		var h lruheap
+		for _, root := range []*node{tree.root, tree.root6} {
+			h = lruof(root, h)
+		}
+		heap.Init(&h)
+		tree.lru = &h
+	}
+	for tree.Len() > tree.maxentries && len(*tree.lru) > 0 {
+		top := &(*tree.lru)[0]
+		n := top.node
+		switch used := n.used.Load(); {
+		case n.value == nil || n.value == exclusion:
+			heap.Pop(tree.lru)
+		case used != top.used:
+			top.used = used
+			heap.Fix(tree.lru, 0)
+		default:
+			heap.Pop(tree.lru)
+			tree.evicted(n)
+			tree.store(n, nil)
+			tree.trim(n)
+		}
+	}
+}
+
+// lruof appends nodes holding values other than exceptions under n (n included) to h.
+func lruof(n *node, h lruheap) lruheap {
+	if n.value != nil && n.value != exclusion {
+		h = append(h, lruentry{n, n.used.Load()})
+	}
+	for _, c := range []*node{n.left, n.right} {
+		if c != nil {
+			h = lruof(c, h)
+		}
+	}
+	return h
+}
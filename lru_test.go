@@ -0,0 +1,105 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMaxEntries(t *testing.T) {
+	tr := NewTree(0)
+	tr.SetMaxEntries(32)
+	for i := 0; i < 32; i++ {
+		if err := tr.AddCIDR(fmt.Sprintf("10.0.%d.0/24", i), i); err != nil {
+			t.Error(err)
+		}
+	}
+	if tr.Len() != 32 {
+		t.Errorf("Wrong length, expected 32, got %d", tr.Len())
+	}
+	// keep first four entries fresh
+	for i := 0; i < 4; i++ {
+		tr.FindCIDR(fmt.Sprintf("10.0.%d.1", i))
+	}
+
+	tr.AddCIDR("dead::/16", "new")
+	if tr.Len() != 32 {
+		t.Errorf("Wrong length, expected 32, got %d", tr.Len())
+	}
+	for ip, val := range map[string]interface{}{"10.0.0.1": 0, "10.0.3.1": 3, "10.0.4.1": nil, "10.0.5.1": 5, "10.0.7.1": 7, "dead::1": "new"} {
+		if inf, _ := tr.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	tr.SetMaxEntries(10)
+	if tr.Len() != 10 {
+		t.Errorf("Wrong length, expected 10, got %d", tr.Len())
+	}
+	for _, ip := range []string{"10.0.0.1", "10.0.3.1", "10.0.7.1", "dead::1"} {
+		if inf, _ := tr.FindCIDR(ip); inf == nil {
+			t.Errorf("Recently used entry for %s should be kept", ip)
+		}
+	}
+
+	tr.SetMaxEntries(0)
+	for i := 0; i < 32; i++ {
+		tr.SetCIDR(fmt.Sprintf("11.0.%d.0/24", i), i)
+	}
+	if tr.Len() != 42 {
+		t.Errorf("Wrong length, expected 42, got %d", tr.Len())
+	}
+}
+
+func TestMaxEntriesExclusion(t *testing.T) {
+	tr := NewTree(0)
+	tr.ExcludeCIDR("10.0.0.0/24")
+	tr.SetMaxEntries(2)
+	tr.AddCIDR("10.0.1.0/24", 1)
+	tr.AddCIDR("10.0.2.0/24", 2)
+	if tr.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", tr.Len())
+	}
+	tr.AddCIDR("10.0.0.0/8", 0)
+	if inf, _ := tr.FindCIDR("10.0.0.1"); inf != nil {
+		t.Errorf("Exception should not be evicted, got %v", inf)
+	}
+	if inf, _ := tr.FindCIDR("10.0.2.1"); inf != 0 {
+		t.Errorf("Wrong value, expected 0, got %v", inf)
+	}
+}
+
+func TestMaxEntriesBulk(t *testing.T) {
+	tr := NewTree(0)
+	tr.SetMaxEntries(4)
+	other := NewTree(0)
+	for i := 0; i < 8; i++ {
+		other.AddCIDR(fmt.Sprintf("10.0.%d.0/24", i), i)
+	}
+	tr.Merge(other, nil)
+	if tr.Len() != 4 {
+		t.Errorf("Wrong length after Merge, expected 4, got %d", tr.Len())
+	}
+
+	tx := tr.Begin()
+	for i := 0; i < 8; i++ {
+		tx.SetCIDR(fmt.Sprintf("11.0.%d.0/24", i), i)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Error(err)
+	}
+	if tr.Len() != 4 {
+		t.Errorf("Wrong length after Commit, expected 4, got %d", tr.Len())
+	}
+	for i := 4; i < 8; i++ {
+		if inf, _ := tr.FindCIDR(fmt.Sprintf("11.0.%d.1", i)); inf != i {
+			t.Errorf("Wrong value, expected %d, got %v", i, inf)
+		}
+	}
+	if err := tr.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+// Package metrics counts lookups and modifications of nradix.SafeTree and exposes them together with number of entries as expvar variables, in Prometheus text format or as Prometheus collector.
+// Tree Stats walk the whole tree, so they are not part of scrapes and should be taken by SafeTree.Stats when needed.
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/asergeyev/nradix"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics keeps counters of one tree.
+type Metrics struct {
+	tree *nradix.SafeTree
+
+	lookups   atomic.Uint64
+	misses    atomic.Uint64
+	inserts   atomic.Uint64
+	deletes   atomic.Uint64
+	evictions atomic.Uint64
+}
+
+// Snapshot is state of counters and tree at some moment.
+type Snapshot struct {
+	Entries   int
+	Lookups   uint64
+	Hits      uint64
+	Misses    uint64
+	HitRatio  float64
+	Inserts   uint64
+	Deletes   uint64
+	Evictions uint64
+}
+
+// exposed lists metrics written by ServeHTTP and Collect, their names are prefixed by nradix_.
+var exposed = []struct {
+	name  string
+	kind  prometheus.ValueType
+	help  string
+	value func(s *Snapshot) uint64
+}{
+	{"entries", prometheus.GaugeValue, "Number of entries stored in the tree.", func(s *Snapshot) uint64 { return uint64(s.Entries) }},
+	{"lookups_total", prometheus.CounterValue, "Number of lookups.", func(s *Snapshot) uint64 { return s.Lookups }},
+	{"lookup_hits_total", prometheus.CounterValue, "Number of lookups which found a value.", func(s *Snapshot) uint64 { return s.Hits }},
+	{"lookup_misses_total", prometheus.CounterValue, "Number of lookups which found nothing.", func(s *Snapshot) uint64 { return s.Misses }},
+	{"inserts_total", prometheus.CounterValue, "Number of values stored in the tree.", func(s *Snapshot) uint64 { return s.Inserts }},
+	{"deletes_total", prometheus.CounterValue, "Number of entries removed from the tree.", func(s *Snapshot) uint64 { return s.Deletes }},
+	{"evictions_total", prometheus.CounterValue, "Number of entries removed because of entries limit or expiration.", func(s *Snapshot) uint64 { return s.Evictions }},
+}
+
+// descs are descriptions of exposed metrics for Prometheus registry.
+var descs = func() []*prometheus.Desc {
+	list := make([]*prometheus.Desc, len(exposed))
+	for i, metric := range exposed {
+		list[i] = prometheus.NewDesc("nradix_"+metric.name, metric.help, nil, nil)
+	}
+	return list
+}()
+
+// New starts counting modifications of the tree. Insert, delete and evict hooks of the tree are taken by Metrics, so they must not be set by anything else.
+func New(tree *nradix.SafeTree) *Metrics {
+	m := &Metrics{tree: tree}
+	tree.Update(func(t *nradix.Tree) error {
+		t.OnInsert(func(netip.Prefix, interface{}, interface{}) { m.inserts.Add(1) })
+		t.OnDelete(func(netip.Prefix, interface{}) { m.deletes.Add(1) })
+		t.OnEvict(func(netip.Prefix, interface{}) { m.evictions.Add(1) })
+		return nil
+	})
+	return m
+}
+
+// FindCIDR is SafeTree.FindCIDR counted as a lookup, which is a miss if nothing was found.
+func (m *Metrics) FindCIDR(cidr string) (interface{}, error) {
+	val, err := m.tree.FindCIDR(cidr)
+	if err == nil {
+		m.Observe(val != nil)
+	}
+	return val, err
+}
+
+func (m *Metrics) FindCIDRb(cidr []byte) (interface{}, error) {
+	val, err := m.tree.FindCIDRb(cidr)
+	if err == nil {
+		m.Observe(val != nil)
+	}
+	return val, err
+}
+
+// Observe counts a lookup made by other means than FindCIDR, found tells whether it was a hit.
+func (m *Metrics) Observe(found bool) {
+	m.lookups.Add(1)
+	if !found {
+		m.misses.Add(1)
+	}
+}
+
+// Snapshot returns current counters and number of entries in the tree, it is cheap enough to be taken by every scrape.
+func (m *Metrics) Snapshot() Snapshot {
+	s := Snapshot{
+		Lookups:   m.lookups.Load(),
+		Misses:    m.misses.Load(),
+		Inserts:   m.inserts.Load(),
+		Deletes:   m.deletes.Load(),
+		Evictions: m.evictions.Load(),
+		Entries:   m.tree.Len(),
+	}
+	// counters are loaded one by one, so a concurrent miss could be seen without its lookup
+	if s.Misses > s.Lookups {
+		s.Misses = s.Lookups
+	}
+	s.Hits = s.Lookups - s.Misses
+	if s.Lookups > 0 {
+		s.HitRatio = float64(s.Hits) / float64(s.Lookups)
+	}
+	return s
+}
+
+// Publish exports Snapshot as expvar variable with given name. Like expvar.Publish it panics if the name is already taken.
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return m.Snapshot() }))
+}
+
+// ServeHTTP writes Snapshot in Prometheus text exposition format, with metric names prefixed by nradix_.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s := m.Snapshot()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, metric := range exposed {
+		kind := "gauge"
+		if metric.kind == prometheus.CounterValue {
+			kind = "counter"
+		}
+		fmt.Fprintf(w, "# HELP nradix_%s %s\n# TYPE nradix_%s %s\nnradix_%s %d\n", metric.name, metric.help, metric.name, kind, metric.name, metric.value(&s))
+	}
+}
+
+// Describe sends descriptions of all metrics, so Metrics could be registered as prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range descs {
+		ch <- desc
+	}
+}
+
+// Collect sends current values of all metrics taken from one Snapshot.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	s := m.Snapshot()
+	for i, metric := range exposed {
+		ch <- prometheus.MustNewConstMetric(descs[i], metric.kind, float64(metric.value(&s)))
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/asergeyev/nradix"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetrics(t *testing.T) {
+	tr := nradix.NewSafeTree(0)
+	m := New(tr)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.SetCIDR("10.1.0.0/16", 3)
+	tr.DeleteCIDR("10.0.0.0/8")
+
+	for _, ip := range []string{"10.1.2.3", "10.1.0.1", "11.0.0.1"} {
+		if _, err := m.FindCIDR(ip); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := m.FindCIDR("10.0.0.256"); err == nil {
+		t.Error("Should have gotten error for bad input")
+	}
+	m.Observe(true)
+
+	s := m.Snapshot()
+	if s.Entries != 1 || s.Lookups != 4 || s.Hits != 3 || s.Misses != 1 || s.HitRatio != 0.75 {
+		t.Errorf("Wrong lookup counters, got %+v", s)
+	}
+	if s.Inserts != 3 || s.Deletes != 1 || s.Evictions != 0 {
+		t.Errorf("Wrong modification counters, got %+v", s)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{"nradix_entries 1\n", "nradix_lookup_misses_total 1\n", "# TYPE nradix_inserts_total counter\n", "nradix_inserts_total 3\n"} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Missing %q in output:\n%s", line, body)
+		}
+	}
+
+	m.Publish("nradix_test")
+	var published Snapshot
+	if err := json.Unmarshal([]byte(expvar.Get("nradix_test").String()), &published); err != nil {
+		t.Error(err)
+	}
+	if published.Lookups != 4 || published.Entries != 1 {
+		t.Errorf("Wrong published snapshot, got %+v", published)
+	}
+}
+
+func TestMetricsCollector(t *testing.T) {
+	tr := nradix.NewSafeTree(0)
+	m := New(tr)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	m.FindCIDR("10.1.2.3")
+	m.FindCIDR("11.0.0.1")
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(m); err != nil {
+		t.Fatal(err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]float64)
+	for _, f := range families {
+		metric := f.GetMetric()[0]
+		if c := metric.GetCounter(); c != nil {
+			values[f.GetName()] = c.GetValue()
+		} else {
+			values[f.GetName()] = metric.GetGauge().GetValue()
+		}
+	}
+	for name, val := range map[string]float64{"nradix_entries": 2, "nradix_lookups_total": 2, "nradix_lookup_misses_total": 1, "nradix_inserts_total": 2} {
+		if values[name] != val {
+			t.Errorf("Wrong value of %s, expected %v, got %v", name, val, values[name])
+		}
+	}
+	if len(values) != len(exposed) {
+		t.Errorf("Wrong number of metrics, expected %d, got %d", len(exposed), len(values))
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+// Package mrt loads MRT TABLE_DUMP_V2 RIB dumps (RFC 6396, as published by RouteViews and RIPE RIS) into nradix.Tree,
+// mapping every prefix to its origin AS number.
+package mrt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/netip"
+
+	"github.com/asergeyev/nradix"
+)
+
+const (
+	typeTableDumpV2 = 13
+
+	subtypeRIBIPv4Unicast        = 2
+	subtypeRIBIPv6Unicast        = 4
+	subtypeRIBIPv4UnicastAddPath = 8
+	subtypeRIBIPv6UnicastAddPath = 10
+
+	attrASPath = 2
+	attrExtLen = 0x10
+)
+
+var ErrBadRecord = errors.New("Bad MRT record")
+
+// Load reads MRT records from r (already decompressed) and sets uint32 origin ASN for every unicast RIB prefix.
+// Records of other types are skipped, origin is taken from the first RIB entry having AS_PATH, for AS_SET origin its last listed AS is used.
+// Returns number of prefixes stored.
+func Load(tree *nradix.Tree, r io.Reader) (int, error) {
+	var (
+		header [12]byte
+		msg    []byte
+		count  int
+	)
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF {
+				return count, nil
+			}
+			return count, err
+		}
+		typ := binary.BigEndian.Uint16(header[4:6])
+		subtype := binary.BigEndian.Uint16(header[6:8])
+		length := binary.BigEndian.Uint32(header[8:12])
+		if cap(msg) < int(length) {
+			msg = make([]byte, length)
+		}
+		msg = msg[:length]
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return count, err
+		}
+		if typ != typeTableDumpV2 {
+			continue
+		}
+		var (
+			addrlen int
+			addpath bool
+		)
+		switch subtype {
+		case subtypeRIBIPv4Unicast:
+			addrlen = 4
+		case subtypeRIBIPv6Unicast:
+			addrlen = 16
+		case subtypeRIBIPv4UnicastAddPath:
+			addrlen, addpath = 4, true
+		case subtypeRIBIPv6UnicastAddPath:
+			addrlen, addpath = 16, true
+		default:
+			continue
+		}
+		prefix, asn, ok, err := parseRIB(msg, addrlen, addpath)
+		if err != nil {
+			return count, err
+		}
+		if !ok {
+			continue
+		}
+		if err = tree.SetCIDR(prefix.String(), asn); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+// parseRIB decodes RIB_IPV4_UNICAST or RIB_IPV6_UNICAST message and returns prefix with its origin AS.
+func parseRIB(msg []byte, addrlen int, addpath bool) (netip.Prefix, uint32, bool, error) {
+	// sequence number, prefix length
+	if len(msg) < 5 {
+		return netip.Prefix{}, 0, false, ErrBadRecord
+	}
+	bits := int(msg[4])
+	size := (bits + 7) / 8
+	if bits > addrlen*8 || len(msg) < 5+size+2 {
+		return netip.Prefix{}, 0, false, ErrBadRecord
+	}
+	var key [16]byte
+	copy(key[:], msg[5:5+size])
+	var addr netip.Addr
+	if addrlen == 4 {
+		addr = netip.AddrFrom4([4]byte(key[:4]))
+	} else {
+		addr = netip.AddrFrom16(key)
+	}
+	prefix := netip.PrefixFrom(addr, bits)
+
+	entries := int(binary.BigEndian.Uint16(msg[5+size:]))
+	msg = msg[5+size+2:]
+	for ; entries > 0; entries-- {
+		// peer index, originated time, optional path identifier, attribute length
+		head := 8
+		if addpath {
+			head += 4
+		}
+		if len(msg) < head {
+			return prefix, 0, false, ErrBadRecord
+		}
+		attrlen := int(binary.BigEndian.Uint16(msg[head-2:]))
+		if len(msg) < head+attrlen {
+			return prefix, 0, false, ErrBadRecord
+		}
+		asn, ok, err := origin(msg[head : head+attrlen])
+		if err != nil || ok {
+			return prefix, asn, ok, err
+		}
+		msg = msg[head+attrlen:]
+	}
+	return prefix, 0, false, nil
+}
+
+// origin finds AS_PATH among BGP path attributes and returns its last AS number.
+func origin(attrs []byte) (uint32, bool, error) {
+	for len(attrs) > 0 {
+		if len(attrs) < 3 {
+			return 0, false, ErrBadRecord
+		}
+		flags, code := attrs[0], attrs[1]
+		var length, head int
+		if flags&attrExtLen != 0 {
+			if len(attrs) < 4 {
+				return 0, false, ErrBadRecord
+			}
+			length, head = int(binary.BigEndian.Uint16(attrs[2:4])), 4
+		} else {
+			length, head = int(attrs[2]), 3
+		}
+		if len(attrs) < head+length {
+			return 0, false, ErrBadRecord
+		}
+		if code == attrASPath {
+			return lastAS(attrs[head : head+length])
+		}
+		attrs = attrs[head+length:]
+	}
+	return 0, false, nil
+}
+
+// lastAS returns last AS number of AS_PATH encoded with 4-byte AS numbers as required by TABLE_DUMP_V2.
+func lastAS(path []byte) (uint32, bool, error) {
+	var (
+		asn uint32
+		ok  bool
+	)
+	for len(path) > 0 {
+		if len(path) < 2 {
+			return 0, false, ErrBadRecord
+		}
+		n := int(path[1])
+		if len(path) < 2+n*4 {
+			return 0, false, ErrBadRecord
+		}
+		if n > 0 {
+			asn, ok = binary.BigEndian.Uint32(path[2+(n-1)*4:]), true
+		}
+		path = path[2+n*4:]
+	}
+	return asn, ok, nil
+}
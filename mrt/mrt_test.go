@@ -0,0 +1,83 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package mrt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/asergeyev/nradix"
+)
+
+func record(typ, subtype uint16, msg []byte) []byte {
+	buf := make([]byte, 12, 12+len(msg))
+	binary.BigEndian.PutUint16(buf[4:], typ)
+	binary.BigEndian.PutUint16(buf[6:], subtype)
+	binary.BigEndian.PutUint32(buf[8:], uint32(len(msg)))
+	return append(buf, msg...)
+}
+
+func rib(prefix []byte, bits byte, addpath bool, paths ...[]uint32) []byte {
+	msg := []byte{0, 0, 0, 1, bits}
+	msg = append(msg, prefix...)
+	msg = binary.BigEndian.AppendUint16(msg, uint16(len(paths)))
+	for _, path := range paths {
+		// ORIGIN attribute followed by AS_PATH with single AS_SEQUENCE
+		attrs := []byte{0x40, 1, 1, 0}
+		attrs = append(attrs, 0x40, 2, byte(2+4*len(path)), 2, byte(len(path)))
+		for _, asn := range path {
+			attrs = binary.BigEndian.AppendUint32(attrs, asn)
+		}
+		msg = append(msg, 0, 0, 0, 0, 0, 0)
+		if addpath {
+			msg = append(msg, 0, 0, 0, 7)
+		}
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(attrs)))
+		msg = append(msg, attrs...)
+	}
+	return msg
+}
+
+func TestLoad(t *testing.T) {
+	var dump bytes.Buffer
+	dump.Write(record(typeTableDumpV2, 1, []byte{1, 2, 3, 4, 0, 0, 0, 0})) // peer index table is skipped
+	dump.Write(record(typeTableDumpV2, subtypeRIBIPv4Unicast, rib([]byte{1, 0, 0}, 24, false, []uint32{3356, 13335})))
+	dump.Write(record(typeTableDumpV2, subtypeRIBIPv4Unicast, rib([]byte{8}, 8, false, []uint32{174, 3356})))
+	dump.Write(record(typeTableDumpV2, subtypeRIBIPv6Unicast, rib([]byte{0x20, 0x01, 0x0d, 0xb8}, 32, false, []uint32{6939, 64500})))
+	dump.Write(record(typeTableDumpV2, subtypeRIBIPv4UnicastAddPath, rib([]byte{9, 9}, 16, true, []uint32{1, 2, 64501})))
+	dump.Write(record(12, 1, []byte{1, 2, 3})) // old TABLE_DUMP is skipped
+
+	tr := nradix.NewTree(0)
+	n, err := Load(tr, &dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("Wrong number of prefixes, expected 4, got %d", n)
+	}
+	for ip, asn := range map[string]uint32{"1.0.0.1": 13335, "8.8.8.8": 3356, "2001:db8::1": 64500, "9.9.1.1": 64501} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != asn {
+			t.Errorf("Wrong origin for %s, expected %d, got %v", ip, asn, inf)
+		}
+	}
+}
+
+func TestLoadBad(t *testing.T) {
+	tr := nradix.NewTree(0)
+	msg := rib([]byte{1, 0, 0}, 24, false, []uint32{3356})
+	_, err := Load(tr, bytes.NewReader(record(typeTableDumpV2, subtypeRIBIPv4Unicast, msg[:len(msg)-2])))
+	if err != ErrBadRecord {
+		t.Errorf("Should have gotten ErrBadRecord, instead got err: %v", err)
+	}
+	_, err = Load(tr, bytes.NewReader(record(typeTableDumpV2, subtypeRIBIPv4Unicast, msg)[:20]))
+	if err == nil {
+		t.Error("Should have gotten error for truncated record")
+	}
+}
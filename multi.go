@@ -0,0 +1,129 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"slices"
+)
+
+// MultiTree keeps a number of named tables (VRF style) sharing single node arena and free list, so memory released by one table is reused by others.
+// Thread safety is not guaranteed, the same as for Tree: lookups could run concurrently, modifications need exclusive access.
+type MultiTree struct {
+	tree   *Tree
+	tables map[string]*table
+}
+
+type table struct {
+	root, root6 *node
+}
+
+// NewMultiTree creates MultiTree, preallocate has the same meaning as for NewTree and is shared by all tables.
+func NewMultiTree(preallocate int) *MultiTree {
+	return &MultiTree{tree: NewTree(preallocate), tables: make(map[string]*table)}
+}
+
+// AddCIDR adds value associated with IP/mask to the table, creating table if needed. Will return error for invalid CIDR or if value already exists.
+func (mt *MultiTree) AddCIDR(table, cidr string, val interface{}) error {
+	return mt.AddCIDRb(table, []byte(cidr), val)
+}
+
+func (mt *MultiTree) AddCIDRb(table string, cidr []byte, val interface{}) error {
+	// bad input should not leave empty table behind
+	if _, _, err := parsecidr(cidr); err != nil {
+		return err
+	}
+	mt.use(table, true)
+	return mt.tree.AddCIDRb(cidr, val)
+}
+
+// SetCIDR sets value associated with IP/mask in the table, creating table if needed.
+func (mt *MultiTree) SetCIDR(table, cidr string, val interface{}) error {
+	return mt.SetCIDRb(table, []byte(cidr), val)
+}
+
+func (mt *MultiTree) SetCIDRb(table string, cidr []byte, val interface{}) error {
+	// bad input should not leave empty table behind
+	if _, _, err := parsecidr(cidr); err != nil {
+		return err
+	}
+	mt.use(table, true)
+	return mt.tree.SetCIDRb(cidr, val)
+}
+
+// DeleteCIDR removes value associated with IP/mask from the table.
+func (mt *MultiTree) DeleteCIDR(table, cidr string) error {
+	return mt.DeleteCIDRb(table, []byte(cidr))
+}
+
+func (mt *MultiTree) DeleteCIDRb(table string, cidr []byte) error {
+	if !mt.use(table, false) {
+		return wrap("delete", cidr, ErrNotFound)
+	}
+	return mt.tree.DeleteCIDRb(cidr)
+}
+
+// FindCIDR traverses the table and returns previously saved information in longest covered IP. Unknown table holds nothing.
+func (mt *MultiTree) FindCIDR(table, cidr string) (interface{}, error) {
+	return mt.FindCIDRb(table, []byte(cidr))
+}
+
+func (mt *MultiTree) FindCIDRb(table string, cidr []byte) (interface{}, error) {
+	t := mt.tables[table]
+	if t == nil {
+		return nil, nil
+	}
+	return mt.view(t).FindCIDRb(cidr)
+}
+
+// Len returns number of entries stored in the table.
+func (mt *MultiTree) Len(table string) int {
+	t := mt.tables[table]
+	if t == nil {
+		return 0
+	}
+	return int(t.root.size + t.root6.size)
+}
+
+// Tables returns names of all tables in sorted order.
+func (mt *MultiTree) Tables() []string {
+	names := make([]string, 0, len(mt.tables))
+	for name := range mt.tables {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DropTable removes the table with all its entries, its nodes are kept for other tables.
+func (mt *MultiTree) DropTable(table string) {
+	t := mt.tables[table]
+	if t == nil {
+		return
+	}
+	mt.tree.release(t.root)
+	mt.tree.release(t.root6)
+	delete(mt.tables, table)
+}
+
+// view returns tree looking up the table, it shares nodes and settings with the underlying tree. Lookups use it instead of switching roots of shared tree, so they do not write anything.
+func (mt *MultiTree) view(t *table) *Tree {
+	tree := *mt.tree
+	tree.root, tree.root6 = t.root, t.root6
+	return &tree
+}
+
+// use switches underlying tree to roots of the table for modification, creating it if asked to. Returns false if there is no such table.
+func (mt *MultiTree) use(name string, create bool) bool {
+	t := mt.tables[name]
+	if t == nil {
+		if !create {
+			return false
+		}
+		t = &table{mt.tree.newnode(), mt.tree.newnode()}
+		mt.tables[name] = t
+	}
+	mt.tree.root, mt.tree.root6 = t.root, t.root6
+	return true
+}
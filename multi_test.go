@@ -0,0 +1,105 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMultiTree(t *testing.T) {
+	mt := NewMultiTree(0)
+	if err := mt.AddCIDR("red", "10.0.0.0/8", "red"); err != nil {
+		t.Error(err)
+	}
+	if err := mt.AddCIDR("blue", "10.0.0.0/8", "blue"); err != nil {
+		t.Error(err)
+	}
+	if err := mt.AddCIDR("blue", "10.1.0.0/16", "blue more"); err != nil {
+		t.Error(err)
+	}
+	if err := mt.AddCIDR("blue", "dead::/16", "blue6"); err != nil {
+		t.Error(err)
+	}
+	if err := mt.AddCIDR("red", "10.0.0.0/8", "again"); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+
+	for _, c := range []struct {
+		table, ip string
+		val       interface{}
+	}{{"red", "10.1.2.3", "red"}, {"blue", "10.1.2.3", "blue more"}, {"blue", "10.2.0.1", "blue"}, {"red", "dead::1", nil},
+		{"blue", "dead::1", "blue6"}, {"green", "10.1.2.3", nil}} {
+		inf, err := mt.FindCIDR(c.table, c.ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != c.val {
+			t.Errorf("Wrong value for %s in %s, expected %v, got %v", c.ip, c.table, c.val, inf)
+		}
+	}
+	if mt.Len("red") != 1 || mt.Len("blue") != 3 || mt.Len("green") != 0 {
+		t.Errorf("Wrong lengths, got %d, %d, %d", mt.Len("red"), mt.Len("blue"), mt.Len("green"))
+	}
+	if tables := fmt.Sprint(mt.Tables()); tables != "[blue red]" {
+		t.Errorf("Wrong tables, got %s", tables)
+	}
+	if err := mt.DeleteCIDR("green", "10.0.0.0/8"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if err := mt.DeleteCIDR("red", "10.0.0.0/8"); err != nil {
+		t.Error(err)
+	}
+
+	allocated := len(mt.tree.alloc)
+	mt.DropTable("blue")
+	if tables := fmt.Sprint(mt.Tables()); tables != "[red]" {
+		t.Errorf("Wrong tables, got %s", tables)
+	}
+	mt.AddCIDR("green", "10.1.0.0/16", "green")
+	mt.AddCIDR("green", "dead::/16", "green6")
+	if len(mt.tree.alloc) != allocated {
+		t.Errorf("Nodes of dropped table should be reused, allocated %d more", len(mt.tree.alloc)-allocated)
+	}
+	if inf, _ := mt.FindCIDR("green", "10.1.2.3"); inf != "green" {
+		t.Errorf("Wrong value, expected green, got %v", inf)
+	}
+}
+
+func TestMultiTreeBadInput(t *testing.T) {
+	mt := NewMultiTree(0)
+	if err := mt.AddCIDR("red", "10.0.0.256/8", 1); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+	if err := mt.SetCIDR("blue", "dead::/129", 1); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+	if tables := fmt.Sprint(mt.Tables()); tables != "[]" {
+		t.Errorf("Bad input should not create tables, got %s", tables)
+	}
+}
+
+func TestMultiTreeConcurrentFind(t *testing.T) {
+	mt := NewMultiTree(0)
+	mt.AddCIDR("red", "10.0.0.0/8", "red")
+	mt.AddCIDR("blue", "10.0.0.0/8", "blue")
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"red", "blue", "red", "blue"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				if inf, _ := mt.FindCIDR(name, "10.1.1.1"); inf != name {
+					t.Errorf("Wrong value in %s, got %v", name, inf)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
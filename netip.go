@@ -0,0 +1,83 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// AddPrefix adds value associated with prefix to the tree. Will return error for invalid prefix or if value already exists.
+func (tree *Tree) AddPrefix(prefix netip.Prefix, val interface{}) error {
+	if !prefix.IsValid() {
+		return wrap("parse", []byte(prefix.String()), ErrBadIP)
+	}
+	if tree.strict && prefix != prefix.Masked() {
+		return wrap("parse", []byte(prefix.String()), ErrHostBits)
+	}
+	if err := tree.checkredundant("add", []byte(prefix.String()), val, false); err != nil {
+		return err
+	}
+	root, hi, lo, bits := tree.addrkey(prefix.Masked().Addr(), prefix.Bits())
+	return wrap("add", []byte(prefix.String()), tree.insertkey(root, hi, lo, bits, val, false))
+}
+
+// SetPrefix sets value associated with prefix in the tree, overwriting existing one.
+func (tree *Tree) SetPrefix(prefix netip.Prefix, val interface{}) error {
+	if !prefix.IsValid() {
+		return wrap("parse", []byte(prefix.String()), ErrBadIP)
+	}
+	if tree.strict && prefix != prefix.Masked() {
+		return wrap("parse", []byte(prefix.String()), ErrHostBits)
+	}
+	if err := tree.checkredundant("set", []byte(prefix.String()), val, true); err != nil {
+		return err
+	}
+	root, hi, lo, bits := tree.addrkey(prefix.Masked().Addr(), prefix.Bits())
+	return wrap("set", []byte(prefix.String()), tree.insertkey(root, hi, lo, bits, val, true))
+}
+
+// DeletePrefix removes value associated with prefix from the tree.
+func (tree *Tree) DeletePrefix(prefix netip.Prefix) error {
+	if !prefix.IsValid() {
+		return wrap("parse", []byte(prefix.String()), ErrBadIP)
+	}
+	root, hi, lo, bits := tree.addrkey(prefix.Masked().Addr(), prefix.Bits())
+	return wrap("delete", []byte(prefix.String()), tree.deletekey(root, hi, lo, bits, false))
+}
+
+// FindPrefix returns previously saved information in longest prefix covering given one.
+func (tree *Tree) FindPrefix(prefix netip.Prefix) (interface{}, error) {
+	if !prefix.IsValid() {
+		return nil, wrap("parse", []byte(prefix.String()), ErrBadIP)
+	}
+	root, hi, lo, bits := tree.addrkey(prefix.Masked().Addr(), prefix.Bits())
+	return tree.findkey(root, hi, lo, bits), nil
+}
+
+// FindAddr returns previously saved information in longest prefix covering address.
+func (tree *Tree) FindAddr(addr netip.Addr) (interface{}, error) {
+	if !addr.IsValid() {
+		return nil, wrap("parse", []byte(addr.String()), ErrBadIP)
+	}
+	root, hi, lo, bits := tree.addrkey(addr, addr.BitLen())
+	return tree.findkey(root, hi, lo, bits), nil
+}
+
+// ContainsAddr reports whether address is covered by any prefix stored in the tree.
+func (tree *Tree) ContainsAddr(addr netip.Addr) bool {
+	val, err := tree.FindAddr(addr)
+	return err == nil && val != nil
+}
+
+// addrkey returns root of address family, address loaded as by loadkey and prefix length in that root.
+func (tree *Tree) addrkey(addr netip.Addr, bits int) (*node, uint64, uint64, int) {
+	if addr.Is4() {
+		ip := addr.As4()
+		return tree.root, uint64(binary.BigEndian.Uint32(ip[:])) << 32, 0, bits
+	}
+	ip := addr.As16()
+	return tree.key6(binary.BigEndian.Uint64(ip[:]), binary.BigEndian.Uint64(ip[8:]), bits)
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func TestNetip(t *testing.T) {
+	tr := NewTree(0)
+	if err := tr.AddPrefix(netip.MustParsePrefix("10.1.2.3/8"), 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("10.1.0.0/16", 2); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddPrefix(netip.MustParsePrefix("dead::/16"), 3); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddPrefix(netip.MustParsePrefix("10.0.0.0/8"), 4); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if err := tr.AddPrefix(netip.Prefix{}, 4); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+
+	for addr, val := range map[string]interface{}{"10.1.2.3": 2, "10.2.0.1": 1, "11.0.0.1": nil, "dead::1": 3, "beef::1": nil} {
+		inf, err := tr.FindAddr(netip.MustParseAddr(addr))
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", addr, val, inf)
+		}
+		if tr.ContainsAddr(netip.MustParseAddr(addr)) != (val != nil) {
+			t.Errorf("Wrong membership of %s", addr)
+		}
+	}
+	inf, err := tr.FindPrefix(netip.MustParsePrefix("10.1.0.0/15"))
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	if _, err := tr.FindAddr(netip.Addr{}); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+
+	if err := tr.SetPrefix(netip.MustParsePrefix("10.1.0.0/16"), 5); err != nil {
+		t.Error(err)
+	}
+	inf, _ = tr.FindCIDR("10.1.2.3")
+	if inf.(int) != 5 {
+		t.Errorf("Wrong value, expected 5, got %v", inf)
+	}
+	if err := tr.DeletePrefix(netip.MustParsePrefix("dead::/16")); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeletePrefix(netip.MustParsePrefix("dead::/16")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if tr.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", tr.Len())
+	}
+}
@@ -0,0 +1,512 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"slices"
+)
+
+// Merge adds all entries of other to the tree. For prefixes stored in both trees resolve is called with value of the tree and value of other, its result is stored (nil removes entry).
+// Nil resolve lets values of other win. Other is not modified and must not be the tree itself.
+func (tree *Tree) Merge(other *Tree, resolve func(prefix netip.Prefix, a, b interface{}) interface{}) {
+	other.walk(func(key net.IP, bits int, n *node) error {
+		node := tree.locate(key, bits)
+		val := n.value
+		if node.held() && resolve != nil {
+			val = resolve(newprefix(key, bits), node.value, n.value)
+		}
+		tree.setvalue(node, val)
+		tree.trim(node)
+		return nil
+	})
+	tree.evict()
+}
+
+// Diff compares the tree with other and returns entries stored only in other (added), only in the tree (removed) and prefixes stored in both with different values (changed, holding value of other).
+// Values are compared by eq, nil eq compares them with reflect.DeepEqual. All lists are in the same order as Walk.
+func (tree *Tree) Diff(other *Tree, eq func(a, b interface{}) bool) (added, removed, changed []Entry) {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+	tree.zip(other, func(key net.IP, bits int, a, b *node) error {
+		switch {
+		case a == nil:
+			added = append(added, newentry(key, bits, b.value))
+		case b == nil:
+			removed = append(removed, newentry(key, bits, a.value))
+		case !eq(a.value, b.value):
+			changed = append(changed, newentry(key, bits, b.value))
+		}
+		return nil
+	})
+	return added, removed, changed
+}
+
+// zip walks the tree and other side by side calling fn for every prefix holding value in any of them, node missing in one of the trees or holding no value visible to walk is passed as nil.
+func (tree *Tree) zip(other *Tree, fn func(key net.IP, bits int, a, b *node) error) error {
+	if err := zip(tree.root, other.root, make(net.IP, net.IPv4len), 0, fn); err != nil {
+		return err
+	}
+	return zip(tree.root6, other.root6, make(net.IP, net.IPv6len), 0, fn)
+}
+
+// zip visits nodes at the same position under a and b (any of them could be nil) like walk does for single tree.
+func zip(a, b *node, key net.IP, d int, fn func(key net.IP, bits int, a, b *node) error) error {
+	if ha, hb := heldof(a), heldof(b); ha != nil || hb != nil {
+		if err := fn(key, d, ha, hb); err != nil {
+			return err
+		}
+	}
+	if d == len(key)*8 {
+		return nil
+	}
+	var al, ar, bl, br *node
+	if a != nil {
+		al, ar = a.left, a.right
+	}
+	if b != nil {
+		bl, br = b.left, b.right
+	}
+	if al != nil || bl != nil {
+		if err := zip(al, bl, key, d+1, fn); err != nil {
+			return err
+		}
+	}
+	if ar != nil || br != nil {
+		bit := startbyte >> uint(d&7)
+		key[d>>3] |= bit
+		err := zip(ar, br, key, d+1, fn)
+		key[d>>3] &^= bit
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// heldof returns n if it holds value visible to walk and nil otherwise.
+func heldof(n *node) *node {
+	if n != nil && n.held() {
+		return n
+	}
+	return nil
+}
+
+// Subtract removes all address space covered by prefixes stored in other from the tree. Entries only partially covered are split into the largest prefixes left uncovered, carrying the same value.
+// Address space under exceptions of other is not covered by it, so it is kept. Expired entries are ignored in both trees.
+func (tree *Tree) Subtract(other *Tree) {
+	tree.subtract(tree.root, other.root, nil, false, other.excludes)
+	tree.subtract(tree.root6, other.root6, nil, false, other.excludes)
+}
+
+// subtract removes address space covered under o from n, carry is value inherited from split entry above n and covered tells whether entry of other above o covers it.
+// Covered subtree is cleared at once unless other has exceptions, which could uncover parts of it. Returns true if whole n got carry, so halves can be joined back.
+func (tree *Tree) subtract(n, o *node, carry interface{}, covered, holes bool) bool {
+	if o != nil && o.live() {
+		covered = o.value != exclusion
+	}
+	if covered && (o == nil || !holes) {
+		tree.clear(n)
+		return false
+	}
+	if o == nil {
+		if n.live() || carry == nil {
+			return false
+		}
+		tree.setvalue(n, carry)
+		return true
+	}
+	switch {
+	case n.value == exclusion:
+		// space under exception is not covered by the tree, so split entry is not carried into it
+		carry = nil
+	case n.value != nil:
+		if n.live() {
+			carry = n.value
+		}
+		tree.setvalue(n, nil)
+	}
+	whole := carry != nil
+	for _, right := range []bool{false, true} {
+		c, oc := n.left, o.left
+		if right {
+			c, oc = n.right, o.right
+		}
+		if c == nil {
+			if carry == nil {
+				continue
+			}
+			c = tree.newchild(n, right)
+		}
+		if !tree.subtract(c, oc, carry, covered, holes) {
+			whole = false
+		}
+	}
+	if whole {
+		// nothing under o was covered after all (exceptions or expired entries only)
+		tree.setvalue(n.left, nil)
+		tree.setvalue(n.right, nil)
+		tree.setvalue(n, carry)
+	}
+	for _, c := range []*node{n.left, n.right} {
+		if c != nil {
+			tree.unlink(c)
+		}
+	}
+	return whole
+}
+
+// clear removes value of n and all entries under it.
+func (tree *Tree) clear(n *node) {
+	for _, c := range []*node{n.left, n.right} {
+		if c != nil {
+			tree.release(c)
+		}
+	}
+	n.left, n.right = nil, nil
+	tree.resize(n, -n.size)
+	tree.removed(n)
+	n.value = nil
+	n.hits.Store(0)
+	n.expires = 0
+}
+
+// newchild creates left or right child of n.
+func (tree *Tree) newchild(n *node, right bool) *node {
+	c := tree.newnode()
+	c.parent = n
+	if right {
+		n.right = c
+	} else {
+		n.left = c
+	}
+	return c
+}
+
+// unlink releases n to the free list if it has no value and no children, unlike trim its parents are left alone.
+func (tree *Tree) unlink(n *node) {
+	if n.value != nil || n.left != nil || n.right != nil {
+		return
+	}
+	if n.parent.right == n {
+		n.parent.right = nil
+	} else {
+		n.parent.left = nil
+	}
+	n.right = tree.free
+	tree.free = n
+}
+
+// Intersect returns new tree covering only address space covered by both the tree and other. Each resulting prefix gets value returned by combine for values covering it in the tree and in other (nil result leaves prefix out).
+// Nil combine keeps values of the tree. Exceptions in either tree uncover their space, so the result gets exceptions where they cut into its entries. Expired entries are ignored.
+func (tree *Tree) Intersect(other *Tree, combine func(prefix netip.Prefix, a, b interface{}) interface{}) *Tree {
+	out := NewTree(0)
+	out.intersect(tree.root, other.root, make(net.IP, net.IPv4len), 0, nil, nil, false, combine)
+	out.intersect(tree.root6, other.root6, make(net.IP, net.IPv6len), 0, nil, nil, false, combine)
+	return out
+}
+
+// intersect adds to the tree prefixes under a and b, ca and cb are values covering them from above and covered tells whether the tree already has entry above.
+func (tree *Tree) intersect(a, b *node, key net.IP, d int, ca, cb interface{}, covered bool, combine func(prefix netip.Prefix, a, b interface{}) interface{}) {
+	var al, ar, bl, br *node
+	stored := false
+	if a != nil {
+		if a.live() {
+			ca, stored = heldvalue(a), true
+		}
+		al, ar = a.left, a.right
+	}
+	if b != nil {
+		if b.live() {
+			cb, stored = heldvalue(b), true
+		}
+		bl, br = b.left, b.right
+	}
+	if stored {
+		switch {
+		case ca != nil && cb != nil:
+			val := ca
+			if combine != nil {
+				val = combine(newprefix(key, d), ca, cb)
+			}
+			if val != nil {
+				tree.setvalue(tree.locate(key, d), val)
+				covered = true
+			}
+		case covered:
+			// entry above would cover space uncovered by exception
+			tree.excludes = true
+			tree.setvalue(tree.locate(key, d), exclusion)
+			covered = false
+		}
+	}
+	if d == len(key)*8 || (a == nil && ca == nil) || (b == nil && cb == nil) {
+		return
+	}
+	if al != nil || bl != nil {
+		tree.intersect(al, bl, key, d+1, ca, cb, covered, combine)
+	}
+	if ar != nil || br != nil {
+		bit := startbyte >> uint(d&7)
+		key[d>>3] |= bit
+		tree.intersect(ar, br, key, d+1, ca, cb, covered, combine)
+		key[d>>3] &^= bit
+	}
+}
+
+// heldvalue returns value of live n, nil for exception.
+func heldvalue(n *node) interface{} {
+	if n.value == exclusion {
+		return nil
+	}
+	return n.value
+}
+
+// Equal reports whether the tree and other hold the same prefixes with equal values. Values are compared by eq, nil eq compares them with reflect.DeepEqual.
+// Expired entries are skipped as Walk skips them, so Len of equal trees could differ. Exceptions change lookups, so both trees should have the same ones.
+func (tree *Tree) Equal(other *Tree, eq func(a, b interface{}) bool) bool {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+	if (tree.excludes || other.excludes) && !slices.Equal(tree.exceptions(), other.exceptions()) {
+		return false
+	}
+	return tree.zip(other, func(key net.IP, bits int, a, b *node) error {
+		if a == nil || b == nil || !eq(a.value, b.value) {
+			return Stop
+		}
+		return nil
+	}) == nil
+}
+
+// Hash returns SHA-256 digest of all prefixes stored in the tree and their values encoded by enc, trees with the same content have the same digest regardless of insertion order.
+// Nil enc formats values with %#v, which is stable for strings, numbers and structs of them but not for pointers.
+func (tree *Tree) Hash(enc func(val interface{}) ([]byte, error)) ([]byte, error) {
+	if enc == nil {
+		enc = func(val interface{}) ([]byte, error) {
+			return fmt.Appendf(nil, "%#v", val), nil
+		}
+	}
+	h := sha256.New()
+	var buf []byte
+	err := tree.walk(func(key net.IP, bits int, n *node) error {
+		val, err := enc(n.value)
+		if err != nil {
+			return err
+		}
+		buf = append(buf[:0], byte(len(key)), byte(bits))
+		buf = append(buf, key[:(bits+7)/8]...)
+		buf = binary.AppendUvarint(buf, uint64(len(val)))
+		h.Write(buf)
+		h.Write(val)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// Aggregate replaces every pair of sibling prefixes holding equal values with their parent prefix, repeating while possible, and returns how many entries were removed.
+// Parent holding different value or exception is never overwritten, expired entries are ignored. Values are compared by eq, nil eq compares them with reflect.DeepEqual.
+func (tree *Tree) Aggregate(eq func(a, b interface{}) bool) int {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+	size := tree.Len()
+	tree.aggregate(tree.root, eq)
+	tree.aggregate(tree.root6, eq)
+	return size - tree.Len()
+}
+
+func (tree *Tree) aggregate(n *node, eq func(a, b interface{}) bool) {
+	l, r := n.left, n.right
+	if l == nil || r == nil {
+		for _, c := range []*node{l, r} {
+			if c != nil {
+				tree.aggregate(c, eq)
+			}
+		}
+		return
+	}
+	tree.aggregate(l, eq)
+	tree.aggregate(r, eq)
+	if !l.held() || !r.held() || !eq(l.value, r.value) || (n.live() && (n.value == exclusion || !eq(n.value, l.value))) {
+		return
+	}
+	if !n.live() {
+		tree.setvalue(n, l.value)
+	}
+	tree.setvalue(l, nil)
+	tree.setvalue(r, nil)
+	tree.unlink(l)
+	tree.unlink(r)
+}
+
+// Deaggregate replaces entry stored at IP/mask with all its subprefixes of length newLen carrying the same value, so parts of it can be changed separately.
+// Address space already covered by more specific entries is left to them. Returns ErrNotFound if there is no such entry and ErrBadIP if newLen is shorter than mask or more than 2^24 prefixes would be created.
+func (tree *Tree) Deaggregate(cidr string, newLen int) error {
+	return tree.Deaggregateb([]byte(cidr), newLen)
+}
+
+func (tree *Tree) Deaggregateb(cidr []byte, newLen int) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	bits := masklen(mask)
+	if newLen < bits || newLen > len(key)*8 || newLen-bits > 24 {
+		return wrap("deaggregate", cidr, ErrBadIP)
+	}
+	n := tree.lookup(key, bits)
+	if n == nil || n.value == nil {
+		return wrap("deaggregate", cidr, ErrNotFound)
+	}
+	if newLen == bits {
+		return nil
+	}
+	val := n.value
+	tree.setvalue(n, nil)
+	tree.deaggregate(n, newLen-bits, val)
+	return nil
+}
+
+// deaggregate stores val in all nodes depth levels below n which are not covered by other values.
+func (tree *Tree) deaggregate(n *node, depth int, val interface{}) {
+	if depth == 0 {
+		if n.value == nil {
+			tree.setvalue(n, val)
+		}
+		return
+	}
+	for _, right := range []bool{false, true} {
+		c := n.left
+		if right {
+			c = n.right
+		}
+		if c == nil {
+			c = tree.newchild(n, right)
+		} else if c.value != nil {
+			continue
+		}
+		tree.deaggregate(c, depth-1, val)
+	}
+}
+
+// Gaps returns the smallest list of prefixes inside IP/mask not covered by any entry stored in the tree, in ascending order.
+func (tree *Tree) Gaps(cidr string) ([]netip.Prefix, error) {
+	return tree.Gapsb([]byte(cidr))
+}
+
+func (tree *Tree) Gapsb(cidr []byte) ([]netip.Prefix, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	bits := masklen(mask)
+	key = key.Mask(mask)
+	n, covered := tree.below(key, bits)
+	if n == nil {
+		if covered {
+			return nil, nil
+		}
+		return []netip.Prefix{newprefix(key, bits)}, nil
+	}
+	return gapsof(n, key, bits, covered, nil), nil
+}
+
+// below returns node at IP/mask (nil if there is none) and whether entry above it covers it, exception entries and expired ones do not.
+func (tree *Tree) below(key net.IP, bits int) (n *node, covered bool) {
+	n = tree.rootof(key)
+	for d := 0; n != nil && d < bits; d++ {
+		if n.live() {
+			covered = n.value != exclusion
+		}
+		n = n.child(key, d)
+	}
+	return n, covered
+}
+
+// gapsof appends prefixes under n not covered by any value to gaps, covered tells whether entry above n covers it.
+func gapsof(n *node, key net.IP, d int, covered bool, gaps []netip.Prefix) []netip.Prefix {
+	if n.live() {
+		covered = n.value != exclusion
+	}
+	if n.left == nil && n.right == nil {
+		if !covered {
+			gaps = append(gaps, newprefix(key, d))
+		}
+		return gaps
+	}
+	start := len(gaps)
+	bit := startbyte >> uint(d&7)
+	if n.left != nil {
+		gaps = gapsof(n.left, key, d+1, covered, gaps)
+	} else if !covered {
+		gaps = append(gaps, newprefix(key, d+1))
+	}
+	key[d>>3] |= bit
+	if n.right != nil {
+		gaps = gapsof(n.right, key, d+1, covered, gaps)
+	} else if !covered {
+		gaps = append(gaps, newprefix(key, d+1))
+	}
+	key[d>>3] &^= bit
+	// both halves are uncovered as a whole, so n is one gap
+	if len(gaps) == start+2 && gaps[start].Bits() == d+1 && gaps[start+1].Bits() == d+1 {
+		gaps = append(gaps[:start], newprefix(key, d))
+	}
+	return gaps
+}
+
+// Coverage returns fraction (from 0 to 1) of address space of IP/mask covered by entries stored in the tree.
+func (tree *Tree) Coverage(cidr string) (float64, error) {
+	return tree.Coverageb([]byte(cidr))
+}
+
+func (tree *Tree) Coverageb(cidr []byte) (float64, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return 0, err
+	}
+	n, covered := tree.below(key, masklen(mask))
+	if n == nil {
+		if covered {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return coverage(n, covered), nil
+}
+
+// coverage returns fraction of address space under n covered by values, covered tells whether entry above n covers it.
+func coverage(n *node, covered bool) float64 {
+	if n.live() {
+		covered = n.value != exclusion
+	}
+	var part float64
+	if covered {
+		part = 1
+	}
+	if n.left == nil && n.right == nil {
+		return part
+	}
+	part = 0
+	for _, c := range []*node{n.left, n.right} {
+		switch {
+		case c != nil:
+			part += coverage(c, covered) / 2
+		case covered:
+			part += 0.5
+		}
+	}
+	return part
+}
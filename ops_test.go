@@ -0,0 +1,560 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestMerge(t *testing.T) {
+	base := NewTree(0)
+	base.AddCIDR("10.0.0.0/8", "base")
+	base.AddCIDR("10.1.0.0/16", "base")
+	base.AddCIDR("10.2.0.0/16", "base")
+	base.AddCIDR("dead::/16", "base")
+	tenant := NewTree(0)
+	tenant.AddCIDR("10.1.0.0/16", "tenant")
+	tenant.AddCIDR("10.2.0.0/16", "drop")
+	tenant.AddCIDR("10.3.0.0/16", "tenant")
+	tenant.AddCIDR("beef::/16", "tenant")
+
+	var conflicts []string
+	base.Merge(tenant, func(prefix netip.Prefix, a, b interface{}) interface{} {
+		conflicts = append(conflicts, prefix.String())
+		if b == "drop" {
+			return nil
+		}
+		return a.(string) + "+" + b.(string)
+	})
+	if len(conflicts) != 2 || conflicts[0] != "10.1.0.0/16" || conflicts[1] != "10.2.0.0/16" {
+		t.Errorf("Wrong conflicts, got %v", conflicts)
+	}
+	for ip, val := range map[string]interface{}{"10.0.0.1": "base", "10.1.0.1": "base+tenant", "10.2.0.1": "base", "10.3.0.1": "tenant",
+		"dead::1": "base", "beef::1": "tenant"} {
+		inf, err := base.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if base.Len() != 5 {
+		t.Errorf("Wrong length, expected 5, got %d", base.Len())
+	}
+	if tenant.Len() != 4 {
+		t.Errorf("Other tree should not be modified, got length %d", tenant.Len())
+	}
+
+	base.Merge(tenant, nil)
+	if inf, _ := base.FindCIDR("10.2.0.1"); inf != "drop" {
+		t.Errorf("Wrong value, expected drop, got %v", inf)
+	}
+}
+
+func TestMergeDead(t *testing.T) {
+	base := NewTree(0)
+	base.AddCIDR("10.0.0.0/8", "base")
+	base.ExcludeCIDR("10.1.0.0/16")
+	base.SetCIDRTTL("10.2.0.0/16", "expired", -time.Second)
+	other := NewTree(0)
+	other.AddCIDR("10.1.0.0/16", "other")
+	other.AddCIDR("10.2.0.0/16", "other")
+	other.SetCIDRTTL("10.3.0.0/16", "expired", -time.Second)
+
+	base.Merge(other, func(prefix netip.Prefix, a, b interface{}) interface{} {
+		t.Errorf("Resolve should not be called for dead entry at %s, got %v", prefix, a)
+		return b
+	})
+	for ip, val := range map[string]interface{}{"10.1.0.1": "other", "10.2.0.1": "other", "10.3.0.1": "base"} {
+		if inf, _ := base.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := NewTree(0)
+	a.AddCIDR("10.0.0.0/8", 1)
+	a.AddCIDR("10.1.0.0/16", 2)
+	a.AddCIDR("10.2.0.0/16", []int{3})
+	a.AddCIDR("dead::/16", 4)
+	b := NewTree(0)
+	b.AddCIDR("10.0.0.0/8", 1)
+	b.AddCIDR("10.1.0.0/16", 20)
+	b.AddCIDR("10.2.0.0/16", []int{3})
+	b.AddCIDR("10.3.0.0/16", 5)
+	b.AddCIDR("beef::/16", 6)
+
+	added, removed, changed := a.Diff(b, nil)
+	check := func(name string, entries []Entry, expected ...string) {
+		if len(entries) != len(expected) {
+			t.Errorf("Wrong number of %s entries, expected %v, got %v", name, expected, entries)
+			return
+		}
+		for i, e := range entries {
+			if e.Prefix.String() != expected[i] {
+				t.Errorf("Wrong %s entry, expected %s, got %s", name, expected[i], e.Prefix)
+			}
+		}
+	}
+	check("added", added, "10.3.0.0/16", "beef::/16")
+	check("removed", removed, "dead::/16")
+	check("changed", changed, "10.1.0.0/16")
+	if len(changed) == 1 && changed[0].Value != 20 {
+		t.Errorf("Wrong changed value, expected 20, got %v", changed[0].Value)
+	}
+
+	added, removed, changed = a.Diff(b, func(x, y interface{}) bool { return true })
+	check("added", added, "10.3.0.0/16", "beef::/16")
+	check("removed", removed, "dead::/16")
+	check("changed", changed)
+
+	added, removed, changed = a.Diff(a.Clone(), nil)
+	if len(added)+len(removed)+len(changed) != 0 {
+		t.Errorf("Clone should not differ, got %v %v %v", added, removed, changed)
+	}
+}
+
+func TestDiffDead(t *testing.T) {
+	a := NewTree(0)
+	a.AddCIDR("10.0.0.0/8", 1)
+	b := a.Clone()
+	a.SetCIDRTTL("10.1.0.0/16", 2, -time.Second)
+	b.ExcludeCIDR("10.2.0.0/16")
+	b.SetCIDRTTL("10.3.0.0/16", 3, -time.Second)
+
+	added, removed, changed := a.Diff(b, nil)
+	if len(added)+len(removed)+len(changed) != 0 {
+		t.Errorf("Expired entries and exceptions should not differ, got %v %v %v", added, removed, changed)
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	block := NewTree(0)
+	block.AddCIDR("10.0.0.0/8", "block")
+	block.AddCIDR("10.1.2.0/24", "block more")
+	block.AddCIDR("192.168.0.0/16", "block")
+	block.AddCIDR("dead::/16", "block6")
+	allow := NewTree(0)
+	allow.AddCIDR("10.1.0.0/16", true)
+	allow.AddCIDR("192.168.0.0/16", true)
+	allow.AddCIDR("dead:beef::/32", true)
+	allow.AddCIDR("172.16.0.0/12", true)
+
+	block.Subtract(allow)
+	expected := []string{"10.0.0.0/16", "10.2.0.0/15", "10.4.0.0/14", "10.8.0.0/13", "10.16.0.0/12", "10.32.0.0/11", "10.64.0.0/10", "10.128.0.0/9"}
+	cidrs := block.ListCIDRs()
+	if len(cidrs) != 24 || block.Len() != 24 {
+		t.Errorf("Wrong number of entries, expected 24, got %d (%d): %v", len(cidrs), block.Len(), cidrs)
+	}
+	for ip, val := range map[string]interface{}{"10.0.0.1": "block", "10.1.2.3": nil, "10.200.0.1": "block", "192.168.1.1": nil,
+		"172.16.0.1": nil, "dead::1": "block6", "dead:beef::1": nil, "dead:beee::1": "block6"} {
+		inf, err := block.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	for _, cidr := range expected {
+		if inf, err := block.ExactMatchCIDR(cidr); err != nil || inf != "block" {
+			t.Errorf("Wrong value for %s, expected block, got %v (%v)", cidr, inf, err)
+		}
+	}
+	// roots, paths to 10.1.0.0/16 and dead:beef::/32 with their siblings, nodes of 192.168.0.0/16 are released
+	if nodes := block.nodes(block.root) + block.nodes(block.root6); nodes != 1+15+8+1+31+16 {
+		t.Errorf("Wrong number of nodes, got %d", nodes)
+	}
+}
+
+func TestSubtractDead(t *testing.T) {
+	block := NewTree(0)
+	block.SetCIDRTTL("11.0.0.0/8", "expired", -time.Second)
+	block.AddCIDR("12.0.0.0/8", "block")
+	block.AddCIDR("13.0.0.0/8", "block")
+	block.ExcludeCIDR("13.1.0.0/16")
+	allow := NewTree(0)
+	allow.AddCIDR("11.1.0.0/16", true)
+	allow.AddCIDR("12.0.0.0/8", true)
+	allow.ExcludeCIDR("12.1.0.0/16")
+	allow.AddCIDR("13.2.0.0/16", true)
+	allow.SetCIDRTTL("13.0.0.0/16", true, -time.Second)
+
+	block.Subtract(allow)
+	for ip, val := range map[string]interface{}{"11.0.0.1": nil, "11.2.0.1": nil, "12.0.0.1": nil, "12.1.0.1": "block",
+		"13.0.0.1": "block", "13.1.0.1": nil, "13.2.0.1": nil, "13.3.0.1": "block"} {
+		if inf, _ := block.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if cidrs := block.ListCIDRs(); len(cidrs) != 9 {
+		t.Errorf("Wrong entries, got %v", cidrs)
+	}
+	if err := block.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	customers := NewTree(0)
+	customers.AddCIDR("10.1.0.0/16", "acme")
+	customers.AddCIDR("10.2.0.0/16", "globex")
+	customers.AddCIDR("10.3.0.0/24", "initech")
+	customers.AddCIDR("dead::/16", "acme6")
+	feed := NewTree(0)
+	feed.AddCIDR("10.1.2.0/24", "botnet")
+	feed.AddCIDR("10.3.0.0/16", "spam")
+	feed.AddCIDR("10.4.0.0/16", "spam")
+	feed.AddCIDR("beef::/16", "spam")
+
+	out := customers.Intersect(feed, func(prefix netip.Prefix, a, b interface{}) interface{} {
+		return a.(string) + "/" + b.(string)
+	})
+	cidrs := out.ListCIDRs()
+	if len(cidrs) != 2 || cidrs[0] != "10.1.2.0/24" || cidrs[1] != "10.3.0.0/24" {
+		t.Errorf("Wrong intersection, got %v", cidrs)
+	}
+	for ip, val := range map[string]interface{}{"10.1.2.3": "acme/botnet", "10.3.0.1": "initech/spam", "10.1.3.1": nil, "10.4.0.1": nil, "dead::1": nil} {
+		inf, err := out.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	feed.AddCIDR("::/0", "all")
+	out = customers.Intersect(feed, nil)
+	if inf, _ := out.FindCIDR("dead::1"); inf != "acme6" {
+		t.Errorf("Wrong value, expected acme6, got %v", inf)
+	}
+	if out.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", out.Len())
+	}
+}
+
+func TestIntersectDead(t *testing.T) {
+	customers := NewTree(0)
+	customers.AddCIDR("10.0.0.0/8", "acme")
+	customers.ExcludeCIDR("10.1.0.0/16")
+	customers.AddCIDR("10.1.1.0/24", "globex")
+	customers.SetCIDRTTL("11.0.0.0/8", "expired", -time.Second)
+	feed := NewTree(0)
+	feed.AddCIDR("10.0.0.0/8", "spam")
+	feed.AddCIDR("11.0.0.0/8", "spam")
+	feed.AddCIDR("12.0.0.0/8", "spam")
+	feed.ExcludeCIDR("12.1.0.0/16")
+
+	out := customers.Intersect(feed, func(prefix netip.Prefix, a, b interface{}) interface{} {
+		return fmt.Sprint(a, "/", b)
+	})
+	for ip, val := range map[string]interface{}{"10.0.0.1": "acme/spam", "10.1.0.1": nil, "10.1.1.1": "globex/spam", "11.0.0.1": nil, "12.1.0.1": nil} {
+		if inf, _ := out.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if cidrs := out.ListCIDRs(); len(cidrs) != 2 {
+		t.Errorf("Wrong intersection, got %v", cidrs)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := NewTree(0)
+	a.AddCIDR("10.0.0.0/8", 1)
+	a.AddCIDR("10.1.0.0/16", []int{2})
+	a.AddCIDR("dead::/16", 3)
+	b := a.Clone()
+	if !a.Equal(b, nil) {
+		t.Error("Clone should be equal")
+	}
+	b.SetCIDR("10.1.0.0/16", []int{2})
+	if !a.Equal(b, nil) {
+		t.Error("Trees with deeply equal values should be equal")
+	}
+	if a.Equal(b, func(x, y interface{}) bool { _, ok := x.([]int); return !ok && x == y }) {
+		t.Error("Comparator should be used")
+	}
+	b.SetCIDR("10.1.0.0/16", 2)
+	if a.Equal(b, nil) {
+		t.Error("Trees with different values should not be equal")
+	}
+	b = a.Clone()
+	b.DeleteCIDR("10.0.0.0/8")
+	b.AddCIDR("10.0.0.0/9", 1)
+	if a.Equal(b, nil) || b.Equal(a, nil) {
+		t.Error("Trees with different prefixes should not be equal")
+	}
+	if !NewTree(0).Equal(NewTree(10), nil) {
+		t.Error("Empty trees should be equal")
+	}
+}
+
+func TestEqualDead(t *testing.T) {
+	a := NewTree(0)
+	a.AddCIDR("10.0.0.0/8", 1)
+	b := a.Clone()
+	a.SetCIDRTTL("10.1.0.0/16", 2, -time.Second)
+	b.ExcludeCIDR("10.2.0.0/16")
+	if fmt.Sprint(a.ListCIDRs()) != fmt.Sprint(b.ListCIDRs()) {
+		t.Fatalf("Listed prefixes should be the same, got %v and %v", a.ListCIDRs(), b.ListCIDRs())
+	}
+	if a.Equal(b, nil) || b.Equal(a, nil) {
+		t.Error("Trees should not be equal when only one of them has an exception")
+	}
+	a.ExcludeCIDR("10.2.0.0/16")
+	if !a.Equal(b, nil) || !b.Equal(a, nil) {
+		t.Error("Trees should be equal when they differ only by expired entries")
+	}
+	b.AddCIDR("10.1.0.0/16", 2)
+	if a.Equal(b, nil) {
+		t.Error("Trees should not be equal")
+	}
+}
+
+func TestHash(t *testing.T) {
+	a := NewTree(0)
+	a.AddCIDR("10.0.0.0/8", "a")
+	a.AddCIDR("10.1.0.0/16", "b")
+	a.AddCIDR("dead::/16", 3)
+	b := NewTree(0)
+	b.AddCIDR("dead::/16", 3)
+	b.AddCIDR("10.1.0.0/16", "b")
+	b.AddCIDR("10.0.0.0/8", "a")
+
+	ha, err := a.Hash(nil)
+	if err != nil {
+		t.Error(err)
+	}
+	hb, _ := b.Hash(nil)
+	if string(ha) != string(hb) || len(ha) != 32 {
+		t.Errorf("Wrong hash, expected equal digests, got %x and %x", ha, hb)
+	}
+
+	b.SetCIDR("dead::/16", "3")
+	if hb, _ = b.Hash(nil); string(ha) == string(hb) {
+		t.Error("Hash should depend on value types")
+	}
+	b.SetCIDR("dead::/16", 3)
+	b.AddCIDR("dead::/17", 3)
+	if hb, _ = b.Hash(nil); string(ha) == string(hb) {
+		t.Error("Hash should depend on prefixes")
+	}
+
+	if _, err = a.Hash(func(val interface{}) ([]byte, error) { return nil, ErrBadFormat }); err != ErrBadFormat {
+		t.Errorf("Should have gotten ErrBadFormat, instead got err: %v", err)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tr := NewTree(0)
+	for i := 0; i < 4; i++ {
+		tr.AddCIDR(fmt.Sprintf("10.0.%d.0/24", i), "a")
+	}
+	tr.AddCIDR("10.0.4.0/24", "a")
+	tr.AddCIDR("10.0.5.0/24", "b")
+	tr.AddCIDR("10.0.1.128/25", "c")
+	tr.AddCIDR("10.0.6.0/23", "d")
+	tr.AddCIDR("10.0.6.0/24", "x")
+	tr.AddCIDR("10.0.7.0/24", "x")
+	tr.AddCIDR("dead::/17", 1)
+	tr.AddCIDR("dead:8000::/17", 1)
+
+	if removed := tr.Aggregate(nil); removed != 4 {
+		t.Errorf("Wrong number of removed entries, expected 4, got %d", removed)
+	}
+	expected := "[10.0.0.0/22 10.0.1.128/25 10.0.4.0/24 10.0.5.0/24 10.0.6.0/23 10.0.6.0/24 10.0.7.0/24 dead::/16]"
+	if cidrs := fmt.Sprint(tr.ListCIDRs()); cidrs != expected {
+		t.Errorf("Wrong aggregation, expected %s, got %s", expected, cidrs)
+	}
+	for ip, val := range map[string]interface{}{"10.0.2.1": "a", "10.0.1.200": "c", "10.0.1.1": "a", "dead:ffff::1": 1} {
+		if inf, _ := tr.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if removed := tr.Aggregate(func(a, b interface{}) bool { return true }); removed != 5 {
+		t.Errorf("Wrong number of removed entries, expected 5, got %d", removed)
+	}
+	expected = "[10.0.0.0/21 10.0.1.128/25 dead::/16]"
+	if cidrs := fmt.Sprint(tr.ListCIDRs()); cidrs != expected {
+		t.Errorf("Wrong aggregation, expected %s, got %s", expected, cidrs)
+	}
+}
+
+func TestAggregateDead(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/9", "a")
+	tr.SetCIDRTTL("10.128.0.0/9", "a", -time.Second)
+	tr.SetCIDRTTL("10.0.0.0/8", "b", -time.Second)
+	tr.AddCIDR("11.0.0.0/9", "a")
+	tr.AddCIDR("11.128.0.0/9", "a")
+	tr.AddCIDR("12.0.0.0/9", "a")
+	tr.AddCIDR("12.128.0.0/9", "a")
+	tr.ExcludeCIDR("12.0.0.0/8")
+
+	tr.Aggregate(nil)
+	expected := "[10.0.0.0/9 11.0.0.0/8 12.0.0.0/9 12.128.0.0/9]"
+	if cidrs := fmt.Sprint(tr.ListCIDRs()); cidrs != expected {
+		t.Errorf("Wrong aggregation, expected %s, got %s", expected, cidrs)
+	}
+	if inf, _ := tr.FindCIDR("10.200.0.1"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+
+	tr.SetCIDRTTL("10.128.0.0/9", "a", time.Hour)
+	tr.Aggregate(nil)
+	if inf, _ := tr.FindCIDR("10.200.0.1"); inf != "a" {
+		t.Errorf("Wrong value, expected a, got %v", inf)
+	}
+}
+
+func TestDeaggregate(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/22", "a")
+	tr.AddCIDR("10.0.2.0/23", "b")
+	tr.AddCIDR("10.0.1.0/25", "c")
+	tr.AddCIDR("2001:db8::/46", "v6")
+
+	if err := tr.Deaggregate("10.0.0.0/22", 24); err != nil {
+		t.Error(err)
+	}
+	expected := "[10.0.0.0/24 10.0.1.0/24 10.0.1.0/25 10.0.2.0/23 2001:db8::/46]"
+	if cidrs := fmt.Sprint(tr.ListCIDRs()); cidrs != expected {
+		t.Errorf("Wrong deaggregation, expected %s, got %s", expected, cidrs)
+	}
+	for ip, val := range map[string]interface{}{"10.0.0.1": "a", "10.0.1.1": "c", "10.0.1.200": "a", "10.0.3.1": "b", "10.0.4.1": nil} {
+		if inf, _ := tr.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	if err := tr.Deaggregate("2001:db8::/46", 48); err != nil {
+		t.Error(err)
+	}
+	expected = "[10.0.0.0/24 10.0.1.0/24 10.0.1.0/25 10.0.2.0/23 2001:db8::/48 2001:db8:1::/48 2001:db8:2::/48 2001:db8:3::/48]"
+	if cidrs := fmt.Sprint(tr.ListCIDRs()); cidrs != expected {
+		t.Errorf("Wrong deaggregation, expected %s, got %s", expected, cidrs)
+	}
+
+	if err := tr.Deaggregate("10.0.0.0/22", 24); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	for _, newLen := range []int{22, 33, -1} {
+		if err := tr.Deaggregate("10.0.2.0/23", newLen); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP for %d, instead got err: %v", newLen, err)
+		}
+	}
+	if err := tr.Deaggregate("2001:db8::/48", 96); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
+
+func TestGaps(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/24", 1)
+	tr.AddCIDR("10.0.2.0/24", 2)
+	tr.AddCIDR("10.0.3.0/25", 3)
+	tr.AddCIDR("10.1.0.0/16", 4)
+	tr.AddCIDR("2001:db8::/33", 5)
+
+	for cidr, expected := range map[string]string{
+		"10.0.0.0/22":   "[10.0.1.0/24 10.0.3.128/25]",
+		"10.0.0.0/23":   "[10.0.1.0/24]",
+		"10.1.2.0/24":   "[]",
+		"10.1.0.0/16":   "[]",
+		"10.2.0.0/16":   "[10.2.0.0/16]",
+		"10.0.0.0/15":   "[10.0.1.0/24 10.0.3.128/25 10.0.4.0/22 10.0.8.0/21 10.0.16.0/20 10.0.32.0/19 10.0.64.0/18 10.0.128.0/17]",
+		"2001:db8::/32": "[2001:db8:8000::/33]",
+		"10.0.3.1":      "[]",
+		"10.0.3.129":    "[10.0.3.129/32]",
+	} {
+		gaps, err := tr.Gaps(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if fmt.Sprint(gaps) != expected {
+			t.Errorf("Wrong gaps in %s, expected %s, got %v", cidr, expected, gaps)
+		}
+	}
+	if _, err := tr.Gaps("10.0.0.256/24"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/9", 1)
+	tr.AddCIDR("10.0.1.0/24", 2)
+	tr.AddCIDR("10.128.0.0/10", 3)
+	tr.AddCIDR("10.255.255.255/32", 4)
+	tr.AddCIDR("2001:db8::/34", 5)
+
+	for cidr, expected := range map[string]float64{
+		"10.0.0.0/8":    0.75 + 1.0/(1<<24),
+		"10.0.0.0/9":    1,
+		"10.0.1.0/25":   1,
+		"10.192.0.0/10": 1.0 / (1 << 22),
+		"11.0.0.0/8":    0,
+		"0.0.0.0/0":     (0.75*(1<<24) + 1) / (1 << 32),
+		"2001:db8::/32": 0.25,
+		"::/0":          1.0 / (1 << 34),
+	} {
+		covered, err := tr.Coverage(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if covered != expected {
+			t.Errorf("Wrong coverage of %s, expected %v, got %v", cidr, expected, covered)
+		}
+	}
+	if _, err := tr.Coverage("10.0.0.0/8/8"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
+
+func TestGapsExclusion(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.ExcludeCIDR("10.1.0.0/16")
+	tr.AddCIDR("10.1.128.0/17", 2)
+	tr.ExcludeCIDR("11.0.0.0/16")
+	tr.SetCIDRTTL("12.0.0.0/16", 3, -time.Second)
+
+	for cidr, expected := range map[string]string{
+		"10.0.0.0/8":  "[10.1.0.0/17]",
+		"10.1.0.0/16": "[10.1.0.0/17]",
+		"10.1.0.0/24": "[10.1.0.0/24]",
+		"11.0.0.0/16": "[11.0.0.0/16]",
+		"12.0.0.0/16": "[12.0.0.0/16]",
+	} {
+		gaps, err := tr.Gaps(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if fmt.Sprint(gaps) != expected {
+			t.Errorf("Wrong gaps in %s, expected %s, got %v", cidr, expected, gaps)
+		}
+	}
+	for cidr, expected := range map[string]float64{
+		"10.0.0.0/8":  1 - 1.0/(1<<9),
+		"10.1.0.0/16": 0.5,
+		"11.0.0.0/16": 0,
+		"12.0.0.0/8":  0,
+	} {
+		covered, err := tr.Coverage(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if covered != expected {
+			t.Errorf("Wrong coverage of %s, expected %v, got %v", cidr, expected, covered)
+		}
+	}
+}
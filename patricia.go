@@ -0,0 +1,235 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"math/bits"
+	"net"
+)
+
+// pnode keeps whole prefix it stands for, so chains of nodes with single child are skipped.
+type pnode struct {
+	key         net.IP
+	bits        int
+	value       interface{}
+	left, right *pnode
+}
+
+// PatriciaTree is path-compressed radix tree for IP/mask. Node exists only for stored prefix or where paths of two prefixes split,
+// so every prefix costs at most two nodes no matter how long it is. Zero value is empty tree.
+type PatriciaTree struct {
+	root, root6 *pnode
+	count       int
+}
+
+// NewPatriciaTree creates empty PatriciaTree.
+func NewPatriciaTree() *PatriciaTree {
+	return new(PatriciaTree)
+}
+
+// AddCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR or if value already exists.
+func (tree *PatriciaTree) AddCIDR(cidr string, val interface{}) error {
+	return tree.AddCIDRb([]byte(cidr), val)
+}
+
+func (tree *PatriciaTree) AddCIDRb(cidr []byte, val interface{}) error {
+	return tree.insert(cidr, val, false)
+}
+
+// SetCIDR sets value associated with IP/mask in the tree, overwriting existing one.
+func (tree *PatriciaTree) SetCIDR(cidr string, val interface{}) error {
+	return tree.SetCIDRb([]byte(cidr), val)
+}
+
+func (tree *PatriciaTree) SetCIDRb(cidr []byte, val interface{}) error {
+	return tree.insert(cidr, val, true)
+}
+
+// DeleteCIDR removes value associated with IP/mask from the tree.
+func (tree *PatriciaTree) DeleteCIDR(cidr string) error {
+	return tree.DeleteCIDRb([]byte(cidr))
+}
+
+func (tree *PatriciaTree) DeleteCIDRb(cidr []byte) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	root := tree.rootof(key)
+	n, err := (*root).remove(key, masklen(mask))
+	if err != nil {
+		return wrap("delete", cidr, err)
+	}
+	*root = n
+	tree.count--
+	return nil
+}
+
+// FindCIDR traverses tree to proper Node and returns previously saved information in longest covered IP.
+func (tree *PatriciaTree) FindCIDR(cidr string) (interface{}, error) {
+	return tree.FindCIDRb([]byte(cidr))
+}
+
+func (tree *PatriciaTree) FindCIDRb(cidr []byte) (interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	bits := masklen(mask)
+	var value interface{}
+	for n := *tree.rootof(key); n != nil && n.bits <= bits && commonbits(n.key, key, n.bits) == n.bits; n = n.child(key) {
+		if n.value != nil {
+			value = n.value
+		}
+		if n.bits == bits {
+			break
+		}
+	}
+	return value, nil
+}
+
+// Len returns number of entries stored in the tree.
+func (tree *PatriciaTree) Len() int {
+	return tree.count
+}
+
+// Walk calls fn for every prefix stored in the tree in the same order as Tree.Walk.
+// Walk stops and returns the error if fn returns one, except for SkipSubtree and Stop.
+func (tree *PatriciaTree) Walk(fn func(prefix *net.IPNet, val interface{}) error) error {
+	err := tree.root.walk(fn)
+	if err == nil {
+		err = tree.root6.walk(fn)
+	}
+	if err == Stop {
+		return nil
+	}
+	return err
+}
+
+func (tree *PatriciaTree) rootof(key net.IP) **pnode {
+	if len(key) == net.IPv4len {
+		return &tree.root
+	}
+	return &tree.root6
+}
+
+func (tree *PatriciaTree) insert(cidr []byte, val interface{}, overwrite bool) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	bits := masklen(mask)
+	key = key.Mask(mask)
+	p := tree.rootof(key)
+	for {
+		n := *p
+		if n == nil {
+			*p = &pnode{key: key, bits: bits, value: val}
+			break
+		}
+		common := commonbits(n.key, key, min(n.bits, bits))
+		if common == n.bits && common == bits {
+			if n.value != nil && !overwrite {
+				return wrap("add", cidr, ErrNodeBusy)
+			}
+			if n.value != nil {
+				tree.count--
+			}
+			n.value = val
+			break
+		}
+		if common == n.bits {
+			p = n.childref(key)
+			continue
+		}
+		// new prefix is above n or they split at common bit
+		up := &pnode{key: key.Mask(net.CIDRMask(common, len(key)*8)), bits: common}
+		if common == bits {
+			up.key, up.value = key, val
+		} else {
+			*up.childref(key) = &pnode{key: key, bits: bits, value: val}
+		}
+		*up.childref(n.key) = n
+		*p = up
+		break
+	}
+	tree.count++
+	return nil
+}
+
+// remove clears value of prefix key/bits under n and returns what should replace n.
+func (n *pnode) remove(key net.IP, bits int) (*pnode, error) {
+	if n == nil || n.bits > bits || commonbits(n.key, key, n.bits) != n.bits {
+		return n, ErrNotFound
+	}
+	if n.bits == bits {
+		if n.value == nil {
+			return n, ErrNotFound
+		}
+		n.value = nil
+	} else {
+		child := n.childref(key)
+		c, err := (*child).remove(key, bits)
+		if err != nil {
+			return n, err
+		}
+		*child = c
+	}
+	// node without value is needed only to split paths
+	switch {
+	case n.value != nil || (n.left != nil && n.right != nil):
+		return n, nil
+	case n.left != nil:
+		return n.left, nil
+	default:
+		return n.right, nil
+	}
+}
+
+// child returns next node on the path of key (which should be longer than n).
+func (n *pnode) child(key net.IP) *pnode {
+	return *n.childref(key)
+}
+
+func (n *pnode) childref(key net.IP) **pnode {
+	if bitset(key, n.bits) {
+		return &n.right
+	}
+	return &n.left
+}
+
+func (n *pnode) walk(fn func(prefix *net.IPNet, val interface{}) error) error {
+	if n == nil {
+		return nil
+	}
+	if n.value != nil {
+		e := newentry(n.key, n.bits, n.value)
+		if err := fn(e.Prefix, e.Value); err == SkipSubtree {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if err := n.left.walk(fn); err != nil {
+		return err
+	}
+	return n.right.walk(fn)
+}
+
+// commonbits returns number of leading bits a and b share, but no more than max.
+func commonbits(a, b net.IP, max int) int {
+	var common int
+	for i := 0; common < max; i++ {
+		if x := a[i] ^ b[i]; x != 0 {
+			common += bits.LeadingZeros8(x)
+			break
+		}
+		common += 8
+	}
+	if common > max {
+		return max
+	}
+	return common
+}
@@ -0,0 +1,91 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestPatriciaTree(t *testing.T) {
+	tr := NewPatriciaTree()
+	for cidr, val := range map[string]int{"10.1.0.0/16": 2, "10.0.0.0/8": 1, "10.1.2.0/24": 3, "10.1.3.0/24": 4, "192.168.0.0/16": 5, "dead::/16": 6} {
+		if err := tr.AddCIDR(cidr, val); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := tr.AddCIDR("10.1.0.0/16", 7); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	for ip, val := range map[string]interface{}{"10.1.2.1": 3, "10.1.3.1": 4, "10.1.4.1": 2, "10.2.0.1": 1, "11.0.0.1": nil, "192.168.1.1": 5, "192.169.1.1": nil,
+		"10.1.0.0/16": 2, "10.1.0.0/15": 1, "0.0.0.0/0": nil, "dead::1": 6, "beef::1": nil} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Len() != 6 {
+		t.Errorf("Wrong length, expected 6, got %d", tr.Len())
+	}
+
+	var cidrs []string
+	tr.Walk(func(prefix *net.IPNet, val interface{}) error {
+		cidrs = append(cidrs, prefix.String())
+		return nil
+	})
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "10.1.3.0/24", "192.168.0.0/16", "dead::/16"}
+	if fmt.Sprint(cidrs) != fmt.Sprint(expected) {
+		t.Errorf("Wrong walk order, expected %v, got %v", expected, cidrs)
+	}
+
+	if err := tr.DeleteCIDR("10.1.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteCIDR("10.1.0.0/16"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if err := tr.DeleteCIDR("10.1.0.0/23"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if err := tr.SetCIDR("10.1.2.0/24", 8); err != nil {
+		t.Error(err)
+	}
+	for ip, val := range map[string]interface{}{"10.1.2.1": 8, "10.1.4.1": 1} {
+		inf, _ := tr.FindCIDR(ip)
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Len() != 5 {
+		t.Errorf("Wrong length, expected 5, got %d", tr.Len())
+	}
+}
+
+func TestPatriciaTreeCompression(t *testing.T) {
+	tr := NewPatriciaTree()
+	tr.AddCIDR("1.2.3.0/24", 1)
+	if tr.root == nil || tr.root.left != nil || tr.root.right != nil {
+		t.Error("Single prefix should be stored in single node")
+	}
+
+	tr.AddCIDR("1.2.4.0/24", 2)
+	if tr.root.value != nil || tr.root.bits != 21 || tr.root.left.bits != 24 || tr.root.right.bits != 24 {
+		t.Errorf("Prefixes should be split at /21, got /%d", tr.root.bits)
+	}
+
+	tr.DeleteCIDR("1.2.3.0/24")
+	if tr.root.value.(int) != 2 || tr.root.left != nil || tr.root.right != nil {
+		t.Error("Split node should be removed with one of the prefixes")
+	}
+	tr.DeleteCIDR("1.2.4.0/24")
+	if tr.root != nil {
+		t.Error("Tree should be empty")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+// MatchByPriority turns on (or off) matching by priority: lookups return covering entry with the highest priority, the most specific one among entries of equal priority.
+// Entries stored without priority have priority 0, so "explicit deny beats broad allow" is a deny entry with priority above allow entries.
+func (tree *Tree) MatchByPriority(enable bool) {
+	tree.byprio = enable
+}
+
+// AddCIDRPriority adds value associated with IP/mask to the tree with given priority. Will return error for invalid CIDR or if value already exists.
+func (tree *Tree) AddCIDRPriority(cidr string, val interface{}, priority int32) error {
+	return tree.AddCIDRPriorityb([]byte(cidr), val, priority)
+}
+
+func (tree *Tree) AddCIDRPriorityb(cidr []byte, val interface{}, priority int32) error {
+	return tree.insertpriority(cidr, val, priority, false)
+}
+
+// SetCIDRPriority sets value associated with IP/mask in the tree with given priority, overwriting existing one.
+func (tree *Tree) SetCIDRPriority(cidr string, val interface{}, priority int32) error {
+	return tree.SetCIDRPriorityb([]byte(cidr), val, priority)
+}
+
+func (tree *Tree) SetCIDRPriorityb(cidr []byte, val interface{}, priority int32) error {
+	return tree.insertpriority(cidr, val, priority, true)
+}
+
+func (tree *Tree) insertpriority(cidr []byte, val interface{}, priority int32, overwrite bool) error {
+	if err := tree.checkhost(cidr); err != nil {
+		return err
+	}
+	if err := tree.checkredundant(insertop(overwrite), cidr, val, overwrite); err != nil {
+		return err
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	node := tree.locate(key, masklen(mask))
+	if node.live() && !overwrite {
+		return wrap("add", cidr, ErrNodeBusy)
+	}
+	tree.setvalue(node, val)
+	node.priority = priority
+	tree.trim(node)
+	tree.evict()
+	return nil
+}
+
+// better reports whether lookup should prefer n found deeper on the path over found.
+func (tree *Tree) better(n, found *node) bool {
+	return !tree.byprio || found == nil || n.priority >= found.priority
+}
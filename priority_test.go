@@ -0,0 +1,53 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatchByPriority(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDRPriority("10.0.0.0/8", "deny", 10)
+	tr.AddCIDR("10.1.0.0/16", "allow")
+	tr.AddCIDRPriority("10.1.2.0/24", "allow more", 10)
+	tr.AddCIDRPriority("dead::/16", "deny6", 1)
+	tr.AddCIDR("dead:beef::/32", "allow6")
+	if err := tr.AddCIDRPriority("10.1.0.0/16", "again", 1); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+
+	cases := map[string][2]interface{}{
+		"10.1.3.4":     {"allow", "deny"},
+		"10.1.2.3":     {"allow more", "allow more"},
+		"10.2.0.1":     {"deny", "deny"},
+		"dead:beef::1": {"allow6", "deny6"},
+	}
+	for i, enable := range []bool{false, true} {
+		tr.MatchByPriority(enable)
+		for ip, vals := range cases {
+			inf, err := tr.FindCIDR(ip)
+			if err != nil {
+				t.Error(err)
+			}
+			if inf != vals[i] {
+				t.Errorf("Wrong value for %s (priority %v), expected %v, got %v", ip, enable, vals[i], inf)
+			}
+			if m, _ := tr.FindCIDRMatch(ip); m == nil || m.Value != vals[i] {
+				t.Errorf("Wrong match for %s (priority %v), expected %v, got %v", ip, enable, vals[i], m)
+			}
+		}
+	}
+
+	tr.SetCIDR("10.0.0.0/8", "deny")
+	if inf, _ := tr.FindCIDR("10.1.3.4"); inf != "allow" {
+		t.Errorf("SetCIDR should reset priority, got %v", inf)
+	}
+	tr.SetCIDRPriority("10.1.0.0/16", "allow", -1)
+	if inf, _ := tr.FindCIDR("10.1.3.4"); inf != "deny" {
+		t.Errorf("Wrong value, expected deny, got %v", inf)
+	}
+}
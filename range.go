@@ -0,0 +1,102 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"net"
+)
+
+// AddRange adds value associated with every IP from start to end (inclusive) to the tree, splitting range into minimal set of CIDRs.
+// Will return error for invalid range or if value already exists for one of CIDRs, in which case CIDRs before it stay added.
+func (tree *Tree) AddRange(start, end string, val interface{}) error {
+	return tree.AddRangeb([]byte(start), []byte(end), val)
+}
+
+func (tree *Tree) AddRangeb(start, end []byte, val interface{}) error {
+	return tree.splitrange(start, end, func(key net.IP, mask net.IPMask) error {
+		cidr := []byte((&net.IPNet{IP: key, Mask: mask}).String())
+		if tree.redundant != nil && tree.isredundant(key, masklen(mask), val, false) {
+			return wrap("add", cidr, ErrRedundant)
+		}
+		return wrap("add", cidr, tree.insert(key, mask, val, false))
+	})
+}
+
+// DeleteRange removes all entries fully contained within range of IPs from start to end (inclusive).
+func (tree *Tree) DeleteRange(start, end string) error {
+	return tree.DeleteRangeb([]byte(start), []byte(end))
+}
+
+func (tree *Tree) DeleteRangeb(start, end []byte) error {
+	return tree.splitrange(start, end, func(key net.IP, mask net.IPMask) error {
+		if n := tree.lookup(key, masklen(mask)); n != nil {
+			tree.prune(n)
+		}
+		return nil
+	})
+}
+
+// splitrange parses start and end IPs and calls fn for every CIDR in minimal set covering the range.
+func (tree *Tree) splitrange(start, end []byte, fn func(key net.IP, mask net.IPMask) error) error {
+	first, _, err := parsecidr(start)
+	if err != nil {
+		return err
+	}
+	last, _, err := parsecidr(end)
+	if err != nil {
+		return err
+	}
+	if len(first) != len(last) || bytes.Compare(first, last) > 0 {
+		return wrap("parse", append(append(start[:len(start):len(start)], '-'), end...), ErrBadIP)
+	}
+	size := len(first) * 8
+	for {
+		// grow block while it stays aligned and within the range
+		var host int
+		for host < size && !bitset(first, size-1-host) && bytes.Compare(sethost(first, host+1), last) <= 0 {
+			host++
+		}
+		if err := fn(first, net.CIDRMask(size-host, size)); err != nil {
+			return err
+		}
+		top := sethost(first, host)
+		if bytes.Equal(top, last) {
+			return nil
+		}
+		first = increment(top)
+	}
+}
+
+// bitset reports whether bit number n (counting from the most significant) is set in key.
+func bitset(key net.IP, n int) bool {
+	return key[n>>3]&(startbyte>>uint(n&7)) != 0
+}
+
+// sethost returns copy of key with lowest bits set.
+func sethost(key net.IP, bits int) net.IP {
+	ip := make(net.IP, len(key))
+	copy(ip, key)
+	for i := len(ip) - 1; bits > 0; i, bits = i-1, bits-8 {
+		if bits >= 8 {
+			ip[i] = 0xff
+		} else {
+			ip[i] |= byte(1)<<uint(bits) - 1
+		}
+	}
+	return ip
+}
+
+// increment returns copy of key increased by one, it wraps around to zero address on overflow.
+func increment(key net.IP) net.IP {
+	ip := make(net.IP, len(key))
+	copy(ip, key)
+	for i := len(ip) - 1; i >= 0; i-- {
+		if ip[i]++; ip[i] != 0 {
+			break
+		}
+	}
+	return ip
+}
@@ -0,0 +1,111 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestAddRange(t *testing.T) {
+	tr := NewTree(0)
+	err := tr.AddRange("192.0.2.17", "192.0.2.73", 1)
+	if err != nil {
+		t.Error(err)
+	}
+
+	var prefixes []string
+	tr.walk(func(key net.IP, bits int, n *node) error {
+		prefixes = append(prefixes, newentry(key, bits, n.value).Prefix.String())
+		return nil
+	})
+	expected := []string{"192.0.2.17/32", "192.0.2.18/31", "192.0.2.20/30", "192.0.2.24/29", "192.0.2.32/27", "192.0.2.64/29", "192.0.2.72/31"}
+	if len(prefixes) != len(expected) {
+		t.Fatalf("Wrong CIDRs, expected %v, got %v", expected, prefixes)
+	}
+	for i := range expected {
+		if prefixes[i] != expected[i] {
+			t.Errorf("Wrong CIDR, expected %s, got %s", expected[i], prefixes[i])
+		}
+	}
+
+	for ip, val := range map[string]interface{}{"192.0.2.16": nil, "192.0.2.17": 1, "192.0.2.50": 1, "192.0.2.73": 1, "192.0.2.74": nil} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	// whole address space
+	tr = NewTree(0)
+	if err = tr.AddRange("0.0.0.0", "255.255.255.255", 2); err != nil {
+		t.Error(err)
+	}
+	if tr.root.value == nil || tr.root.left != nil || tr.root.right != nil {
+		t.Error("Whole address space should be stored in the root")
+	}
+
+	tr = NewTree(0)
+	if err = tr.AddRange("dead::fffe", "dead::1:1", 3); err != nil {
+		t.Error(err)
+	}
+	inf, err := tr.FindCIDR("dead::1:0")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+
+	if err = tr.AddRange("10.0.0.2", "10.0.0.1", 4); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+	if err = tr.AddRange("10.0.0.1", "dead::1", 4); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
+
+func TestDeleteRange(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.0.0.16/28", 2)
+	tr.AddCIDR("10.0.0.32/28", 3)
+	tr.AddCIDR("10.0.0.64/28", 4)
+	tr.AddCIDR("10.0.0.0/27", 5)
+
+	// covers 10.0.0.16/28 and 10.0.0.32/28 fully, but only part of others
+	err := tr.DeleteRange("10.0.0.10", "10.0.0.50")
+	if err != nil {
+		t.Error(err)
+	}
+	for ip, val := range map[string]interface{}{"10.0.0.1": 5, "10.0.0.17": 5, "10.0.0.33": 1, "10.0.0.65": 4} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	err = tr.DeleteRange("0.0.0.0", "255.255.255.255")
+	if err != nil {
+		t.Error(err)
+	}
+	if tr.root.value != nil || tr.root.left != nil || tr.root.right != nil {
+		t.Error("Tree should have been emptied")
+	}
+	if tr.free == nil {
+		t.Error("Removed nodes should be put to the free list")
+	}
+
+	if err = tr.DeleteRange("10.0.0.2", "10.0.0.1"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net/netip"
+)
+
+// FindIPv4 returns previously saved information in longest prefix covering IPv4 address given as number (10.0.0.1 is 0x0a000001).
+func (tree *Tree) FindIPv4(ip uint32) interface{} {
+	return tree.find32(ip, 0xffffffff)
+}
+
+// FindIPv4Bytes returns previously saved information in longest prefix covering IPv4 address a.b.c.d.
+func (tree *Tree) FindIPv4Bytes(a, b, c, d byte) interface{} {
+	return tree.find32(uint32(a)<<24|uint32(b)<<16|uint32(c)<<8|uint32(d), 0xffffffff)
+}
+
+// Find16 returns previously saved information in longest prefix covering IPv6 address given as 16 bytes.
+func (tree *Tree) Find16(key [16]byte) interface{} {
+	hi, lo := loadkey(key[:])
+	root, hi, lo, bits := tree.key6(hi, lo, 128)
+	return tree.findkey(root, hi, lo, bits)
+}
+
+// Add16 adds value associated with IPv6 address given as 16 bytes and prefix length to the tree. Will return error for invalid length or if value already exists.
+func (tree *Tree) Add16(key [16]byte, bits int, val interface{}) error {
+	if bits < 0 || bits > 128 {
+		return wrap("parse", []byte(netip.AddrFrom16(key).String()), ErrBadIP)
+	}
+	hi, lo := loadkey(key[:])
+	if mhi, mlo := maskkey(hi, lo, bits); tree.strict && (mhi != hi || mlo != lo) {
+		return wrap("parse", []byte(netip.PrefixFrom(netip.AddrFrom16(key), bits).String()), ErrHostBits)
+	}
+	if err := tree.checkredundant("add", []byte(netip.PrefixFrom(netip.AddrFrom16(key), bits).String()), val, false); err != nil {
+		return err
+	}
+	hi, lo = maskkey(hi, lo, bits)
+	prefix := netip.PrefixFrom(netip.AddrFrom16(key), bits).String()
+	root, hi, lo, bits := tree.key6(hi, lo, bits)
+	return wrap("add", []byte(prefix), tree.insertkey(root, hi, lo, bits, val, false))
+}
+
+// FindNumeric returns previously saved information in longest prefix covering IPv4 address written as decimal ("1249516568") or hexadecimal ("0x4A7A1C18") number, as found in some logs and proxy headers.
+func (tree *Tree) FindNumeric(ip string) (interface{}, error) {
+	return tree.FindNumericb([]byte(ip))
+}
+
+func (tree *Tree) FindNumericb(ip []byte) (interface{}, error) {
+	key, err := loadnum4(ip)
+	if err != nil {
+		return nil, wrap("parse", ip, err)
+	}
+	return tree.find32(key, 0xffffffff), nil
+}
+
+// loadnum4 parses IPv4 address written as 32-bit decimal or 0x-prefixed hexadecimal number.
+func loadnum4(ipstr []byte) (uint32, error) {
+	var ip uint64
+	if len(ipstr) > 2 && ipstr[0] == '0' && (ipstr[1] == 'x' || ipstr[1] == 'X') {
+		if len(ipstr) > 10 {
+			return 0, ErrBadIP
+		}
+		for _, c := range ipstr[2:] {
+			d, ok := unhex(c)
+			if !ok {
+				return 0, ErrBadIP
+			}
+			ip = ip<<4 | uint64(d)
+		}
+		return uint32(ip), nil
+	}
+	if len(ipstr) == 0 {
+		return 0, ErrBadIP
+	}
+	for _, c := range ipstr {
+		if c < '0' || c > '9' {
+			return 0, ErrBadIP
+		}
+		if ip = ip*10 + uint64(c-'0'); ip > 0xffffffff {
+			return 0, ErrBadIP
+		}
+	}
+	return uint32(ip), nil
+}
@@ -0,0 +1,84 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindIPv4(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("a00::/8", 3)
+
+	for ip, val := range map[uint32]interface{}{0x0a010203: 2, 0x0a020304: 1, 0x0b000001: nil} {
+		if inf := tr.FindIPv4(ip); inf != val {
+			t.Errorf("Wrong value for %08x, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if inf := tr.FindIPv4Bytes(10, 1, 255, 255); inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	if inf := tr.FindIPv4Bytes(9, 255, 255, 255); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { tr.FindIPv4Bytes(10, 1, 2, 3) }); allocs != 0 {
+		t.Errorf("FindIPv4Bytes should not allocate, got %v allocations", allocs)
+	}
+}
+
+func TestKey16(t *testing.T) {
+	tr := NewTree(0)
+	key := [16]byte{0xde, 0xad, 0xbe, 0xef, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	if err := tr.Add16(key, 32, 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.Add16(key, 128, 2); err != nil {
+		t.Error(err)
+	}
+	if err := tr.Add16(key, 32, 3); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if err := tr.Add16(key, 129, 3); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+	if inf, _ := tr.FindCIDR("dead:beef::/32"); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	if inf := tr.Find16(key); inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	key[15] = 2
+	if inf := tr.Find16(key); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	key[0] = 0
+	if inf := tr.Find16(key); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+}
+
+func TestFindNumeric(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("74.122.28.0/24", 1)
+	tr.AddCIDR("0.0.0.0/8", 2)
+
+	for ip, val := range map[string]interface{}{"1249516568": 1, "0x4A7A1C18": 1, "0x4a7a1cff": 1, "0": 2, "0x1": 2, "4294967295": nil} {
+		inf, err := tr.FindNumeric(ip)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", ip, err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %q, expected %v, got %v", ip, val, inf)
+		}
+	}
+	for _, ip := range []string{"", "0x", "4294967296", "0x100000000", "0x4G", "74.122.28.24", "-1"} {
+		if _, err := tr.FindNumeric(ip); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP for %q, instead got err: %v", ip, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// rcubatch is number of retired nodes that makes writer wait for readers and reuse them.
+const rcubatch = 256
+
+type rcunode struct {
+	left, right atomic.Pointer[rcunode]
+	value       atomic.Pointer[interface{}]
+	// parent and next are used only by writers
+	parent, next *rcunode
+}
+
+// RCUTree is radix tree for IP/mask with wait-free lookups. Writers modify nodes in place and are serialized by mutex,
+// removed nodes are kept aside until every reader that could see them is gone and only then reused, each such grace period bumps generation counter.
+// Compared to COWTree only changed nodes are written, paths from the root are not copied. Zero value is empty tree.
+type RCUTree struct {
+	mu     sync.Mutex
+	v4, v6 rcunode
+
+	generation atomic.Uint64
+	readers    [2]atomic.Int64
+	count      atomic.Int64
+
+	retired []*rcunode
+	free    *rcunode
+}
+
+// NewRCUTree creates empty RCUTree.
+func NewRCUTree() *RCUTree {
+	return new(RCUTree)
+}
+
+// AddCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR or if value already exists.
+func (tree *RCUTree) AddCIDR(cidr string, val interface{}) error {
+	return tree.AddCIDRb([]byte(cidr), val)
+}
+
+func (tree *RCUTree) AddCIDRb(cidr []byte, val interface{}) error {
+	return tree.set(cidr, val, false)
+}
+
+// SetCIDR sets value associated with IP/mask in the tree, overwriting existing one.
+func (tree *RCUTree) SetCIDR(cidr string, val interface{}) error {
+	return tree.SetCIDRb([]byte(cidr), val)
+}
+
+func (tree *RCUTree) SetCIDRb(cidr []byte, val interface{}) error {
+	return tree.set(cidr, val, true)
+}
+
+// DeleteCIDR removes value associated with IP/mask from the tree.
+func (tree *RCUTree) DeleteCIDR(cidr string) error {
+	return tree.DeleteCIDRb([]byte(cidr))
+}
+
+func (tree *RCUTree) DeleteCIDRb(cidr []byte) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	bits := masklen(mask)
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+
+	n := tree.root(key)
+	for d := 0; n != nil && d < bits; d++ {
+		n = n.child(key, d)
+	}
+	if n == nil || n.value.Load() == nil {
+		return wrap("delete", cidr, ErrNotFound)
+	}
+	n.value.Store(nil)
+	tree.count.Add(-1)
+	for n.parent != nil && n.value.Load() == nil && n.left.Load() == nil && n.right.Load() == nil {
+		if n.parent.right.Load() == n {
+			n.parent.right.Store(nil)
+		} else {
+			n.parent.left.Store(nil)
+		}
+		tree.retired = append(tree.retired, n)
+		n = n.parent
+	}
+	if len(tree.retired) >= rcubatch {
+		tree.synchronize()
+	}
+	return nil
+}
+
+// FindCIDR traverses tree to proper Node and returns previously saved information in longest covered IP.
+func (tree *RCUTree) FindCIDR(cidr string) (interface{}, error) {
+	return tree.FindCIDRb([]byte(cidr))
+}
+
+func (tree *RCUTree) FindCIDRb(cidr []byte) (interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	bits := masklen(mask)
+	g := tree.enter()
+	defer tree.readers[g&1].Add(-1)
+
+	var value *interface{}
+	n := tree.root(key)
+	for d := 0; n != nil; d++ {
+		if v := n.value.Load(); v != nil {
+			value = v
+		}
+		if d == bits {
+			break
+		}
+		n = n.child(key, d)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	return *value, nil
+}
+
+// Len returns number of entries stored in the tree.
+func (tree *RCUTree) Len() int {
+	return int(tree.count.Load())
+}
+
+// Generation returns number of grace periods passed, every one of them makes removed nodes available for reuse.
+func (tree *RCUTree) Generation() uint64 {
+	return tree.generation.Load()
+}
+
+// Synchronize waits for all lookups started before the call to finish and reuses nodes removed by then. Writers do it on their own after removing many nodes.
+func (tree *RCUTree) Synchronize() {
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+	tree.synchronize()
+}
+
+func (tree *RCUTree) set(cidr []byte, val interface{}, overwrite bool) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	bits := masklen(mask)
+	tree.mu.Lock()
+	defer tree.mu.Unlock()
+
+	n := tree.root(key)
+	for d := 0; d < bits; d++ {
+		next := n.child(key, d)
+		if next == nil {
+			// node is fully set up before it is published to readers
+			next = tree.newnode(n)
+			if bitset(key, d) {
+				n.right.Store(next)
+			} else {
+				n.left.Store(next)
+			}
+		}
+		n = next
+	}
+	old := n.value.Load()
+	if old != nil && !overwrite {
+		return wrap("add", cidr, ErrNodeBusy)
+	}
+	switch {
+	case old == nil && val != nil:
+		tree.count.Add(1)
+	case old != nil && val == nil:
+		tree.count.Add(-1)
+	}
+	if val == nil {
+		n.value.Store(nil)
+	} else {
+		n.value.Store(&val)
+	}
+	return nil
+}
+
+func (tree *RCUTree) root(key net.IP) *rcunode {
+	if len(key) == net.IPv4len {
+		return &tree.v4
+	}
+	return &tree.v6
+}
+
+// enter registers reader in current generation and returns it.
+func (tree *RCUTree) enter() uint64 {
+	for {
+		g := tree.generation.Load()
+		tree.readers[g&1].Add(1)
+		if tree.generation.Load() == g {
+			return g
+		}
+		// generation changed under us, writer may be already waiting for the old one
+		tree.readers[g&1].Add(-1)
+	}
+}
+
+// synchronize starts new generation, waits for readers of the old one and moves retired nodes to the free list.
+func (tree *RCUTree) synchronize() {
+	g := tree.generation.Add(1) - 1
+	for tree.readers[g&1].Load() != 0 {
+		runtime.Gosched()
+	}
+	for _, n := range tree.retired {
+		n.next = tree.free
+		tree.free = n
+	}
+	tree.retired = tree.retired[:0]
+}
+
+func (tree *RCUTree) newnode(parent *rcunode) *rcunode {
+	n := tree.free
+	if n == nil {
+		n = new(rcunode)
+	} else {
+		tree.free = n.next
+		n.left.Store(nil)
+		n.right.Store(nil)
+		n.value.Store(nil)
+		n.next = nil
+	}
+	n.parent = parent
+	return n
+}
+
+func (n *rcunode) child(key net.IP, d int) *rcunode {
+	if bitset(key, d) {
+		return n.right.Load()
+	}
+	return n.left.Load()
+}
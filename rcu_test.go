@@ -0,0 +1,111 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRCUTree(t *testing.T) {
+	tr := NewRCUTree()
+	if err := tr.AddCIDR("10.0.0.0/8", 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("10.1.0.0/16", 2); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("dead::/16", 3); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("10.1.0.0/16", 4); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	for ip, val := range map[string]interface{}{"10.1.1.1": 2, "10.2.1.1": 1, "11.0.0.1": nil, "dead::1": 3, "beef::1": nil} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", tr.Len())
+	}
+
+	if err := tr.DeleteCIDR("10.1.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteCIDR("10.1.0.0/16"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	inf, err := tr.FindCIDR("10.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+
+	g := tr.Generation()
+	tr.Synchronize()
+	if tr.Generation() != g+1 {
+		t.Errorf("Wrong generation, expected %d, got %d", g+1, tr.Generation())
+	}
+	if tr.free == nil {
+		t.Error("Removed nodes should be reused after synchronize")
+	}
+	if err := tr.AddCIDR("10.1.0.0/16", 5); err != nil {
+		t.Error(err)
+	}
+	inf, err = tr.FindCIDR("10.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != 5 {
+		t.Errorf("Wrong value, expected 5, got %v", inf)
+	}
+}
+
+func TestRCUTreeConcurrent(t *testing.T) {
+	tr := NewRCUTree()
+	tr.AddCIDR("10.0.0.0/8", -1)
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				inf, err := tr.FindCIDR(fmt.Sprintf("10.%d.1.1", i%256))
+				if err != nil || inf == nil {
+					t.Errorf("Wrong value, got %v, %v", inf, err)
+					return
+				}
+			}
+		}()
+	}
+	for round := 0; round < 4; round++ {
+		for i := 0; i < 256; i++ {
+			tr.AddCIDR(fmt.Sprintf("10.%d.0.0/16", i), i)
+		}
+		for i := 0; i < 256; i++ {
+			tr.DeleteCIDR(fmt.Sprintf("10.%d.0.0/16", i))
+		}
+	}
+	close(done)
+	wg.Wait()
+	if tr.Len() != 1 || tr.Generation() == 0 {
+		t.Errorf("Wrong state, expected 1 entry and some generations, got %d and %d", tr.Len(), tr.Generation())
+	}
+}
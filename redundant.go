@@ -0,0 +1,95 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"reflect"
+)
+
+// RejectRedundant turns on (or off) rejecting of inserts with ErrRedundant when the closest entry covering the prefix already holds equal value, so the new one would change nothing.
+// Every insert is checked: Add and Set for CIDRs, prefixes, networks and ranges, their TTL and priority forms, Add16, SwapCIDR, UpdateCIDR (and so UpsertCIDR and AppendCIDR),
+// SetWholeRangeCIDR, AddBatch and transactions. Priorities are not compared. Values are compared by eq, nil eq compares them with reflect.DeepEqual.
+// Entries stored before are not checked, use Redundant to find them.
+func (tree *Tree) RejectRedundant(enable bool, eq func(a, b interface{}) bool) {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+	tree.redundant = nil
+	if enable {
+		tree.redundant = eq
+	}
+}
+
+// Redundant returns all entries holding value equal to the one of the closest entry covering them, so removing them does not change any lookup. Values are compared by eq, nil eq compares them with reflect.DeepEqual.
+func (tree *Tree) Redundant(eq func(a, b interface{}) bool) []Entry {
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+	var entries []Entry
+	var audit func(n, cover *node, key net.IP, d int)
+	audit = func(n, cover *node, key net.IP, d int) {
+		if n.live() {
+			if cover != nil && n.value != exclusion && cover.value != exclusion && eq(cover.value, n.value) {
+				entries = append(entries, newentry(key, d, n.value))
+			}
+			cover = n
+		}
+		if n.left != nil {
+			audit(n.left, cover, key, d+1)
+		}
+		if n.right != nil {
+			key[d>>3] |= startbyte >> uint(d&7)
+			audit(n.right, cover, key, d+1)
+			key[d>>3] &^= startbyte >> uint(d&7)
+		}
+	}
+	audit(tree.root, nil, make(net.IP, net.IPv4len), 0)
+	audit(tree.root6, nil, make(net.IP, net.IPv6len), 0)
+	return entries
+}
+
+// checkredundant returns error if the tree rejects redundant entries and storing val at cidr would change no lookup. Bad input and busy prefixes are left for the insert to report.
+func (tree *Tree) checkredundant(op string, cidr []byte, val interface{}, overwrite bool) error {
+	if tree.redundant == nil {
+		return nil
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil
+	}
+	if tree.isredundant(key, masklen(mask), val, overwrite) {
+		return wrap(op, cidr, ErrRedundant)
+	}
+	return nil
+}
+
+// insertop returns name of insert operation for errors.
+func insertop(overwrite bool) string {
+	if overwrite {
+		return "set"
+	}
+	return "add"
+}
+
+// isredundant reports whether val is equal to value of the closest entry covering key/bits and the entry at key/bits is either empty or holds equal value too.
+// Entry at key/bits is never redundant for inserts which do not overwrite, so they could report it as busy.
+func (tree *Tree) isredundant(key net.IP, bits int, val interface{}, overwrite bool) bool {
+	if val == nil {
+		return false
+	}
+	var cover *node
+	node := tree.rootof(key)
+	for d := 0; node != nil && d < bits; d++ {
+		if node.live() {
+			cover = node
+		}
+		node = node.child(key, d)
+	}
+	if node != nil && node.live() && (!overwrite || node.value == exclusion || !tree.redundant(node.value, val)) {
+		return false
+	}
+	return cover != nil && cover.value != exclusion && tree.redundant(cover.value, val)
+}
@@ -0,0 +1,139 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestRedundant(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "a")
+	tr.AddCIDR("10.1.0.0/16", "a")
+	tr.AddCIDR("10.1.2.0/24", "b")
+	tr.AddCIDR("10.1.2.128/25", "b")
+	tr.AddCIDR("10.1.3.0/24", "a")
+	tr.AddCIDR("dead::/16", []int{1})
+	tr.AddCIDR("dead:beef::/32", []int{1})
+	tr.ExcludeCIDR("10.2.0.0/16")
+	tr.AddCIDR("10.2.1.0/24", "a")
+
+	if entries := fmt.Sprint(prefixes(tr.Redundant(nil))); entries != "[10.1.0.0/16 10.1.2.128/25 10.1.3.0/24 dead:beef::/32]" {
+		t.Errorf("Wrong redundant entries, got %s", entries)
+	}
+	never := func(a, b interface{}) bool { return false }
+	if entries := tr.Redundant(never); len(entries) != 0 {
+		t.Errorf("Wrong redundant entries, expected none, got %v", entries)
+	}
+
+	tr.RejectRedundant(true, nil)
+	for _, c := range []struct {
+		cidr string
+		val  interface{}
+		err  error
+	}{{"10.1.4.0/24", "a", ErrRedundant}, {"10.1.2.0/26", "b", ErrRedundant}, {"10.1.4.0/24", "c", nil},
+		{"10.0.0.0/8", "a", nil}, {"10.2.2.0/24", "a", nil}, {"dead:beef:1::/48", []int{1}, ErrRedundant}, {"10.0.0.256/24", "a", ErrBadIP}} {
+		if err := tr.SetCIDR(c.cidr, c.val); !errors.Is(err, c.err) || (c.err == nil) != (err == nil) {
+			t.Errorf("Wrong result of setting %s to %v, expected %v, got err: %v", c.cidr, c.val, c.err, err)
+		}
+	}
+	if err := tr.AddCIDR("10.1.5.0/24", "a"); !errors.Is(err, ErrRedundant) {
+		t.Errorf("Should have gotten ErrRedundant, instead got err: %v", err)
+	}
+	if _, err := tr.ExactMatchCIDR("10.1.5.0/24"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Rejected entry should not be stored, instead got err: %v", err)
+	}
+
+	if err := tr.AddCIDR("10.1.0.0/16", "a"); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if _, _, err := tr.SwapCIDR("10.1.6.0/24", "a"); !errors.Is(err, ErrRedundant) {
+		t.Errorf("Should have gotten ErrRedundant, instead got err: %v", err)
+	}
+	if err := tr.AddBatch([]BatchEntry{{"10.1.7.0/24", "c"}, {"10.1.6.0/24", "a"}}); !errors.Is(err, ErrRedundant) {
+		t.Errorf("Should have gotten ErrRedundant, instead got err: %v", err)
+	}
+	tx := tr.Begin()
+	if err := tx.SetCIDR("10.1.6.0/24", "a"); !errors.Is(err, ErrRedundant) {
+		t.Errorf("Should have gotten ErrRedundant, instead got err: %v", err)
+	}
+	tx.Rollback()
+
+	// overwriting different value changes lookups, so it is never redundant
+	tr.SetCIDR("10.3.0.0/16", "b")
+	if err := tr.SetCIDR("10.3.0.0/16", "a"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("10.3.0.1"); inf != "a" {
+		t.Errorf("Wrong value, expected a, got %v", inf)
+	}
+	if err := tr.SetCIDR("10.3.0.0/16", "a"); !errors.Is(err, ErrRedundant) {
+		t.Errorf("Should have gotten ErrRedundant, instead got err: %v", err)
+	}
+
+	tr.RejectRedundant(false, nil)
+	if err := tr.AddCIDR("10.1.5.0/24", "a"); err != nil {
+		t.Error(err)
+	}
+}
+
+func prefixes(entries []Entry) []string {
+	var s []string
+	for _, e := range entries {
+		s = append(s, e.Prefix.String())
+	}
+	return s
+}
+
+func TestRedundantSetters(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "a")
+	tr.RejectRedundant(true, nil)
+	prefix, ipnet := netip.MustParsePrefix("10.1.0.0/16"), &net.IPNet{IP: net.IP{10, 1, 0, 0}, Mask: net.CIDRMask(16, 32)}
+	for name, set := range map[string]func() error{
+		"AddCIDR":         func() error { return tr.AddCIDR("10.1.0.0/16", "a") },
+		"SetCIDR":         func() error { return tr.SetCIDR("10.1.0.0/16", "a") },
+		"AddPrefix":       func() error { return tr.AddPrefix(prefix, "a") },
+		"SetPrefix":       func() error { return tr.SetPrefix(prefix, "a") },
+		"AddIPNet":        func() error { return tr.AddIPNet(ipnet, "a") },
+		"SetIPNet":        func() error { return tr.SetIPNet(ipnet, "a") },
+		"AddRange":        func() error { return tr.AddRange("10.1.0.0", "10.1.255.255", "a") },
+		"AddCIDRTTL":      func() error { return tr.AddCIDRTTL("10.1.0.0/16", "a", time.Hour) },
+		"SetCIDRTTL":      func() error { return tr.SetCIDRTTL("10.1.0.0/16", "a", time.Hour) },
+		"AddCIDRPriority": func() error { return tr.AddCIDRPriority("10.1.0.0/16", "a", 1) },
+		"SetCIDRPriority": func() error { return tr.SetCIDRPriority("10.1.0.0/16", "a", 1) },
+		"Add16":           func() error { return tr.Add16([16]byte{10: 0xff, 11: 0xff, 12: 10, 13: 1}, 112, "a") },
+		"SwapCIDR": func() error {
+			_, _, err := tr.SwapCIDR("10.1.0.0/16", "a")
+			return err
+		},
+		"UpdateCIDR": func() error {
+			return tr.UpdateCIDR("10.1.0.0/16", func(old interface{}, exists bool) (interface{}, bool) { return "a", true })
+		},
+		"UpsertCIDR": func() error { return tr.UpsertCIDR("10.1.0.0/16", "a", nil) },
+		"SetWholeRangeCIDR": func() error {
+			_, err := tr.SetWholeRangeCIDR("10.1.0.0/16", "a")
+			return err
+		},
+		"AddBatch": func() error { return tr.AddBatch([]BatchEntry{{"10.1.0.0/16", "a"}}) },
+		"Tx": func() error {
+			tx := tr.Begin()
+			defer tx.Rollback()
+			return tx.SetCIDR("10.1.0.0/16", "a")
+		},
+	} {
+		if err := set(); !errors.Is(err, ErrRedundant) {
+			t.Errorf("%s should have gotten ErrRedundant, instead got err: %v", name, err)
+		}
+		if tr.Len() != 1 {
+			t.Fatalf("%s stored redundant entry", name)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// ReloadableTree holds Tree which is replaced as a whole on reload. Lookups use current tree without locks while new one is being built,
+// trees published to ReloadableTree must not be modified anymore.
+type ReloadableTree struct {
+	tree atomic.Pointer[Tree]
+}
+
+// NewReloadableTree creates ReloadableTree holding tree (empty one if tree is nil).
+func NewReloadableTree(tree *Tree) *ReloadableTree {
+	if tree == nil {
+		tree = NewTree(0)
+	}
+	rt := new(ReloadableTree)
+	rt.tree.Store(tree)
+	return rt
+}
+
+// Load returns current tree, it could be used for any lookups but must not be modified.
+func (rt *ReloadableTree) Load() *Tree {
+	return rt.tree.Load()
+}
+
+// Swap publishes tree and returns previous one.
+func (rt *ReloadableTree) Swap(tree *Tree) *Tree {
+	return rt.tree.Swap(tree)
+}
+
+// BuildAndSwap calls build to fill fresh tree and publishes it if build succeeds, readers keep using previous tree until then.
+// Call it in a separate goroutine to reload in background.
+func (rt *ReloadableTree) BuildAndSwap(build func(tree *Tree) error) error {
+	tree := NewTree(0)
+	if err := build(tree); err != nil {
+		return err
+	}
+	rt.tree.Store(tree)
+	return nil
+}
+
+// ReloadCSV replaces tree with one loaded by LoadCSV, current tree is kept if input has errors.
+func (rt *ReloadableTree) ReloadCSV(r io.Reader, opts *CSVOptions) error {
+	return rt.BuildAndSwap(func(tree *Tree) error {
+		_, err := tree.LoadCSV(r, opts)
+		return err
+	})
+}
+
+// ReloadEntries replaces tree with one holding entries, current tree is kept if any of them is invalid.
+func (rt *ReloadableTree) ReloadEntries(entries []BatchEntry) error {
+	return rt.BuildAndSwap(func(tree *Tree) error {
+		return tree.AddBatch(entries)
+	})
+}
+
+// FindCIDR looks IP/mask up in current tree like Tree.FindCIDR.
+func (rt *ReloadableTree) FindCIDR(cidr string) (interface{}, error) {
+	return rt.tree.Load().FindCIDR(cidr)
+}
+
+// Contains checks IP/mask in current tree like Tree.Contains.
+func (rt *ReloadableTree) Contains(ip string) bool {
+	return rt.tree.Load().Contains(ip)
+}
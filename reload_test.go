@@ -0,0 +1,60 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReloadableTree(t *testing.T) {
+	rt := NewReloadableTree(nil)
+	if rt.Contains("10.0.0.1") {
+		t.Error("Empty tree should not contain anything")
+	}
+
+	if err := rt.ReloadEntries([]BatchEntry{{"10.0.0.0/8", 1}}); err != nil {
+		t.Error(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			if inf, err := rt.FindCIDR("10.1.1.1"); err != nil || inf == nil {
+				t.Errorf("Reader should always see complete tree, got %v, %v", inf, err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		err := rt.ReloadCSV(strings.NewReader("10.0.0.0/8,a\n10.1.0.0/16,b\n"), nil)
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+
+	// bad input keeps current tree
+	if err := rt.ReloadCSV(strings.NewReader("11.0.0.0/8,a\nbad,b\n"), nil); err == nil {
+		t.Error("Should have gotten error for bad input")
+	}
+	inf, err := rt.FindCIDR("10.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != "b" {
+		t.Errorf("Wrong value, expected b, got %v", inf)
+	}
+	if rt.Contains("11.0.0.1") {
+		t.Error("Failed reload should not be published")
+	}
+
+	old := rt.Swap(NewTree(0))
+	if old.Len() != 2 || rt.Load().Len() != 0 {
+		t.Errorf("Swap should publish new tree, got %d and %d entries", old.Len(), rt.Load().Len())
+	}
+}
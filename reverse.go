@@ -0,0 +1,104 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+)
+
+var (
+	suffix4 = []byte(".in-addr.arpa")
+	suffix6 = []byte(".ip6.arpa")
+)
+
+// FindReverseName returns previously saved information in longest prefix covering address of reverse DNS name ("24.28.26.73.in-addr.arpa" or nibble-format ip6.arpa name).
+// Names with fewer labels ("26.73.in-addr.arpa") are looked up as prefixes of the corresponding length. Trailing dot is optional.
+func (tree *Tree) FindReverseName(name string) (interface{}, error) {
+	return tree.FindReverseNameb([]byte(name))
+}
+
+func (tree *Tree) FindReverseNameb(name []byte) (interface{}, error) {
+	if l := len(name); l > 0 && name[l-1] == '.' {
+		name = name[:l-1]
+	}
+	switch {
+	case hassuffix(name, suffix4):
+		key, bits, err := parsereverse4(name[:len(name)-len(suffix4)])
+		if err != nil {
+			return nil, wrap("parse", name, err)
+		}
+		return tree.find32(key, ^uint32(0)<<(32-bits)), nil
+	case hassuffix(name, suffix6):
+		hi, lo, bits, err := parsereverse6(name[:len(name)-len(suffix6)])
+		if err != nil {
+			return nil, wrap("parse", name, err)
+		}
+		root, hi, lo, bits := tree.key6(hi, lo, bits)
+		return tree.findkey(root, hi, lo, bits), nil
+	}
+	return nil, wrap("parse", name, ErrBadIP)
+}
+
+// hassuffix reports whether name ends with suffix ignoring case.
+func hassuffix(name, suffix []byte) bool {
+	return len(name) >= len(suffix) && bytes.EqualFold(name[len(name)-len(suffix):], suffix)
+}
+
+// parsereverse4 parses reversed decimal octets of in-addr.arpa name into key and prefix length.
+func parsereverse4(labels []byte) (key uint32, bits int, err error) {
+	for len(labels) > 0 {
+		if bits == 32 {
+			return 0, 0, ErrBadIP
+		}
+		label := labels
+		if p := bytes.LastIndexByte(labels, '.'); p >= 0 {
+			label, labels = labels[p+1:], labels[:p]
+			if p == 0 {
+				return 0, 0, ErrBadIP
+			}
+		} else {
+			labels = nil
+		}
+		if len(label) == 0 || len(label) > 3 {
+			return 0, 0, ErrBadIP
+		}
+		var oct uint32
+		for _, c := range label {
+			if c < '0' || c > '9' {
+				return 0, 0, ErrBadIP
+			}
+			oct = oct*10 + uint32(c-'0')
+		}
+		if oct > 255 {
+			return 0, 0, ErrBadIP
+		}
+		key |= oct << uint(24-bits)
+		bits += 8
+	}
+	if bits == 0 {
+		return 0, 0, ErrBadIP
+	}
+	return key, bits, nil
+}
+
+// parsereverse6 parses reversed hex nibbles of ip6.arpa name into key loaded as by loadkey and prefix length.
+func parsereverse6(labels []byte) (hi, lo uint64, bits int, err error) {
+	if len(labels) == 0 || len(labels)%2 != 1 || len(labels) > 63 {
+		return 0, 0, 0, ErrBadIP
+	}
+	for i := len(labels) - 1; i >= 0; i -= 2 {
+		d, ok := unhex(labels[i])
+		if !ok || (i > 0 && labels[i-1] != '.') {
+			return 0, 0, 0, ErrBadIP
+		}
+		if bits < 64 {
+			hi |= uint64(d) << uint(60-bits)
+		} else {
+			lo |= uint64(d) << uint(124-bits)
+		}
+		bits += 4
+	}
+	return hi, lo, bits, nil
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindReverseName(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("73.26.28.0/24", 1)
+	tr.AddCIDR("73.0.0.0/8", 2)
+	tr.AddCIDR("2001:db8::/32", 3)
+	tr.AddCIDR("2001:db8::1/128", 4)
+
+	for name, val := range map[string]interface{}{
+		"24.28.26.73.in-addr.arpa":  1,
+		"24.28.26.73.IN-ADDR.ARPA.": 1,
+		"24.29.26.73.in-addr.arpa":  2,
+		"28.26.73.in-addr.arpa":     1,
+		"26.73.in-addr.arpa":        2,
+		"24.28.26.74.in-addr.arpa":  nil,
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa": 4,
+		"2.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.B.D.0.1.0.0.2.ip6.arpa": 3,
+		"8.b.d.0.1.0.0.2.ip6.arpa.": 3,
+		"9.b.d.0.1.0.0.2.ip6.arpa":  nil,
+	} {
+		inf, err := tr.FindReverseName(name)
+		if err != nil {
+			t.Errorf("Unexpected error for %q: %v", name, err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %q, expected %v, got %v", name, val, inf)
+		}
+	}
+
+	for _, name := range []string{"", "in-addr.arpa", ".in-addr.arpa", "256.28.26.73.in-addr.arpa", "1.24.28.26.73.in-addr.arpa",
+		"24..26.73.in-addr.arpa", "x.73.in-addr.arpa", "73.26.28.24", ".ip6.arpa", "10.b.d.0.ip6.arpa", "g.ip6.arpa",
+		"0.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa"} {
+		if _, err := tr.FindReverseName(name); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP for %q, instead got err: %v", name, err)
+		}
+	}
+}
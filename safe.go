@@ -0,0 +1,1092 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"iter"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+// SafeTree is Tree protected by RWMutex, lookups take read lock and modifications take write lock. It should be created with NewSafeTree, zero value could only be used for decoding.
+type SafeTree struct {
+	mu   sync.RWMutex
+	tree *Tree
+}
+
+// NewSafeTree creates SafeTree, preallocate has the same meaning as for NewTree.
+func NewSafeTree(preallocate int) *SafeTree {
+	return &SafeTree{tree: NewTree(preallocate)}
+}
+
+// View calls fn with underlying tree under read lock, so a number of lookups see the same state. Tree must not be modified or retained by fn.
+func (st *SafeTree) View(fn func(tree *Tree) error) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return fn(st.tree)
+}
+
+// Update calls fn with underlying tree under write lock, so a number of modifications are seen by readers at once. Tree must not be retained by fn.
+func (st *SafeTree) Update(fn func(tree *Tree) error) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return fn(st.tree)
+}
+
+// Stream sends entries recorded under read lock to returned channel, so tree could be modified while channel is read.
+func (st *SafeTree) Stream(ctx context.Context) <-chan Entry {
+	st.mu.RLock()
+	entries := make([]Entry, 0, st.tree.Len())
+	st.tree.walk(func(key net.IP, bits int, n *node) error {
+		entries = append(entries, newentry(key, bits, n.value))
+		return nil
+	})
+	st.mu.RUnlock()
+
+	ch := make(chan Entry)
+	go func() {
+		defer close(ch)
+		for _, e := range entries {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// StartJanitor starts goroutine calling Expire every interval, so nodes of expired entries are released even if they are never looked up again. Janitor stops when ctx is done.
+func (st *SafeTree) StartJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				st.Expire()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// FindCIDR is Tree.FindCIDR protected by the lock.
+func (st *SafeTree) FindCIDR(cidr string) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindCIDR(cidr)
+}
+
+func (st *SafeTree) FindCIDRb(cidr []byte) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindCIDRb(cidr)
+}
+
+// FindCIDRMatch is Tree.FindCIDRMatch protected by the lock.
+func (st *SafeTree) FindCIDRMatch(cidr string) (*Entry, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindCIDRMatch(cidr)
+}
+
+func (st *SafeTree) FindCIDRMatchb(cidr []byte) (*Entry, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindCIDRMatchb(cidr)
+}
+
+// GetCIDR is Tree.GetCIDR protected by the lock.
+func (st *SafeTree) GetCIDR(cidr string) (interface{}, bool, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.GetCIDR(cidr)
+}
+
+func (st *SafeTree) GetCIDRb(cidr []byte) (interface{}, bool, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.GetCIDRb(cidr)
+}
+
+// ExactMatchCIDR is Tree.ExactMatchCIDR protected by the lock.
+func (st *SafeTree) ExactMatchCIDR(cidr string) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.ExactMatchCIDR(cidr)
+}
+
+func (st *SafeTree) ExactMatchCIDRb(cidr []byte) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.ExactMatchCIDRb(cidr)
+}
+
+// FindShortestCIDR is Tree.FindShortestCIDR protected by the lock.
+func (st *SafeTree) FindShortestCIDR(cidr string) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindShortestCIDR(cidr)
+}
+
+func (st *SafeTree) FindShortestCIDRb(cidr []byte) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindShortestCIDRb(cidr)
+}
+
+// FindCIDRMaxLen is Tree.FindCIDRMaxLen protected by the lock.
+func (st *SafeTree) FindCIDRMaxLen(cidr string, maxBits int) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindCIDRMaxLen(cidr, maxBits)
+}
+
+func (st *SafeTree) FindCIDRMaxLenb(cidr []byte, maxBits int) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindCIDRMaxLenb(cidr, maxBits)
+}
+
+// FindAllValues is Tree.FindAllValues protected by the lock.
+func (st *SafeTree) FindAllValues(cidr string) ([]interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindAllValues(cidr)
+}
+
+func (st *SafeTree) FindAllValuesb(cidr []byte) ([]interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindAllValuesb(cidr)
+}
+
+// Contains is Tree.Contains protected by the lock.
+func (st *SafeTree) Contains(ip string) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Contains(ip)
+}
+
+func (st *SafeTree) Containsb(ip []byte) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Containsb(ip)
+}
+
+// ContainsIP is Tree.ContainsIP protected by the lock.
+func (st *SafeTree) ContainsIP(ip net.IP) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.ContainsIP(ip)
+}
+
+// SupernetsCIDR is Tree.SupernetsCIDR protected by the lock.
+func (st *SafeTree) SupernetsCIDR(cidr string) ([]Entry, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.SupernetsCIDR(cidr)
+}
+
+func (st *SafeTree) SupernetsCIDRb(cidr []byte) ([]Entry, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.SupernetsCIDRb(cidr)
+}
+
+// Ancestors is Tree.Ancestors protected by the lock.
+func (st *SafeTree) Ancestors(ip string) ([]Entry, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Ancestors(ip)
+}
+
+// DescendantsCIDR is Tree.DescendantsCIDR protected by the lock.
+func (st *SafeTree) DescendantsCIDR(cidr string) ([]Entry, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.DescendantsCIDR(cidr)
+}
+
+func (st *SafeTree) DescendantsCIDRb(cidr []byte) ([]Entry, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.DescendantsCIDRb(cidr)
+}
+
+// Len is Tree.Len protected by the lock.
+func (st *SafeTree) Len() int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Len()
+}
+
+// Reset is Tree.Reset protected by the lock.
+func (st *SafeTree) Reset() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.Reset()
+}
+
+// Compact is Tree.Compact protected by the lock.
+func (st *SafeTree) Compact() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Compact()
+}
+
+// SubtreeSize is Tree.SubtreeSize protected by the lock.
+func (st *SafeTree) SubtreeSize(cidr string) (int, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.SubtreeSize(cidr)
+}
+
+func (st *SafeTree) SubtreeSizeb(cidr []byte) (int, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.SubtreeSizeb(cidr)
+}
+
+// AddCIDR is Tree.AddCIDR protected by the lock.
+func (st *SafeTree) AddCIDR(cidr string, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddCIDR(cidr, val)
+}
+
+func (st *SafeTree) AddCIDRb(cidr []byte, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddCIDRb(cidr, val)
+}
+
+// SetCIDR is Tree.SetCIDR protected by the lock.
+func (st *SafeTree) SetCIDR(cidr string, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetCIDR(cidr, val)
+}
+
+func (st *SafeTree) SetCIDRb(cidr []byte, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetCIDRb(cidr, val)
+}
+
+// AddBatch is Tree.AddBatch protected by the lock.
+func (st *SafeTree) AddBatch(entries []BatchEntry) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddBatch(entries)
+}
+
+// SwapCIDR is Tree.SwapCIDR protected by the lock.
+func (st *SafeTree) SwapCIDR(cidr string, val interface{}) (interface{}, bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SwapCIDR(cidr, val)
+}
+
+func (st *SafeTree) SwapCIDRb(cidr []byte, val interface{}) (interface{}, bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SwapCIDRb(cidr, val)
+}
+
+// UpdateCIDR calls fn under write lock, fn must not use the tree.
+func (st *SafeTree) UpdateCIDR(cidr string, fn func(old interface{}, exists bool) (val interface{}, keep bool)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.UpdateCIDR(cidr, fn)
+}
+
+func (st *SafeTree) UpdateCIDRb(cidr []byte, fn func(old interface{}, exists bool) (val interface{}, keep bool)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.UpdateCIDRb(cidr, fn)
+}
+
+// UpsertCIDR is Tree.UpsertCIDR protected by the lock.
+func (st *SafeTree) UpsertCIDR(cidr string, val interface{}, merge func(old, new interface{}) interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.UpsertCIDR(cidr, val, merge)
+}
+
+func (st *SafeTree) UpsertCIDRb(cidr []byte, val interface{}, merge func(old, new interface{}) interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.UpsertCIDRb(cidr, val, merge)
+}
+
+// AppendCIDR is Tree.AppendCIDR protected by the lock.
+func (st *SafeTree) AppendCIDR(cidr string, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AppendCIDR(cidr, val)
+}
+
+func (st *SafeTree) AppendCIDRb(cidr []byte, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AppendCIDRb(cidr, val)
+}
+
+// AddRange is Tree.AddRange protected by the lock.
+func (st *SafeTree) AddRange(start, end string, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddRange(start, end, val)
+}
+
+func (st *SafeTree) AddRangeb(start, end []byte, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddRangeb(start, end, val)
+}
+
+// SetWholeRangeCIDR is Tree.SetWholeRangeCIDR protected by the lock.
+func (st *SafeTree) SetWholeRangeCIDR(cidr string, val interface{}) (int, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetWholeRangeCIDR(cidr, val)
+}
+
+func (st *SafeTree) SetWholeRangeCIDRb(cidr []byte, val interface{}) (int, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetWholeRangeCIDRb(cidr, val)
+}
+
+// DeleteCIDR is Tree.DeleteCIDR protected by the lock.
+func (st *SafeTree) DeleteCIDR(cidr string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteCIDR(cidr)
+}
+
+func (st *SafeTree) DeleteCIDRb(cidr []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteCIDRb(cidr)
+}
+
+// DeleteCIDRValue is Tree.DeleteCIDRValue protected by the lock.
+func (st *SafeTree) DeleteCIDRValue(cidr string) (interface{}, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteCIDRValue(cidr)
+}
+
+func (st *SafeTree) DeleteCIDRValueb(cidr []byte) (interface{}, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteCIDRValueb(cidr)
+}
+
+// DeleteWholeRangeCIDR is Tree.DeleteWholeRangeCIDR protected by the lock.
+func (st *SafeTree) DeleteWholeRangeCIDR(cidr string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteWholeRangeCIDR(cidr)
+}
+
+func (st *SafeTree) DeleteWholeRangeCIDRb(cidr []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteWholeRangeCIDRb(cidr)
+}
+
+// DeleteRange is Tree.DeleteRange protected by the lock.
+func (st *SafeTree) DeleteRange(start, end string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteRange(start, end)
+}
+
+func (st *SafeTree) DeleteRangeb(start, end []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteRangeb(start, end)
+}
+
+// DeleteIf calls fn for every entry under write lock, fn must not use the tree.
+func (st *SafeTree) DeleteIf(fn func(prefix string, val interface{}) bool) int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteIf(fn)
+}
+
+// LoadCSV is Tree.LoadCSV protected by the lock.
+func (st *SafeTree) LoadCSV(r io.Reader, opts *CSVOptions) (int, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.LoadCSV(r, opts)
+}
+
+// Walk calls fn for every prefix stored in the tree under read lock, fn must not modify the tree.
+func (st *SafeTree) Walk(fn func(prefix *net.IPNet, val interface{}) error) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Walk(fn)
+}
+
+// WalkUnderCIDR calls fn for every prefix inside IP/mask under read lock, fn must not modify the tree.
+func (st *SafeTree) WalkUnderCIDR(cidr string, fn func(prefix *net.IPNet, val interface{}) error) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.WalkUnderCIDR(cidr, fn)
+}
+
+func (st *SafeTree) WalkUnderCIDRb(cidr []byte, fn func(prefix *net.IPNet, val interface{}) error) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.WalkUnderCIDRb(cidr, fn)
+}
+
+// All returns iterator over all prefixes, read lock is held during whole iteration so tree must not be modified from the loop.
+func (st *SafeTree) All() iter.Seq2[netip.Prefix, interface{}] {
+	return func(yield func(netip.Prefix, interface{}) bool) {
+		st.mu.RLock()
+		defer st.mu.RUnlock()
+		st.tree.All()(yield)
+	}
+}
+
+// Sorted returns iterator over all prefixes in sorted order, read lock is held during whole iteration so tree must not be modified from the loop.
+func (st *SafeTree) Sorted() iter.Seq2[netip.Prefix, interface{}] {
+	return func(yield func(netip.Prefix, interface{}) bool) {
+		st.mu.RLock()
+		defer st.mu.RUnlock()
+		st.tree.Sorted()(yield)
+	}
+}
+
+// SnapshotAll is Tree.SnapshotAll protected by the lock.
+func (st *SafeTree) SnapshotAll() iter.Seq2[netip.Prefix, interface{}] {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.SnapshotAll()
+}
+
+// ListCIDRs is Tree.ListCIDRs protected by the lock.
+func (st *SafeTree) ListCIDRs() []string {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.ListCIDRs()
+}
+
+// ListPrefixes is Tree.ListPrefixes protected by the lock.
+func (st *SafeTree) ListPrefixes() []netip.Prefix {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.ListPrefixes()
+}
+
+// Keys is Tree.Keys protected by the lock.
+func (st *SafeTree) Keys() []netip.Prefix {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Keys()
+}
+
+// Values is Tree.Values protected by the lock.
+func (st *SafeTree) Values() []interface{} {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Values()
+}
+
+// MarshalBinary is Tree.MarshalBinary protected by the lock.
+func (st *SafeTree) MarshalBinary() ([]byte, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.MarshalBinary()
+}
+
+// MarshalBinaryWith is Tree.MarshalBinaryWith protected by the lock.
+func (st *SafeTree) MarshalBinaryWith(enc func(val interface{}) ([]byte, error)) ([]byte, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.MarshalBinaryWith(enc)
+}
+
+// UnmarshalBinary is Tree.UnmarshalBinary protected by the lock.
+func (st *SafeTree) UnmarshalBinary(data []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.tree == nil {
+		st.tree = new(Tree)
+	}
+	return st.tree.UnmarshalBinary(data)
+}
+
+// UnmarshalBinaryWith is Tree.UnmarshalBinaryWith protected by the lock.
+func (st *SafeTree) UnmarshalBinaryWith(data []byte, dec func(val []byte) (interface{}, error)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.tree == nil {
+		st.tree = new(Tree)
+	}
+	return st.tree.UnmarshalBinaryWith(data, dec)
+}
+
+// GobEncode is Tree.GobEncode protected by the lock.
+func (st *SafeTree) GobEncode() ([]byte, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.GobEncode()
+}
+
+// GobDecode is Tree.GobDecode protected by the lock.
+func (st *SafeTree) GobDecode(data []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.tree == nil {
+		st.tree = new(Tree)
+	}
+	return st.tree.GobDecode(data)
+}
+
+// MarshalJSON is Tree.MarshalJSON protected by the lock.
+func (st *SafeTree) MarshalJSON() ([]byte, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.MarshalJSON()
+}
+
+// MarshalJSONWith is Tree.MarshalJSONWith protected by the lock.
+func (st *SafeTree) MarshalJSONWith(enc func(val interface{}) (interface{}, error)) ([]byte, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.MarshalJSONWith(enc)
+}
+
+// UnmarshalJSON is Tree.UnmarshalJSON protected by the lock.
+func (st *SafeTree) UnmarshalJSON(data []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.tree == nil {
+		st.tree = new(Tree)
+	}
+	return st.tree.UnmarshalJSON(data)
+}
+
+// UnmarshalJSONWith is Tree.UnmarshalJSONWith protected by the lock.
+func (st *SafeTree) UnmarshalJSONWith(data []byte, dec func(raw json.RawMessage) (interface{}, error)) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.tree == nil {
+		st.tree = new(Tree)
+	}
+	return st.tree.UnmarshalJSONWith(data, dec)
+}
+
+// MarshalText is Tree.MarshalText protected by the lock.
+func (st *SafeTree) MarshalText() ([]byte, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.MarshalText()
+}
+
+// UnmarshalText is Tree.UnmarshalText protected by the lock.
+func (st *SafeTree) UnmarshalText(text []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.tree == nil {
+		st.tree = new(Tree)
+	}
+	return st.tree.UnmarshalText(text)
+}
+
+// WriteIndex is Tree.WriteIndex protected by the lock.
+func (st *SafeTree) WriteIndex(w io.Writer, enc func(val interface{}) ([]byte, error)) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.WriteIndex(w, enc)
+}
+
+// ExportIPSet is Tree.ExportIPSet protected by the lock.
+func (st *SafeTree) ExportIPSet(w io.Writer, setName string, opts *IPSetOptions) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.ExportIPSet(w, setName, opts)
+}
+
+// DumpDOT is Tree.DumpDOT protected by the lock.
+func (st *SafeTree) DumpDOT(w io.Writer) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.DumpDOT(w)
+}
+
+// CountHits is Tree.CountHits protected by the lock.
+func (st *SafeTree) CountHits(enable bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.CountHits(enable)
+}
+
+// HitsCIDR is Tree.HitsCIDR protected by the lock.
+func (st *SafeTree) HitsCIDR(cidr string) (uint64, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.HitsCIDR(cidr)
+}
+
+func (st *SafeTree) HitsCIDRb(cidr []byte) (uint64, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.HitsCIDRb(cidr)
+}
+
+// WalkHits is Tree.WalkHits protected by the lock.
+func (st *SafeTree) WalkHits(fn func(prefix *net.IPNet, hits uint64) error) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.WalkHits(fn)
+}
+
+// ResetHits is Tree.ResetHits protected by the lock, counters are atomic so it runs concurrently with lookups.
+func (st *SafeTree) ResetHits() {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	st.tree.ResetHits()
+}
+
+// AddPrefix is Tree.AddPrefix protected by the lock.
+func (st *SafeTree) AddPrefix(prefix netip.Prefix, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddPrefix(prefix, val)
+}
+
+// SetPrefix is Tree.SetPrefix protected by the lock.
+func (st *SafeTree) SetPrefix(prefix netip.Prefix, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetPrefix(prefix, val)
+}
+
+// DeletePrefix is Tree.DeletePrefix protected by the lock.
+func (st *SafeTree) DeletePrefix(prefix netip.Prefix) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeletePrefix(prefix)
+}
+
+// FindPrefix is Tree.FindPrefix protected by the lock.
+func (st *SafeTree) FindPrefix(prefix netip.Prefix) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindPrefix(prefix)
+}
+
+// FindAddr is Tree.FindAddr protected by the lock.
+func (st *SafeTree) FindAddr(addr netip.Addr) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindAddr(addr)
+}
+
+// ContainsAddr is Tree.ContainsAddr protected by the lock.
+func (st *SafeTree) ContainsAddr(addr netip.Addr) bool {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.ContainsAddr(addr)
+}
+
+// AddIPNet is Tree.AddIPNet protected by the lock.
+func (st *SafeTree) AddIPNet(ipnet *net.IPNet, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddIPNet(ipnet, val)
+}
+
+// SetIPNet is Tree.SetIPNet protected by the lock.
+func (st *SafeTree) SetIPNet(ipnet *net.IPNet, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetIPNet(ipnet, val)
+}
+
+// DeleteIPNet is Tree.DeleteIPNet protected by the lock.
+func (st *SafeTree) DeleteIPNet(ipnet *net.IPNet) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteIPNet(ipnet)
+}
+
+// FindIPNet is Tree.FindIPNet protected by the lock.
+func (st *SafeTree) FindIPNet(ipnet *net.IPNet) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindIPNet(ipnet)
+}
+
+// FindIP is Tree.FindIP protected by the lock.
+func (st *SafeTree) FindIP(ip net.IP) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindIP(ip)
+}
+
+// FindIPv4 is Tree.FindIPv4 protected by the lock.
+func (st *SafeTree) FindIPv4(ip uint32) interface{} {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindIPv4(ip)
+}
+
+// FindIPv4Bytes is Tree.FindIPv4Bytes protected by the lock.
+func (st *SafeTree) FindIPv4Bytes(a, b, c, d byte) interface{} {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindIPv4Bytes(a, b, c, d)
+}
+
+// Find16 is Tree.Find16 protected by the lock.
+func (st *SafeTree) Find16(key [16]byte) interface{} {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Find16(key)
+}
+
+// Add16 is Tree.Add16 protected by the lock.
+func (st *SafeTree) Add16(key [16]byte, bits int, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Add16(key, bits, val)
+}
+
+// FindNumeric is Tree.FindNumeric protected by the lock.
+func (st *SafeTree) FindNumeric(ip string) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindNumeric(ip)
+}
+
+func (st *SafeTree) FindNumericb(ip []byte) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindNumericb(ip)
+}
+
+// FindReverseName is Tree.FindReverseName protected by the lock.
+func (st *SafeTree) FindReverseName(name string) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindReverseName(name)
+}
+
+func (st *SafeTree) FindReverseNameb(name []byte) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindReverseNameb(name)
+}
+
+// Deaggregate is Tree.Deaggregate protected by the lock.
+func (st *SafeTree) Deaggregate(cidr string, newLen int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Deaggregate(cidr, newLen)
+}
+
+func (st *SafeTree) Deaggregateb(cidr []byte, newLen int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Deaggregateb(cidr, newLen)
+}
+
+// Gaps is Tree.Gaps protected by the lock.
+func (st *SafeTree) Gaps(cidr string) ([]netip.Prefix, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Gaps(cidr)
+}
+
+func (st *SafeTree) Gapsb(cidr []byte) ([]netip.Prefix, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Gapsb(cidr)
+}
+
+// Coverage is Tree.Coverage protected by the lock.
+func (st *SafeTree) Coverage(cidr string) (float64, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Coverage(cidr)
+}
+
+func (st *SafeTree) Coverageb(cidr []byte) (float64, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Coverageb(cidr)
+}
+
+// AddCIDRTTL is Tree.AddCIDRTTL protected by the lock.
+func (st *SafeTree) AddCIDRTTL(cidr string, val interface{}, ttl time.Duration) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddCIDRTTL(cidr, val, ttl)
+}
+
+func (st *SafeTree) AddCIDRTTLb(cidr []byte, val interface{}, ttl time.Duration) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddCIDRTTLb(cidr, val, ttl)
+}
+
+// SetCIDRTTL is Tree.SetCIDRTTL protected by the lock.
+func (st *SafeTree) SetCIDRTTL(cidr string, val interface{}, ttl time.Duration) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetCIDRTTL(cidr, val, ttl)
+}
+
+func (st *SafeTree) SetCIDRTTLb(cidr []byte, val interface{}, ttl time.Duration) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetCIDRTTLb(cidr, val, ttl)
+}
+
+// Expire is Tree.Expire protected by the lock.
+func (st *SafeTree) Expire() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Expire()
+}
+
+// SetMaxEntries is Tree.SetMaxEntries protected by the lock.
+func (st *SafeTree) SetMaxEntries(max int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.SetMaxEntries(max)
+}
+
+// TrackDetails is Tree.TrackDetails protected by the lock.
+func (st *SafeTree) TrackDetails(enable bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.TrackDetails(enable)
+}
+
+// LookupDetail is Tree.LookupDetail protected by the lock.
+func (st *SafeTree) LookupDetail(cidr string) (*Detail, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.LookupDetail(cidr)
+}
+
+func (st *SafeTree) LookupDetailb(cidr []byte) (*Detail, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.LookupDetailb(cidr)
+}
+
+// OnInsert is Tree.OnInsert protected by the lock.
+func (st *SafeTree) OnInsert(fn func(prefix netip.Prefix, old, new interface{})) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.OnInsert(fn)
+}
+
+// OnDelete is Tree.OnDelete protected by the lock.
+func (st *SafeTree) OnDelete(fn func(prefix netip.Prefix, old interface{})) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.OnDelete(fn)
+}
+
+// OnEvict is Tree.OnEvict protected by the lock.
+func (st *SafeTree) OnEvict(fn func(prefix netip.Prefix, old interface{})) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.OnEvict(fn)
+}
+
+// MatchByPriority is Tree.MatchByPriority protected by the lock.
+func (st *SafeTree) MatchByPriority(enable bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.MatchByPriority(enable)
+}
+
+// AddCIDRPriority is Tree.AddCIDRPriority protected by the lock.
+func (st *SafeTree) AddCIDRPriority(cidr string, val interface{}, priority int32) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddCIDRPriority(cidr, val, priority)
+}
+
+func (st *SafeTree) AddCIDRPriorityb(cidr []byte, val interface{}, priority int32) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.AddCIDRPriorityb(cidr, val, priority)
+}
+
+// SetCIDRPriority is Tree.SetCIDRPriority protected by the lock.
+func (st *SafeTree) SetCIDRPriority(cidr string, val interface{}, priority int32) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetCIDRPriority(cidr, val, priority)
+}
+
+func (st *SafeTree) SetCIDRPriorityb(cidr []byte, val interface{}, priority int32) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetCIDRPriorityb(cidr, val, priority)
+}
+
+// ExcludeCIDR is Tree.ExcludeCIDR protected by the lock.
+func (st *SafeTree) ExcludeCIDR(cidr string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.ExcludeCIDR(cidr)
+}
+
+func (st *SafeTree) ExcludeCIDRb(cidr []byte) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.ExcludeCIDRb(cidr)
+}
+
+// SetTag is Tree.SetTag protected by the lock.
+func (st *SafeTree) SetTag(cidr, tag string, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetTag(cidr, tag, val)
+}
+
+func (st *SafeTree) SetTagb(cidr []byte, tag string, val interface{}) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.SetTagb(cidr, tag, val)
+}
+
+// DeleteTag is Tree.DeleteTag protected by the lock.
+func (st *SafeTree) DeleteTag(cidr, tag string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteTag(cidr, tag)
+}
+
+func (st *SafeTree) DeleteTagb(cidr []byte, tag string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.DeleteTagb(cidr, tag)
+}
+
+// FindTag is Tree.FindTag protected by the lock.
+func (st *SafeTree) FindTag(cidr, tag string) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindTag(cidr, tag)
+}
+
+func (st *SafeTree) FindTagb(cidr []byte, tag string) (interface{}, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindTagb(cidr, tag)
+}
+
+// FindTags is Tree.FindTags protected by the lock.
+func (st *SafeTree) FindTags(cidr string) (Tags, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindTags(cidr)
+}
+
+func (st *SafeTree) FindTagsb(cidr []byte) (Tags, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.FindTagsb(cidr)
+}
+
+// Checkpoint is Tree.Checkpoint protected by the lock.
+func (st *SafeTree) Checkpoint() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Checkpoint()
+}
+
+// Rollback is Tree.Rollback protected by the lock.
+func (st *SafeTree) Rollback(id int) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.tree.Rollback(id)
+}
+
+// SetMaxCheckpoints is Tree.SetMaxCheckpoints protected by the lock.
+func (st *SafeTree) SetMaxCheckpoints(max int) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.SetMaxCheckpoints(max)
+}
+
+// Checkpoints is Tree.Checkpoints protected by the lock.
+func (st *SafeTree) Checkpoints() []int {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Checkpoints()
+}
+
+// Stats is Tree.Stats protected by the lock.
+func (st *SafeTree) Stats() Stats {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Stats()
+}
+
+// Validate is Tree.Validate protected by the lock.
+func (st *SafeTree) Validate() error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Validate()
+}
+
+// Dump is Tree.Dump protected by the lock.
+func (st *SafeTree) Dump(w io.Writer, empty bool) error {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Dump(w, empty)
+}
+
+// String is Tree.String protected by the lock.
+func (st *SafeTree) String() string {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.String()
+}
+
+// RejectHostBits is Tree.RejectHostBits protected by the lock.
+func (st *SafeTree) RejectHostBits(enable bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.RejectHostBits(enable)
+}
+
+// RejectRedundant is Tree.RejectRedundant protected by the lock.
+func (st *SafeTree) RejectRedundant(enable bool, eq func(a, b interface{}) bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.tree.RejectRedundant(enable, eq)
+}
+
+// Redundant is Tree.Redundant protected by the lock.
+func (st *SafeTree) Redundant(eq func(a, b interface{}) bool) []Entry {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.tree.Redundant(eq)
+}
@@ -0,0 +1,93 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSafeTree(t *testing.T) {
+	st := NewSafeTree(0)
+	st.AddCIDR("10.0.0.0/8", 0)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				st.SetCIDR(fmt.Sprintf("10.%d.%d.0/24", w, i), i)
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if inf, err := st.FindCIDR("10.200.0.1"); err != nil || inf.(int) != 0 {
+					t.Errorf("Wrong value, expected 0, got %v, %v", inf, err)
+				}
+				for range st.All() {
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if st.Len() != 401 {
+		t.Errorf("Wrong length, expected 401, got %d", st.Len())
+	}
+
+	err := st.Update(func(tree *Tree) error {
+		tree.DeleteWholeRangeCIDR("10.0.0.0/8")
+		return tree.AddCIDR("11.0.0.0/8", 1)
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	err = st.View(func(tree *Tree) error {
+		if tree.Len() != 1 {
+			t.Errorf("Wrong length, expected 1, got %d", tree.Len())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	var decoded SafeTree
+	if err = json.Unmarshal([]byte(`{"12.0.0.0/8":2}`), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Contains("12.1.1.1") {
+		t.Error("Decoded tree should contain 12.1.1.1")
+	}
+}
+
+func TestJanitor(t *testing.T) {
+	st := NewSafeTree(0)
+	st.AddCIDR("10.0.0.0/8", 1)
+	st.AddCIDRTTL("10.1.0.0/16", 2, time.Millisecond)
+	st.AddCIDRTTL("dead::/16", 3, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	st.StartJanitor(ctx, time.Millisecond)
+	for deadline := time.Now().Add(time.Second); st.Len() != 1 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	if st.Len() != 1 {
+		t.Errorf("Wrong length, expected 1, got %d", st.Len())
+	}
+
+	cancel()
+	time.Sleep(5 * time.Millisecond)
+	st.AddCIDRTTL("10.2.0.0/16", 4, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if st.Len() != 2 {
+		t.Errorf("Stopped janitor should not expire entries, got length %d", st.Len())
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+type shard struct {
+	mu   sync.RWMutex
+	tree *Tree
+}
+
+// ShardedTree partitions address space by first bits of the key into independent trees, each protected by its own lock,
+// so modifications in different shards run concurrently. Prefixes shorter than shard bits are stored in every shard they cover.
+type ShardedTree struct {
+	bits   int
+	shards []shard
+	count  atomic.Int64
+}
+
+// NewShardedTree creates ShardedTree with 2^bits shards, bits is limited to 1..8.
+func NewShardedTree(bits int) *ShardedTree {
+	if bits < 1 {
+		bits = 1
+	}
+	if bits > 8 {
+		bits = 8
+	}
+	st := &ShardedTree{bits: bits, shards: make([]shard, 1<<uint(bits))}
+	for i := range st.shards {
+		st.shards[i].tree = NewTree(0)
+	}
+	return st
+}
+
+// AddCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR or if value already exists.
+func (st *ShardedTree) AddCIDR(cidr string, val interface{}) error {
+	return st.set([]byte(cidr), val, false)
+}
+
+// SetCIDR sets value associated with IP/mask, overwriting existing one.
+func (st *ShardedTree) SetCIDR(cidr string, val interface{}) error {
+	return st.set([]byte(cidr), val, true)
+}
+
+// DeleteCIDR removes value associated with IP/mask from the tree.
+func (st *ShardedTree) DeleteCIDR(cidr string) error {
+	key, bits, shards, err := st.parse([]byte(cidr))
+	if err != nil {
+		return err
+	}
+	st.lock(shards)
+	defer st.unlock(shards)
+	for _, s := range shards {
+		n := s.tree.lookup(key, bits)
+		if n == nil || n.value == nil {
+			return wrap("delete", []byte(cidr), ErrNotFound)
+		}
+	}
+	for _, s := range shards {
+		n := s.tree.lookup(key, bits)
+		s.tree.setvalue(n, nil)
+		s.tree.trim(n)
+	}
+	st.count.Add(-1)
+	return nil
+}
+
+// FindCIDR traverses shard of IP/mask and returns previously saved information in longest covered IP.
+func (st *ShardedTree) FindCIDR(cidr string) (interface{}, error) {
+	key, mask, err := parsecidr([]byte(cidr))
+	if err != nil {
+		return nil, err
+	}
+	s := &st.shards[key[0]>>uint(8-st.bits)]
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n, _ := s.tree.match(key, masklen(mask))
+	if n == nil {
+		return nil, nil
+	}
+	return n.value, nil
+}
+
+// Contains reports whether IP (or IP/mask) is covered by any prefix stored in the tree.
+func (st *ShardedTree) Contains(ip string) bool {
+	val, err := st.FindCIDR(ip)
+	return err == nil && val != nil
+}
+
+// Len returns number of entries stored in the tree, prefixes stored in a number of shards are counted once.
+func (st *ShardedTree) Len() int {
+	return int(st.count.Load())
+}
+
+func (st *ShardedTree) set(cidr []byte, val interface{}, overwrite bool) error {
+	key, bits, shards, err := st.parse(cidr)
+	if err != nil {
+		return err
+	}
+	st.lock(shards)
+	defer st.unlock(shards)
+	n := shards[0].tree.lookup(key, bits)
+	exists := n != nil && n.value != nil
+	if exists && !overwrite {
+		return wrap("add", cidr, ErrNodeBusy)
+	}
+	for _, s := range shards {
+		s.tree.setvalue(s.tree.locate(key, bits), val)
+	}
+	switch {
+	case !exists && val != nil:
+		st.count.Add(1)
+	case exists && val == nil:
+		st.count.Add(-1)
+	}
+	return nil
+}
+
+// parse returns key of CIDR with its length and shards covered by it.
+func (st *ShardedTree) parse(cidr []byte) (net.IP, int, []*shard, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	bits := masklen(mask)
+	key = key.Mask(mask)
+	first := int(key[0] >> uint(8-st.bits))
+	count := 1
+	if bits < st.bits {
+		count = 1 << uint(st.bits-bits)
+	}
+	shards := make([]*shard, count)
+	for i := range shards {
+		shards[i] = &st.shards[first+i]
+	}
+	return key, bits, shards, nil
+}
+
+// lock takes locks of shards, shards are always in ascending order so there are no deadlocks.
+func (st *ShardedTree) lock(shards []*shard) {
+	for _, s := range shards {
+		s.mu.Lock()
+	}
+}
+
+func (st *ShardedTree) unlock(shards []*shard) {
+	for _, s := range shards {
+		s.mu.Unlock()
+	}
+}
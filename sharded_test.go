@@ -0,0 +1,77 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestShardedTree(t *testing.T) {
+	st := NewShardedTree(4)
+	if err := st.AddCIDR("0.0.0.0/2", 1); err != nil {
+		t.Error(err)
+	}
+	if err := st.AddCIDR("10.0.0.0/8", 2); err != nil {
+		t.Error(err)
+	}
+	if err := st.AddCIDR("dead::/16", 3); err != nil {
+		t.Error(err)
+	}
+	if err := st.AddCIDR("0.0.0.0/2", 4); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	for ip, val := range map[string]interface{}{"1.1.1.1": 1, "63.1.1.1": 1, "10.1.1.1": 2, "64.1.1.1": nil, "dead::1": 3} {
+		inf, err := st.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if st.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", st.Len())
+	}
+
+	if err := st.SetCIDR("0.0.0.0/2", 5); err != nil {
+		t.Error(err)
+	}
+	if err := st.DeleteCIDR("0.0.0.0/2"); err != nil {
+		t.Error(err)
+	}
+	if err := st.DeleteCIDR("0.0.0.0/2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if st.Contains("63.1.1.1") || !st.Contains("10.1.1.1") {
+		t.Error("Wrong membership after deletion")
+	}
+	if st.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", st.Len())
+	}
+}
+
+func TestShardedTreeConcurrent(t *testing.T) {
+	st := NewShardedTree(8)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 256; i++ {
+				if err := st.AddCIDR(fmt.Sprintf("%d.%d.0.0/16", i, w), w); err != nil {
+					t.Error(err)
+				}
+				st.FindCIDR(fmt.Sprintf("%d.%d.1.1", i, w))
+			}
+		}(w)
+	}
+	wg.Wait()
+	if st.Len() != 8*256 {
+		t.Errorf("Wrong length, expected %d, got %d", 8*256, st.Len())
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"unsafe"
+)
+
+// Stats describes memory used by the tree.
+type Stats struct {
+	// Nodes is number of nodes linked into the tree, including both roots and nodes without values
+	Nodes int
+	// Free is number of released nodes kept for reuse
+	Free int
+	// Entries is number of nodes holding a value, the same as Len
+	Entries int
+	// MaxDepth and AvgDepth are prefix lengths of entries, so IPv6 entries are usually deeper than IPv4 ones
+	MaxDepth int
+	AvgDepth float64
+	// Bytes is estimated memory taken by nodes, including free and not yet used preallocated ones. Memory taken by values is not counted.
+	Bytes int
+}
+
+// Stats walks the whole tree and returns its Stats.
+func (tree *Tree) Stats() Stats {
+	var s Stats
+	var depths int
+	for _, root := range []*node{tree.root, tree.root6} {
+		depths += s.add(root, 0)
+	}
+	for n := tree.free; n != nil; n = n.right {
+		s.Free++
+	}
+	if s.Entries > 0 {
+		s.AvgDepth = float64(depths) / float64(s.Entries)
+	}
+	s.Bytes = (s.Nodes + s.Free + cap(tree.alloc) - len(tree.alloc)) * int(unsafe.Sizeof(node{}))
+	return s
+}
+
+// add counts subtree of n located at depth d and returns sum of depths of its entries.
+func (s *Stats) add(n *node, d int) (depths int) {
+	s.Nodes++
+	if n.value != nil {
+		s.Entries++
+		depths += d
+		if d > s.MaxDepth {
+			s.MaxDepth = d
+		}
+	}
+	if n.left != nil {
+		depths += s.add(n.left, d+1)
+	}
+	if n.right != nil {
+		depths += s.add(n.right, d+1)
+	}
+	return depths
+}
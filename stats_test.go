@@ -0,0 +1,35 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestStats(t *testing.T) {
+	tr := NewTree(0)
+	s := tr.Stats()
+	if s.Nodes != 2 || s.Entries != 0 || s.AvgDepth != 0 {
+		t.Errorf("Wrong stats of empty tree, got %+v", s)
+	}
+
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/32", 3)
+	tr.AddCIDR("11.0.0.0/8", 4)
+	tr.DeleteCIDR("11.0.0.0/8")
+	s = tr.Stats()
+	// 10/8 path has 8 nodes and 10.1/16 adds 8 more, 11/8 differs from 10/8 only in the last bit and released its single node
+	if s.Nodes != 2+16+32 || s.Free != 1 || s.Entries != tr.Len() {
+		t.Errorf("Wrong stats, got %+v", s)
+	}
+	if s.MaxDepth != 32 || s.AvgDepth != float64(8+16+32)/3 {
+		t.Errorf("Wrong depths, got %d and %f", s.MaxDepth, s.AvgDepth)
+	}
+	if s.Bytes < (s.Nodes+s.Free)*int(unsafe.Sizeof(node{})) {
+		t.Errorf("Wrong bytes, got %d", s.Bytes)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+	"net"
+)
+
+type sslot struct {
+	child *snode
+	value interface{}
+}
+
+type snode struct {
+	slots []sslot
+}
+
+// StrideTable is read-only lookup structure built from Tree where every step consumes 4 or 8 bits of address instead of one.
+// Prefixes are expanded to all slots they cover, so it takes more memory than Tree but IPv4 lookup visits at most 4 (or 8 with stride 4) nodes.
+// It is safe for concurrent use.
+type StrideTable struct {
+	stride      int
+	root, root6 *snode
+}
+
+// NewStrideTable builds StrideTable with all prefixes stored in tree, stride should be 4 or 8.
+func NewStrideTable(tree *Tree, stride int) (*StrideTable, error) {
+	if stride != 4 && stride != 8 {
+		return nil, ErrBadStride
+	}
+	st := &StrideTable{stride: stride}
+	st.root, st.root6 = st.newnode(), st.newnode()
+	// walk visits shorter prefixes first, so longer ones overwrite expanded slots of those covering them, exceptions included
+	tree.walklive(func(key net.IP, bits int, n *node) error {
+		st.insert(key, bits, n.value)
+		return nil
+	})
+	return st, nil
+}
+
+// Find returns information saved in longest prefix covering IP.
+func (st *StrideTable) Find(ip string) (interface{}, error) {
+	return st.Findb([]byte(ip))
+}
+
+func (st *StrideTable) Findb(ip []byte) (interface{}, error) {
+	if bytes.IndexByte(ip, '/') >= 0 {
+		return nil, wrap("parse", ip, ErrBadIP)
+	}
+	key, _, err := parsecidr(ip)
+	if err != nil {
+		return nil, err
+	}
+	return st.FindIP(key), nil
+}
+
+// FindIP returns information saved in longest prefix covering IP.
+func (st *StrideTable) FindIP(ip net.IP) interface{} {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if len(ip) != net.IPv6len {
+		return nil
+	}
+	var value interface{}
+	n := st.root6
+	if len(ip) == net.IPv4len {
+		n = st.root
+	}
+	for d := 0; n != nil; d += st.stride {
+		slot := &n.slots[st.index(ip, d)]
+		if slot.value != nil {
+			value = slot.value
+		}
+		n = slot.child
+	}
+	if value == exclusion {
+		return nil
+	}
+	return value
+}
+
+func (st *StrideTable) insert(key net.IP, bits int, value interface{}) {
+	n := st.root6
+	if len(key) == net.IPv4len {
+		n = st.root
+	}
+	// prefix is expanded in node at depth d, which is the last one it reaches into
+	d := 0
+	if bits > 0 {
+		d = (bits - 1) / st.stride * st.stride
+	}
+	for depth := 0; depth < d; depth += st.stride {
+		slot := &n.slots[st.index(key, depth)]
+		if slot.child == nil {
+			slot.child = st.newnode()
+		}
+		n = slot.child
+	}
+	first := st.index(key, d)
+	for i := 0; i < 1<<uint(st.stride-(bits-d)); i++ {
+		n.slots[first+i].value = value
+	}
+}
+
+// index returns number made of stride bits of key starting at bit d.
+func (st *StrideTable) index(key net.IP, d int) int {
+	shift := uint(8 - st.stride - d&7)
+	return int(key[d>>3]>>shift) & (1<<uint(st.stride) - 1)
+}
+
+func (st *StrideTable) newnode() *snode {
+	return &snode{slots: make([]sslot, 1<<uint(st.stride))}
+}
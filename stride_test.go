@@ -0,0 +1,51 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestStrideTable(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("0.0.0.0/0", 0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/23", 3)
+	tr.AddCIDR("10.1.2.128/25", 4)
+	tr.AddCIDR("10.1.2.255/32", 5)
+	tr.AddCIDR("dead::/15", 6)
+	tr.AddCIDR("dead:beef::/32", 7)
+
+	for _, stride := range []int{4, 8} {
+		st, err := NewStrideTable(tr, stride)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ip := range []string{"1.1.1.1", "10.0.0.1", "10.1.0.1", "10.1.2.1", "10.1.3.1", "10.1.2.129", "10.1.2.255", "10.1.4.1", "10.2.0.1",
+			"dead::1", "deaf::1", "dead:beef::1", "dead:beee::1", "beef::1"} {
+			expected, _ := tr.FindCIDR(ip)
+			inf, err := st.Find(ip)
+			if err != nil {
+				t.Error(err)
+			}
+			if inf != expected {
+				t.Errorf("Wrong value for %s with stride %d, expected %v, got %v", ip, stride, expected, inf)
+			}
+			if inf = st.FindIP(net.ParseIP(ip)); inf != expected {
+				t.Errorf("Wrong value for %s with stride %d, expected %v, got %v", ip, stride, expected, inf)
+			}
+		}
+		if _, err := st.Find("10.0.0.0/8"); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+		}
+	}
+
+	if _, err := NewStrideTable(tr, 3); err != ErrBadStride {
+		t.Errorf("Should have gotten ErrBadStride, instead got err: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+)
+
+// Tags is value of prefixes filled by SetTag, each tag is set and looked up independently.
+type Tags map[string]interface{}
+
+// SetTag sets one named tag of IP/mask keeping other tags of the prefix. Returns ErrNodeBusy if prefix holds a value which is not Tags or an exception, expired tags are replaced.
+// Stored Tags are never modified in place, so maps returned by lookups stay intact.
+func (tree *Tree) SetTag(cidr, tag string, val interface{}) error {
+	return tree.SetTagb([]byte(cidr), tag, val)
+}
+
+func (tree *Tree) SetTagb(cidr []byte, tag string, val interface{}) error {
+	if err := tree.checkhost(cidr); err != nil {
+		return err
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	node := tree.locate(key, masklen(mask))
+	// expired tags are not kept, exceptions are busy as for AddCIDR
+	var tags Tags
+	if node.live() {
+		var ok bool
+		if tags, ok = node.value.(Tags); !ok {
+			return wrap("set", cidr, ErrNodeBusy)
+		}
+	}
+	next := make(Tags, len(tags)+1)
+	for k, v := range tags {
+		next[k] = v
+	}
+	next[tag] = val
+	tree.setvalue(node, next)
+	tree.evict()
+	return nil
+}
+
+// DeleteTag removes one named tag of IP/mask, prefix is removed together with its last tag. Returns ErrNotFound if there is no such tag.
+func (tree *Tree) DeleteTag(cidr, tag string) error {
+	return tree.DeleteTagb([]byte(cidr), tag)
+}
+
+func (tree *Tree) DeleteTagb(cidr []byte, tag string) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	node := tree.lookup(key, masklen(mask))
+	if node == nil || !node.held() {
+		return wrap("delete", cidr, ErrNotFound)
+	}
+	tags, _ := node.value.(Tags)
+	if _, ok := tags[tag]; !ok {
+		return wrap("delete", cidr, ErrNotFound)
+	}
+	if len(tags) == 1 {
+		tree.setvalue(node, nil)
+		tree.trim(node)
+		return nil
+	}
+	next := make(Tags, len(tags)-1)
+	for k, v := range tags {
+		if k != tag {
+			next[k] = v
+		}
+	}
+	tree.setvalue(node, next)
+	return nil
+}
+
+// FindTag returns value of named tag in longest prefix covering IP which has this tag set.
+func (tree *Tree) FindTag(cidr, tag string) (interface{}, error) {
+	return tree.FindTagb([]byte(cidr), tag)
+}
+
+func (tree *Tree) FindTagb(cidr []byte, tag string) (interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	var found *node
+	tree.tagged(key, masklen(mask), func(n *node, tags Tags) {
+		if _, ok := tags[tag]; ok || tags == nil {
+			found = n
+		}
+	})
+	if found == nil || found.value == exclusion {
+		return nil, nil
+	}
+	tree.hit(found)
+	return found.value.(Tags)[tag], nil
+}
+
+// FindTags returns all tags of prefixes covering IP, each tag taken from longest prefix having it set. Returns nil if there are none.
+func (tree *Tree) FindTags(cidr string) (Tags, error) {
+	return tree.FindTagsb([]byte(cidr))
+}
+
+func (tree *Tree) FindTagsb(cidr []byte) (Tags, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	var result Tags
+	tree.tagged(key, masklen(mask), func(n *node, tags Tags) {
+		if tags == nil {
+			result = nil
+			return
+		}
+		if result == nil {
+			result = make(Tags, len(tags))
+		}
+		for k, v := range tags {
+			result[k] = v
+		}
+		tree.hit(n)
+	})
+	return result, nil
+}
+
+// tagged calls fn for every live node holding Tags on the path of key from the root down, exception entries are reported with nil tags.
+func (tree *Tree) tagged(key net.IP, bits int, fn func(n *node, tags Tags)) {
+	node := tree.rootof(key)
+	for d := 0; node != nil; d++ {
+		if node.live() {
+			if tags, ok := node.value.(Tags); ok {
+				fn(node, tags)
+			} else if node.value == exclusion {
+				fn(node, nil)
+			}
+		}
+		if d == bits {
+			break
+		}
+		node = node.child(key, d)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTags(t *testing.T) {
+	tr := NewTree(0)
+	if err := tr.SetTag("10.0.0.0/8", "geo", "us"); err != nil {
+		t.Error(err)
+	}
+	tr.SetTag("10.0.0.0/8", "asn", 64500)
+	tr.SetTag("10.1.0.0/16", "asn", 64501)
+	tr.SetTag("10.1.2.0/24", "blocklist", true)
+	tr.AddCIDR("192.168.0.0/16", "plain")
+	if err := tr.SetTag("192.168.0.0/16", "geo", "eu"); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+
+	for _, c := range []struct {
+		ip, tag string
+		val     interface{}
+	}{{"10.1.2.3", "geo", "us"}, {"10.1.2.3", "asn", 64501}, {"10.1.2.3", "blocklist", true}, {"10.2.0.1", "asn", 64500},
+		{"10.2.0.1", "blocklist", nil}, {"11.0.0.1", "geo", nil}, {"192.168.0.1", "geo", nil}} {
+		inf, err := tr.FindTag(c.ip, c.tag)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != c.val {
+			t.Errorf("Wrong %s tag for %s, expected %v, got %v", c.tag, c.ip, c.val, inf)
+		}
+	}
+
+	tags, err := tr.FindTags("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(tags) != 3 || tags["geo"] != "us" || tags["asn"] != 64501 || tags["blocklist"] != true {
+		t.Errorf("Wrong tags, got %v", tags)
+	}
+	tags["geo"] = "changed"
+	if inf, _ := tr.FindTag("10.0.0.1", "geo"); inf != "us" {
+		t.Errorf("Returned tags should not alias stored ones, got %v", inf)
+	}
+	if tags, _ := tr.FindTags("11.0.0.1"); tags != nil {
+		t.Errorf("Wrong tags, expected nil, got %v", tags)
+	}
+
+	if err := tr.ExcludeCIDR("10.1.2.0/25"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindTag("10.1.2.3", "geo"); inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if tags, _ := tr.FindTags("10.1.2.3"); tags != nil {
+		t.Errorf("Wrong tags, expected nil, got %v", tags)
+	}
+	if inf, _ := tr.FindTag("10.1.2.200", "blocklist"); inf != true {
+		t.Errorf("Wrong value, expected true, got %v", inf)
+	}
+	tr.DeleteCIDR("10.1.2.0/25")
+
+	if err := tr.DeleteTag("10.1.2.0/24", "geo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if err := tr.DeleteTag("10.0.0.0/8", "geo"); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindTag("10.0.0.1", "asn"); inf != 64500 {
+		t.Errorf("Wrong value, expected 64500, got %v", inf)
+	}
+	if err := tr.DeleteTag("10.1.2.0/24", "blocklist"); err != nil {
+		t.Error(err)
+	}
+	if _, err := tr.ExactMatchCIDR("10.1.2.0/24"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Prefix should be removed with its last tag, instead got err: %v", err)
+	}
+	if tr.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", tr.Len())
+	}
+	if _, err := tr.FindTag("10.0.0.256", "geo"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+}
+
+func TestTagsDead(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "all")
+	tr.ExcludeCIDR("10.1.0.0/16")
+	tr.SetCIDRTTL("10.2.0.0/16", Tags{"geo": "us"}, -time.Second)
+	if err := tr.SetTag("10.1.0.0/16", "geo", "eu"); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if err := tr.DeleteTag("10.1.0.0/16", "geo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if err := tr.DeleteTag("10.2.0.0/16", "geo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if err := tr.SetTag("10.2.0.0/16", "asn", 64500); err != nil {
+		t.Error(err)
+	}
+	if tags, _ := tr.FindTags("10.2.0.1"); len(tags) != 1 || tags["asn"] != 64500 {
+		t.Errorf("Expired tags should not be kept, got %v", tags)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bufio"
+	"bytes"
+	"encoding"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// MarshalText encodes tree as lines of "cidr<TAB>value" sorted like Sorted does. Values implementing encoding.TextMarshaler are encoded with it,
+// others are formatted with fmt.Sprint. Line has no value part if value is true, so blocklists stay plain lists of CIDRs. Exception entries are lines of "!cidr".
+func (tree *Tree) MarshalText() ([]byte, error) {
+	var buf bytes.Buffer
+	err := tree.walklive(func(key net.IP, bits int, n *node) error {
+		if n.value == exclusion {
+			buf.WriteByte('!')
+		}
+		buf.WriteString(newprefix(key, bits).String())
+		if n.value == true || n.value == exclusion {
+			buf.WriteByte('\n')
+			return nil
+		}
+		var text string
+		switch v := n.value.(type) {
+		case encoding.TextMarshaler:
+			b, err := v.MarshalText()
+			if err != nil {
+				return err
+			}
+			text = string(b)
+		default:
+			text = fmt.Sprint(v)
+		}
+		if strings.ContainsAny(text, "\r\n") {
+			return ErrBadFormat
+		}
+		buf.WriteByte('\t')
+		buf.WriteString(text)
+		buf.WriteByte('\n')
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalText replaces content of the tree with lines of "cidr<TAB>value" format, values are stored as strings.
+// Lines with CIDR only get true value, lines of "!cidr" are exceptions, empty lines and lines starting with # are ignored. Tree is left untouched if text could not be decoded,
+// its settings and hooks are kept and entries are checked as by AddCIDR.
+func (tree *Tree) UnmarshalText(text []byte) error {
+	decoded := NewTree(0)
+	decoded.strict, decoded.redundant = tree.strict, tree.redundant
+	sc := bufio.NewScanner(bytes.NewReader(text))
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimRight(sc.Text(), "\r")
+		if s == "" || s[0] == '#' {
+			continue
+		}
+		if s[0] == '!' {
+			if err := decoded.ExcludeCIDR(strings.TrimSpace(s[1:])); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			continue
+		}
+		var val interface{} = true
+		cidr, v, ok := strings.Cut(s, "\t")
+		if ok {
+			val = v
+		}
+		if err := decoded.AddCIDR(strings.TrimSpace(cidr), val); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	tree.adopt(decoded)
+	return nil
+}
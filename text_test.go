@@ -0,0 +1,72 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestText(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("2001:db8::/32", "docs")
+	tr.AddCIDR("10.0.0.0/8", true)
+	tr.AddCIDR("10.1.0.0/16", 42)
+	tr.AddCIDR("192.168.0.0/16", net.ParseIP("10.0.0.1"))
+
+	text, err := tr.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "10.0.0.0/8\n10.1.0.0/16\t42\n192.168.0.0/16\t10.0.0.1\n2001:db8::/32\tdocs\n"
+	if string(text) != expected {
+		t.Errorf("Wrong text, expected\n%s\ngot\n%s", expected, text)
+	}
+
+	restored := NewTree(0)
+	if err = restored.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	for ip, val := range map[string]interface{}{"10.2.0.1": true, "10.1.0.1": "42", "192.168.1.1": "10.0.0.1", "2001:db8::1": "docs"} {
+		inf, err := restored.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	err = restored.UnmarshalText([]byte("# blocklist\n\n1.1.1.0/24\n1.1.1.1/24\n"))
+	if !errors.Is(err, ErrNodeBusy) || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("Should have gotten ErrNodeBusy on line 4, instead got err: %v", err)
+	}
+	if restored.Len() != 4 {
+		t.Error("Failed decoding should not modify the tree")
+	}
+
+	tr.AddCIDR("11.0.0.0/8", "multi\nline")
+	if _, err = tr.MarshalText(); err != ErrBadFormat {
+		t.Errorf("Should have gotten ErrBadFormat, instead got err: %v", err)
+	}
+}
+
+func TestTextKeepsSettings(t *testing.T) {
+	tr := NewTree(0)
+	tr.CountHits(true)
+	tr.RejectHostBits(true)
+	if err := tr.UnmarshalText([]byte("10.1.2.3/24\tb\n")); !errors.Is(err, ErrHostBits) {
+		t.Errorf("Should have gotten ErrHostBits, instead got err: %v", err)
+	}
+	if err := tr.UnmarshalText([]byte("10.0.0.0/8\ta\n")); err != nil {
+		t.Fatal(err)
+	}
+	tr.FindCIDR("10.0.0.1")
+	if hits, _ := tr.HitsCIDR("10.0.0.0/8"); hits != 1 {
+		t.Errorf("Hit counting should be kept, got %d hits", hits)
+	}
+}
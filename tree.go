@@ -6,21 +6,73 @@ package nradix
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
+	"fmt"
+	"math/bits"
 	"net"
+	"sync/atomic"
+	"time"
+	"unsafe"
 )
 
 type node struct {
 	left, right, parent *node
 	value               interface{}
+	// size is number of values stored in this node and all of its descendants
+	size int32
+	// priority of value, used instead of prefix length by lookups if tree matches by priority
+	priority int32
+	// hits is number of lookups matched by value of this node, maintained only if tree counts hits
+	hits atomic.Uint64
+	// expires is time (in unix nanoseconds) after which value is treated as absent, zero if it never expires
+	expires int64
+	// used is tick of tree clock when value was stored or matched last time, maintained only if tree has entries limit
+	used atomic.Uint64
+	// stored and matched are times (in unix nanoseconds) value was stored and matched last time, maintained only if tree tracks details
+	stored  int64
+	matched atomic.Int64
+}
+
+// live reports whether n holds value which has not expired yet.
+func (n *node) live() bool {
+	return n.value != nil && (n.expires == 0 || n.expires > time.Now().UnixNano())
+}
+
+// held reports whether n holds value visible to users, that is live one which is not an exception.
+func (n *node) held() bool {
+	return n.live() && n.value != exclusion
 }
 
 // Tree implements radix tree for working with IP/mask. Thread safety is not guaranteed, you should choose your own style of protecting safety of operations.
 type Tree struct {
-	root *node
-	free *node
+	// root holds IPv4 prefixes and root6 holds IPv6 ones
+	root  *node
+	root6 *node
+	free  *node
 
 	alloc []node
+
+	counthits bool
+	details   bool
+	// byprio makes lookups prefer value with higher priority over more specific one
+	byprio bool
+	// excludes is set once exception entry was stored in the tree
+	excludes bool
+	// strict rejects inserts of prefixes with host bits set
+	strict bool
+	// redundant compares values of inserted prefix and its covering entry, inserts of equal ones are rejected
+	redundant func(a, b interface{}) bool
+	interner  *Interner
+	hooks     *hooks
+
+	// maxentries limits number of entries, least recently used ones are evicted; clock is ticked by every store and match
+	maxentries int
+	clock      uint64
+	// lru queues entries for eviction, it is built by the first eviction and dropped when nodes are replaced
+	lru *lruheap
+
+	versions *versions
 }
 
 const (
@@ -28,41 +80,30 @@ const (
 	startbyte = byte(0x80)
 )
 
+// Errors for bad input and failed AddCIDR and DeleteCIDR wrap these with the input, so they should be checked with errors.Is.
 var (
 	ErrNodeBusy = errors.New("Node Busy")
 	ErrNotFound = errors.New("No Such Node")
 	ErrBadIP    = errors.New("Bad IP address or mask")
+
+	ErrBadFormat = errors.New("Bad serialized tree")
+	ErrTxDone    = errors.New("Transaction already committed or rolled back")
+	ErrBadStride = errors.New("Stride should be 4 or 8")
+	ErrCorrupt   = errors.New("Tree structure is corrupted")
+	ErrHostBits  = errors.New("Host bits are set in prefix")
+	ErrRedundant = errors.New("Prefix is covered by equal value")
 )
 
 // NewTree creates Tree and preallocates (if preallocate not zero) number of nodes that would be ready to fill with data.
+// Every stored prefix takes up to its length in nodes, but prefixes share nodes of their common part.
 func NewTree(preallocate int) *Tree {
 	tree := new(Tree)
-	tree.root = tree.newnode()
-	if preallocate == 0 {
-		return tree
+	if preallocate > 0 {
+		// both roots come from the same slab
+		tree.alloc = make([]node, 0, preallocate+2)
 	}
-
-	// Simplification, static preallocate max 6 bits
-	if preallocate > 6 || preallocate < 0 {
-		preallocate = 6
-	}
-
-	var key, mask uint32
-
-	for inc := startbit; preallocate > 0; inc, preallocate = inc>>1, preallocate-1 {
-		key = 0
-		mask >>= 1
-		mask |= startbit
-
-		for {
-			tree.insert32(key, mask, nil, false)
-			key += inc
-			if key == 0 { // magic bits collide
-				break
-			}
-		}
-	}
-
+	tree.root = tree.newnode()
+	tree.root6 = tree.newnode()
 	return tree
 }
 
@@ -72,18 +113,25 @@ func (tree *Tree) AddCIDR(cidr string, val interface{}) error {
 }
 
 func (tree *Tree) AddCIDRb(cidr []byte, val interface{}) error {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if err := tree.checkhost(cidr); err != nil {
+		return err
+	}
+	if err := tree.checkredundant("add", cidr, val, false); err != nil {
+		return err
+	}
+	if isv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return err
 		}
-		return tree.insert32(ip, mask, val, false)
+		return wrap("add", cidr, tree.insert32(ip, mask, val, false))
 	}
-	ip, mask, err := parsecidr6(cidr)
+	hi, lo, bits, err := parsekey6(cidr)
 	if err != nil {
 		return err
 	}
-	return tree.insert(ip, mask, val, false)
+	root, hi, lo, bits := tree.key6(hi, lo, bits)
+	return wrap("add", cidr, tree.insertkey(root, hi, lo, bits, val, false))
 }
 
 // AddCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR or if value already exists.
@@ -92,18 +140,241 @@ func (tree *Tree) SetCIDR(cidr string, val interface{}) error {
 }
 
 func (tree *Tree) SetCIDRb(cidr []byte, val interface{}) error {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if err := tree.checkhost(cidr); err != nil {
+		return err
+	}
+	if err := tree.checkredundant("set", cidr, val, true); err != nil {
+		return err
+	}
+	if isv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return err
 		}
 		return tree.insert32(ip, mask, val, true)
 	}
-	ip, mask, err := parsecidr6(cidr)
+	hi, lo, bits, err := parsekey6(cidr)
 	if err != nil {
 		return err
 	}
-	return tree.insert(ip, mask, val, true)
+	root, hi, lo, bits := tree.key6(hi, lo, bits)
+	return tree.insertkey(root, hi, lo, bits, val, true)
+}
+
+// BatchEntry is CIDR with value to be added by AddBatch.
+type BatchEntry struct {
+	CIDR  string
+	Value interface{}
+}
+
+// AddBatch adds all entries to the tree or none of them. Every CIDR is parsed and checked against existing values first, so tree stays untouched if any of them is invalid or busy.
+func (tree *Tree) AddBatch(entries []BatchEntry) error {
+	type parsed struct {
+		key  net.IP
+		bits int
+	}
+	batch := make([]parsed, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for i, e := range entries {
+		if err := tree.checkhost([]byte(e.CIDR)); err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		key, mask, err := parsecidr([]byte(e.CIDR))
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+		bits := masklen(mask)
+		key = key.Mask(mask)
+		id := string(append(key, byte(bits)))
+		if seen[id] {
+			return fmt.Errorf("entry %d: %w", i, wrap("add", []byte(e.CIDR), ErrNodeBusy))
+		}
+		seen[id] = true
+		if node := tree.lookup(key, bits); node != nil && node.live() {
+			return fmt.Errorf("entry %d: %w", i, wrap("add", []byte(e.CIDR), ErrNodeBusy))
+		}
+		if tree.redundant != nil && tree.isredundant(key, bits, e.Value, false) {
+			return fmt.Errorf("entry %d: %w", i, wrap("add", []byte(e.CIDR), ErrRedundant))
+		}
+		batch[i] = parsed{key, bits}
+	}
+	for i, p := range batch {
+		node := tree.locate(p.key, p.bits)
+		tree.setvalue(node, entries[i].Value)
+		tree.trim(node)
+	}
+	tree.evict()
+	return nil
+}
+
+// SwapCIDR sets value associated with IP/mask and returns previous value and whether it existed.
+func (tree *Tree) SwapCIDR(cidr string, val interface{}) (interface{}, bool, error) {
+	return tree.SwapCIDRb([]byte(cidr), val)
+}
+
+func (tree *Tree) SwapCIDRb(cidr []byte, val interface{}) (interface{}, bool, error) {
+	if err := tree.checkhost(cidr); err != nil {
+		return nil, false, err
+	}
+	if err := tree.checkredundant("swap", cidr, val, true); err != nil {
+		return nil, false, err
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, false, err
+	}
+	node := tree.locate(key, masklen(mask))
+	var old interface{}
+	if node.live() && node.value != exclusion {
+		old = node.value
+	}
+	tree.setvalue(node, val)
+	tree.trim(node)
+	tree.evict()
+	return old, old != nil, nil
+}
+
+// UpdateCIDR calls fn with value currently stored at IP/mask (if any) and stores value it returns. Entry is removed if fn does not want to keep it.
+func (tree *Tree) UpdateCIDR(cidr string, fn func(old interface{}, exists bool) (val interface{}, keep bool)) error {
+	return tree.UpdateCIDRb([]byte(cidr), fn)
+}
+
+func (tree *Tree) UpdateCIDRb(cidr []byte, fn func(old interface{}, exists bool) (val interface{}, keep bool)) error {
+	if err := tree.checkhost(cidr); err != nil {
+		return err
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	bits := masklen(mask)
+	node := tree.lookup(key, bits)
+	var old interface{}
+	exists := node != nil && node.held()
+	if exists {
+		old = node.value
+	}
+	val, keep := fn(old, exists)
+	if !keep || val == nil {
+		// expired entries and exceptions are left for Expire and DeleteCIDR
+		if exists {
+			tree.setvalue(node, nil)
+			tree.trim(node)
+		}
+		return nil
+	}
+	if tree.redundant != nil && tree.isredundant(key, bits, val, true) {
+		return wrap("update", cidr, ErrRedundant)
+	}
+	node = tree.locate(key, bits)
+	tree.setvalue(node, val)
+	tree.evict()
+	return nil
+}
+
+// UpsertCIDR adds value associated with IP/mask to the tree, or stores result of merge if there is a value already.
+func (tree *Tree) UpsertCIDR(cidr string, val interface{}, merge func(old, new interface{}) interface{}) error {
+	return tree.UpsertCIDRb([]byte(cidr), val, merge)
+}
+
+func (tree *Tree) UpsertCIDRb(cidr []byte, val interface{}, merge func(old, new interface{}) interface{}) error {
+	return tree.UpdateCIDRb(cidr, func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return val, true
+		}
+		return merge(old, val), true
+	})
+}
+
+// AppendCIDR adds value to the list of values associated with IP/mask. Values are kept as []interface{}, so FindCIDR returns all of them at once; value previously stored by AddCIDR becomes first element of the list.
+func (tree *Tree) AppendCIDR(cidr string, val interface{}) error {
+	return tree.AppendCIDRb([]byte(cidr), val)
+}
+
+func (tree *Tree) AppendCIDRb(cidr []byte, val interface{}) error {
+	return tree.UpdateCIDRb(cidr, func(old interface{}, exists bool) (interface{}, bool) {
+		switch old := old.(type) {
+		case nil:
+			return []interface{}{val}, true
+		case []interface{}:
+			return append(old, val), true
+		default:
+			return []interface{}{old, val}, true
+		}
+	})
+}
+
+// Len returns number of entries stored in the tree.
+func (tree *Tree) Len() int {
+	return int(tree.root.size + tree.root6.size)
+}
+
+// Reset removes all entries from the tree. Nodes are kept in the free list, so refilling the tree does not allocate them again.
+func (tree *Tree) Reset() {
+	for _, root := range []*node{tree.root, tree.root6} {
+		tree.prune(root)
+		root.hits.Store(0)
+	}
+}
+
+// Compact moves all nodes of the tree into a single slab of exact size and drops the free list, so memory kept for removed entries could be garbage collected.
+// Returns number of bytes released.
+func (tree *Tree) Compact() int {
+	var free int
+	for n := tree.free; n != nil; n = n.right {
+		free++
+	}
+	// previous slabs were replaced only when they were full, so only the last one could have unused nodes
+	unused := free + cap(tree.alloc) - len(tree.alloc)
+	if unused == 0 {
+		return 0
+	}
+	c := tree.settings()
+	c.alloc = make([]node, 0, tree.nodes(tree.root)+tree.nodes(tree.root6))
+	c.hooks, c.versions = tree.hooks, tree.versions
+	c.root = c.clone(tree.root, nil)
+	c.root6 = c.clone(tree.root6, nil)
+	*tree = *c
+	return unused * int(unsafe.Sizeof(node{}))
+}
+
+// Clone returns independent copy of the tree, values themselves are not copied. Nodes of Tree are modified in place so all of them are copied, use COWTree.Clone for O(1) copies.
+func (tree *Tree) Clone() *Tree {
+	c := tree.settings()
+	c.root = c.clone(tree.root, nil)
+	c.root6 = c.clone(tree.root6, nil)
+	return c
+}
+
+// adopt replaces nodes of the tree with nodes of c, keeping settings, hooks and checkpoints of the tree. Hooks are not called for replaced entries.
+func (tree *Tree) adopt(c *Tree) {
+	tree.root, tree.root6, tree.free, tree.alloc = c.root, c.root6, c.free, c.alloc
+	tree.excludes = tree.excludes || c.excludes
+	tree.lru = nil
+	tree.evict()
+}
+
+// settings returns tree without nodes having the same settings as this one, hooks are not copied.
+func (tree *Tree) settings() *Tree {
+	return &Tree{counthits: tree.counthits, details: tree.details, byprio: tree.byprio, excludes: tree.excludes,
+		strict: tree.strict, redundant: tree.redundant, interner: tree.interner, maxentries: tree.maxentries, clock: tree.clock}
+}
+
+// SubtreeSize returns number of entries stored in the entire subnet specified by the CIDR (including the exact one) without walking it.
+func (tree *Tree) SubtreeSize(cidr string) (int, error) {
+	return tree.SubtreeSizeb([]byte(cidr))
+}
+
+func (tree *Tree) SubtreeSizeb(cidr []byte) (int, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return 0, err
+	}
+	node := tree.lookup(key, masklen(mask))
+	if node == nil {
+		return 0, nil
+	}
+	return int(node.size), nil
 }
 
 // DeleteWholeRangeCIDR removes all values associated with IPs
@@ -113,18 +384,69 @@ func (tree *Tree) DeleteWholeRangeCIDR(cidr string) error {
 }
 
 func (tree *Tree) DeleteWholeRangeCIDRb(cidr []byte) error {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if isv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return err
 		}
-		return tree.delete32(ip, mask, true)
+		return wrap("delete", cidr, tree.delete32(ip, mask, true))
 	}
-	ip, mask, err := parsecidr6(cidr)
+	hi, lo, bits, err := parsekey6(cidr)
 	if err != nil {
 		return err
 	}
-	return tree.delete(ip, mask, true)
+	root, hi, lo, bits := tree.key6(hi, lo, bits)
+	return wrap("delete", cidr, tree.deletekey(root, hi, lo, bits, true))
+}
+
+// SetWholeRangeCIDR overwrites value of every entry in the entire subnet specified by the CIDR (including the exact one) and returns number of entries affected.
+func (tree *Tree) SetWholeRangeCIDR(cidr string, val interface{}) (int, error) {
+	return tree.SetWholeRangeCIDRb([]byte(cidr), val)
+}
+
+func (tree *Tree) SetWholeRangeCIDRb(cidr []byte, val interface{}) (int, error) {
+	if err := tree.checkhost(cidr); err != nil {
+		return 0, err
+	}
+	if err := tree.checkredundant("set", cidr, val, true); err != nil {
+		return 0, err
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return 0, err
+	}
+	bits := masklen(mask)
+	start := tree.lookup(key, bits)
+	if start == nil {
+		return 0, nil
+	}
+	var matched []*node
+	walk(start, key.Mask(mask), bits, func(key net.IP, bits int, n *node) error {
+		matched = append(matched, n)
+		return nil
+	})
+	// nodes are changed after the walk, nil val releases them
+	for _, n := range matched {
+		tree.setvalue(n, val)
+		tree.trim(n)
+	}
+	return len(matched), nil
+}
+
+// DeleteIf removes every entry for which fn returns true and returns number of entries removed.
+func (tree *Tree) DeleteIf(fn func(prefix string, val interface{}) bool) int {
+	var matched []*node
+	tree.walk(func(key net.IP, bits int, n *node) error {
+		if fn(newentry(key, bits, n.value).Prefix.String(), n.value) {
+			matched = append(matched, n)
+		}
+		return nil
+	})
+	for _, n := range matched {
+		tree.setvalue(n, nil)
+		tree.trim(n)
+	}
+	return len(matched)
 }
 
 // DeleteCIDR removes value associated with IP/mask from the tree.
@@ -133,18 +455,39 @@ func (tree *Tree) DeleteCIDR(cidr string) error {
 }
 
 func (tree *Tree) DeleteCIDRb(cidr []byte) error {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if isv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return err
 		}
-		return tree.delete32(ip, mask, false)
+		return wrap("delete", cidr, tree.delete32(ip, mask, false))
 	}
-	ip, mask, err := parsecidr6(cidr)
+	hi, lo, bits, err := parsekey6(cidr)
 	if err != nil {
 		return err
 	}
-	return tree.delete(ip, mask, false)
+	root, hi, lo, bits := tree.key6(hi, lo, bits)
+	return wrap("delete", cidr, tree.deletekey(root, hi, lo, bits, false))
+}
+
+// DeleteCIDRValue removes value associated with IP/mask from the tree and returns it. Returns ErrNotFound if there was no value.
+func (tree *Tree) DeleteCIDRValue(cidr string) (interface{}, error) {
+	return tree.DeleteCIDRValueb([]byte(cidr))
+}
+
+func (tree *Tree) DeleteCIDRValueb(cidr []byte) (interface{}, error) {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return nil, err
+	}
+	node := tree.lookup(key, masklen(mask))
+	if node == nil || !node.held() {
+		return nil, wrap("delete", cidr, ErrNotFound)
+	}
+	val := node.value
+	tree.setvalue(node, nil)
+	tree.trim(node)
+	return val, nil
 }
 
 // Find CIDR traverses tree to proper Node and returns previously saved information in longest covered IP.
@@ -153,18 +496,19 @@ func (tree *Tree) FindCIDR(cidr string) (interface{}, error) {
 }
 
 func (tree *Tree) FindCIDRb(cidr []byte) (interface{}, error) {
-	if bytes.IndexByte(cidr, '.') > 0 {
+	if isv4(cidr) {
 		ip, mask, err := parsecidr4(cidr)
 		if err != nil {
 			return nil, err
 		}
 		return tree.find32(ip, mask), nil
 	}
-	ip, mask, err := parsecidr6(cidr)
-	if err != nil || ip == nil {
+	hi, lo, bits, err := parsekey6(cidr)
+	if err != nil {
 		return nil, err
 	}
-	return tree.find(ip, mask), nil
+	root, hi, lo, bits := tree.key6(hi, lo, bits)
+	return tree.findkey(root, hi, lo, bits), nil
 }
 
 func (tree *Tree) insert32(key, mask uint32, value interface{}, overwrite bool) error {
@@ -184,10 +528,12 @@ func (tree *Tree) insert32(key, mask uint32, value interface{}, overwrite bool)
 		node = next
 	}
 	if next != nil {
-		if node.value != nil && !overwrite {
+		if node.live() && !overwrite {
 			return ErrNodeBusy
 		}
-		node.value = value
+		tree.setvalue(node, value)
+		tree.trim(node)
+		tree.evict()
 		return nil
 	}
 	for bit&mask != 0 {
@@ -201,7 +547,9 @@ func (tree *Tree) insert32(key, mask uint32, value interface{}, overwrite bool)
 		bit >>= 1
 		node = next
 	}
-	node.value = value
+	tree.setvalue(node, value)
+	tree.trim(node)
+	tree.evict()
 
 	return nil
 }
@@ -210,58 +558,199 @@ func (tree *Tree) insert(key net.IP, mask net.IPMask, value interface{}, overwri
 	if len(key) != len(mask) {
 		return ErrBadIP
 	}
+	hi, lo := loadkey(key)
+	return tree.insertkey(tree.rootof(key), hi, lo, prefixlen(loadkey(net.IP(mask))), value, overwrite)
+}
 
-	var i int
-	bit := startbyte
-	node := tree.root
-	next := tree.root
-	for bit&mask[i] != 0 {
-		if key[i]&bit != 0 {
+// insertkey stores value at depth bits under root on the path of key loaded by loadkey.
+func (tree *Tree) insertkey(root *node, hi, lo uint64, bits int, value interface{}, overwrite bool) error {
+	node := root
+	d := 0
+	for ; d < bits; d++ {
+		next := node.left
+		if keybit(hi, lo, d) {
 			next = node.right
-		} else {
-			next = node.left
 		}
 		if next == nil {
 			break
 		}
-
 		node = next
-
-		if bit >>= 1; bit == 0 {
-			if i++; i == len(key) {
-				break
-			}
-			bit = startbyte
-		}
-
 	}
-	if next != nil {
-		if node.value != nil && !overwrite {
+	if d == bits {
+		if node.live() && !overwrite {
 			return ErrNodeBusy
 		}
-		node.value = value
+		tree.setvalue(node, value)
+		tree.trim(node)
+		tree.evict()
 		return nil
 	}
-
-	for bit&mask[i] != 0 {
-		next = tree.newnode()
+	for ; d < bits; d++ {
+		next := tree.newnode()
 		next.parent = node
-		if key[i]&bit != 0 {
+		if keybit(hi, lo, d) {
 			node.right = next
 		} else {
 			node.left = next
 		}
 		node = next
-		if bit >>= 1; bit == 0 {
-			if i++; i == len(key) {
-				break
+	}
+	tree.setvalue(node, value)
+	tree.trim(node)
+	tree.evict()
+
+	return nil
+}
+
+// clone copies n and all of its descendants into the tree.
+func (tree *Tree) clone(n, parent *node) *node {
+	c := tree.newnode()
+	c.parent = parent
+	c.value, c.size, c.expires, c.priority = n.value, n.size, n.expires, n.priority
+	c.hits.Store(n.hits.Load())
+	c.used.Store(n.used.Load())
+	c.stored = n.stored
+	c.matched.Store(n.matched.Load())
+	if n.left != nil {
+		c.left = tree.clone(n.left, c)
+	}
+	if n.right != nil {
+		c.right = tree.clone(n.right, c)
+	}
+	return c
+}
+
+// key6 returns root holding IPv6 key loaded as by loadkey together with the key and its length in that root.
+// IPv4-mapped keys (::ffff:0:0/96 and longer) are moved to IPv4 root, so ::ffff:10.1.2.3 hits 10.1.2.0/24 and ::ffff:10.1.2.0/120 is stored as 10.1.2.0/24.
+func (tree *Tree) key6(hi, lo uint64, bits int) (*node, uint64, uint64, int) {
+	if hi == 0 && lo>>32 == 0xffff && bits >= 96 {
+		return tree.root, lo << 32, 0, bits - 96
+	}
+	return tree.root6, hi, lo, bits
+}
+
+// rootof returns root holding prefixes of the same family as key.
+func (tree *Tree) rootof(key net.IP) *node {
+	if len(key) == net.IPv4len {
+		return tree.root
+	}
+	return tree.root6
+}
+
+// nodes returns number of nodes in subtree of n.
+func (tree *Tree) nodes(n *node) int {
+	count := 1
+	if n.left != nil {
+		count += tree.nodes(n.left)
+	}
+	if n.right != nil {
+		count += tree.nodes(n.right)
+	}
+	return count
+}
+
+// locate returns node located exactly at depth bits on the path of key, creating missing nodes on the way.
+func (tree *Tree) locate(key net.IP, bits int) *node {
+	node := tree.rootof(key)
+	for d := 0; d < bits; d++ {
+		next := node.child(key, d)
+		if next == nil {
+			next = tree.newnode()
+			next.parent = node
+			if key[d>>3]&(startbyte>>uint(d&7)) != 0 {
+				node.right = next
+			} else {
+				node.left = next
 			}
-			bit = startbyte
 		}
+		node = next
 	}
-	node.value = value
+	return node
+}
 
-	return nil
+// trim releases n and its parents to the free list for as long as they have no value and no children. Root is never released.
+func (tree *Tree) trim(n *node) {
+	for n.parent != nil && n.value == nil && n.left == nil && n.right == nil {
+		if n.parent.right == n {
+			n.parent.right = nil
+		} else {
+			n.parent.left = nil
+		}
+		// reserve this node for future use
+		n.right = tree.free
+		tree.free = n
+		n = n.parent
+	}
+}
+
+// prune removes all values in subtree of n and releases its nodes to the free list.
+func (tree *Tree) prune(n *node) {
+	if n.left != nil {
+		tree.release(n.left)
+		n.left = nil
+	}
+	if n.right != nil {
+		tree.release(n.right)
+		n.right = nil
+	}
+	tree.removed(n)
+	n.value = nil
+	tree.resize(n, -n.size)
+	tree.trim(n)
+}
+
+// release puts n and all of its descendants to the free list, sizes of its parents are not updated.
+func (tree *Tree) release(n *node) {
+	if n.left != nil {
+		tree.release(n.left)
+	}
+	if n.right != nil {
+		tree.release(n.right)
+	}
+	tree.removed(n)
+	n.value = nil
+	n.right = tree.free
+	tree.free = n
+}
+
+// setvalue stores val in n keeping sizes of subtrees up to date, nil val removes entry. Change is reported to hooks.
+func (tree *Tree) setvalue(n *node, val interface{}) {
+	if tree.hooks != nil {
+		tree.changed(n, val)
+	}
+	tree.store(n, val)
+}
+
+// store is setvalue which does not report change to hooks.
+func (tree *Tree) store(n *node, val interface{}) {
+	if tree.interner != nil {
+		val = tree.interner.Intern(val)
+	}
+	switch {
+	case n.value == nil && val != nil:
+		tree.resize(n, 1)
+	case n.value != nil && val == nil:
+		tree.resize(n, -1)
+		n.hits.Store(0)
+		n.matched.Store(0)
+	}
+	n.value = val
+	n.expires = 0
+	n.priority = 0
+	if val != nil {
+		tree.touch(n)
+		tree.queue(n)
+		if tree.details {
+			n.stored = time.Now().UnixNano()
+		}
+	}
+}
+
+// resize adds delta to size of n and all of its parents.
+func (tree *Tree) resize(n *node, delta int32) {
+	for ; n != nil; n = n.parent {
+		n.size += delta
+	}
 }
 
 func (tree *Tree) delete32(key, mask uint32, wholeRange bool) error {
@@ -282,32 +771,14 @@ func (tree *Tree) delete32(key, mask uint32, wholeRange bool) error {
 	if !wholeRange && (node.right != nil || node.left != nil) {
 		// keep it just trim value
 		if node.value != nil {
-			node.value = nil
+			tree.setvalue(node, nil)
 			return nil
 		}
 		return ErrNotFound
 	}
 
-	// need to trim leaf
-	for {
-		if node.parent.right == node {
-			node.parent.right = nil
-		} else {
-			node.parent.left = nil
-		}
-		// reserve this node for future use
-		node.right = tree.free
-		tree.free = node
-		// move to parent, check if it's free of value and children
-		node = node.parent
-		if node.right != nil || node.left != nil || node.value != nil {
-			break
-		}
-		// do not delete root node
-		if node.parent == nil {
-			break
-		}
-	}
+	// need to trim leaf (or whole range under it)
+	tree.prune(node)
 
 	return nil
 }
@@ -316,22 +787,18 @@ func (tree *Tree) delete(key net.IP, mask net.IPMask, wholeRange bool) error {
 	if len(key) != len(mask) {
 		return ErrBadIP
 	}
+	hi, lo := loadkey(key)
+	return tree.deletekey(tree.rootof(key), hi, lo, prefixlen(loadkey(net.IP(mask))), wholeRange)
+}
 
-	var i int
-	bit := startbyte
-	node := tree.root
-	for node != nil && bit&mask[i] != 0 {
-		if key[i]&bit != 0 {
+func (tree *Tree) deletekey(root *node, hi, lo uint64, bits int, wholeRange bool) error {
+	node := root
+	for d := 0; node != nil && d < bits; d++ {
+		if keybit(hi, lo, d) {
 			node = node.right
 		} else {
 			node = node.left
 		}
-		if bit >>= 1; bit == 0 {
-			if i++; i == len(key) {
-				break
-			}
-			bit = startbyte
-		}
 	}
 	if node == nil {
 		return ErrNotFound
@@ -340,43 +807,25 @@ func (tree *Tree) delete(key net.IP, mask net.IPMask, wholeRange bool) error {
 	if !wholeRange && (node.right != nil || node.left != nil) {
 		// keep it just trim value
 		if node.value != nil {
-			node.value = nil
+			tree.setvalue(node, nil)
 			return nil
 		}
 		return ErrNotFound
 	}
 
-	// need to trim leaf
-	for {
-		if node.parent.right == node {
-			node.parent.right = nil
-		} else {
-			node.parent.left = nil
-		}
-		// reserve this node for future use
-		node.right = tree.free
-		tree.free = node
-
-		// move to parent, check if it's free of value and children
-		node = node.parent
-		if node.right != nil || node.left != nil || node.value != nil {
-			break
-		}
-		// do not delete root node
-		if node.parent == nil {
-			break
-		}
-	}
+	// need to trim leaf (or whole range under it)
+	tree.prune(node)
 
 	return nil
 }
 
-func (tree *Tree) find32(key, mask uint32) (value interface{}) {
+func (tree *Tree) find32(key, mask uint32) interface{} {
+	var found *node
 	bit := startbit
 	node := tree.root
 	for node != nil {
-		if node.value != nil {
-			value = node.value
+		if node.live() && tree.better(node, found) {
+			found = node
 		}
 		if key&bit != 0 {
 			node = node.right
@@ -389,40 +838,59 @@ func (tree *Tree) find32(key, mask uint32) (value interface{}) {
 		bit >>= 1
 
 	}
-	return value
+	if found == nil || found.value == exclusion {
+		return nil
+	}
+	tree.hit(found)
+	return found.value
 }
 
-func (tree *Tree) find(key net.IP, mask net.IPMask) (value interface{}) {
-	if len(key) != len(mask) {
-		return ErrBadIP
-	}
-	var i int
-	bit := startbyte
-	node := tree.root
-	for node != nil {
-		if node.value != nil {
-			value = node.value
+func (tree *Tree) findkey(root *node, hi, lo uint64, bits int) interface{} {
+	var found *node
+	node := root
+	for d := 0; node != nil; d++ {
+		if node.live() && tree.better(node, found) {
+			found = node
 		}
-		if key[i]&bit != 0 {
+		if d == bits {
+			break
+		}
+		if keybit(hi, lo, d) {
 			node = node.right
 		} else {
 			node = node.left
 		}
-		if mask[i]&bit == 0 {
-			break
-		}
-		if bit >>= 1; bit == 0 {
-			i, bit = i+1, startbyte
-			if i >= len(key) {
-				// reached depth of the tree, there should be matching node...
-				if node != nil {
-					value = node.value
-				}
-				break
-			}
-		}
 	}
-	return value
+	if found == nil || found.value == exclusion {
+		return nil
+	}
+	tree.hit(found)
+	return found.value
+}
+
+// loadkey returns IP (or mask) as two 64-bit words, IPv4 takes upper half of the first one.
+func loadkey(key net.IP) (hi, lo uint64) {
+	if len(key) == net.IPv4len {
+		return uint64(binary.BigEndian.Uint32(key)) << 32, 0
+	}
+	return binary.BigEndian.Uint64(key), binary.BigEndian.Uint64(key[8:])
+}
+
+// keybit reports whether bit d of key loaded by loadkey is set.
+func keybit(hi, lo uint64, d int) bool {
+	if d >= 64 {
+		hi = lo
+	}
+	return hi<<uint(d&63)&(1<<63) != 0
+}
+
+// prefixlen returns number of leading bits set in mask loaded by loadkey.
+func prefixlen(hi, lo uint64) int {
+	n := bits.LeadingZeros64(^hi)
+	if n == 64 {
+		n += bits.LeadingZeros64(^lo)
+	}
+	return n
 }
 
 func (tree *Tree) newnode() (p *node) {
@@ -435,6 +903,13 @@ func (tree *Tree) newnode() (p *node) {
 		p.parent = nil
 		p.left = nil
 		p.value = nil
+		p.size = 0
+		p.hits.Store(0)
+		p.expires = 0
+		p.priority = 0
+		p.used.Store(0)
+		p.stored = 0
+		p.matched.Store(0)
 		return p
 	}
 
@@ -455,11 +930,17 @@ func loadip4(ipstr []byte) (uint32, error) {
 		oct uint32
 		b   byte
 		num byte
+		// digits of current octet, empty octets are not allowed
+		digits int
 	)
 
 	for _, b = range ipstr {
 		switch {
 		case b == '.':
+			if digits == 0 {
+				return 0, ErrBadIP
+			}
+			digits = 0
 			num++
 			if 0xffffffff-ip < oct {
 				return 0, ErrBadIP
@@ -467,6 +948,7 @@ func loadip4(ipstr []byte) (uint32, error) {
 			ip = ip<<8 + oct
 			oct = 0
 		case b >= '0' && b <= '9':
+			digits++
 			oct = oct*10 + uint32(b-'0')
 			if oct > 255 {
 				return 0, ErrBadIP
@@ -475,7 +957,7 @@ func loadip4(ipstr []byte) (uint32, error) {
 			return 0, ErrBadIP
 		}
 	}
-	if num != 3 {
+	if num != 3 || digits == 0 {
 		return 0, ErrBadIP
 	}
 	if 0xffffffff-ip < oct {
@@ -484,17 +966,57 @@ func loadip4(ipstr []byte) (uint32, error) {
 	return ip<<8 + oct, nil
 }
 
+// wrap adds operation and input it failed on to err, so errors.Is still matches the sentinel. Nil err stays nil.
+func wrap(op string, input []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("nradix: %s %q: %w", op, input, err)
+}
+
+// isv4 reports whether cidr should be parsed as IPv4, colon before the first dot means IPv6 (zone names may contain dots).
+func isv4(cidr []byte) bool {
+	p := bytes.IndexByte(cidr, '.')
+	return p > 0 && bytes.IndexByte(cidr[:p], ':') < 0
+}
+
+// parsecidr4 parses IPv4 address with optional prefix length or dotted netmask ("10.1.2.0/24", "10.1.2.0/255.255.255.0" or "10.1.2.0 255.255.255.0").
 func parsecidr4(cidr []byte) (uint32, uint32, error) {
+	ip, mask, err := scancidr4(cidr)
+	if err != nil {
+		return 0, 0, wrap("parse", cidr, err)
+	}
+	return ip, mask, nil
+}
+
+func scancidr4(cidr []byte) (uint32, uint32, error) {
 	var mask uint32
-	p := bytes.IndexByte(cidr, '/')
-	if p > 0 {
+	p := bytes.IndexAny(cidr, "/ ")
+	if p > 0 && bytes.IndexByte(cidr[p+1:], '.') >= 0 {
+		m, err := loadip4(cidr[p+1:])
+		if err != nil {
+			return 0, 0, err
+		}
+		// netmask bits must be contiguous
+		if ^m&(^m+1) != 0 {
+			return 0, 0, ErrBadIP
+		}
+		mask = m
+		cidr = cidr[:p]
+	} else if p > 0 {
+		if cidr[p] != '/' || p == len(cidr)-1 {
+			return 0, 0, ErrBadIP
+		}
+		var bits uint32
 		for _, c := range cidr[p+1:] {
 			if c < '0' || c > '9' {
 				return 0, 0, ErrBadIP
 			}
-			mask = mask*10 + uint32(c-'0')
+			if bits = bits*10 + uint32(c-'0'); bits > 32 {
+				return 0, 0, ErrBadIP
+			}
 		}
-		mask = 0xffffffff << (32 - mask)
+		mask = 0xffffffff << (32 - bits)
 		cidr = cidr[:p]
 	} else {
 		mask = 0xffffffff
@@ -507,17 +1029,139 @@ func parsecidr4(cidr []byte) (uint32, uint32, error) {
 }
 
 func parsecidr6(cidr []byte) (net.IP, net.IPMask, error) {
-	p := bytes.IndexByte(cidr, '/')
-	if p > 0 {
-		_, ipm, err := net.ParseCIDR(string(cidr))
-		if err != nil {
-			return nil, nil, err
+	hi, lo, bits, err := parsekey6(cidr)
+	if err != nil {
+		return nil, nil, err
+	}
+	ip := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(ip, hi)
+	binary.BigEndian.PutUint64(ip[8:], lo)
+	return ip, net.CIDRMask(bits, 8*net.IPv6len), nil
+}
+
+// parsekey6 parses IPv6 CIDR into masked key loaded as by loadkey and prefix length, it only allocates for errors.
+func parsekey6(cidr []byte) (hi, lo uint64, bits int, err error) {
+	if hi, lo, bits, err = scankey6(cidr); err != nil {
+		return 0, 0, 0, wrap("parse", cidr, err)
+	}
+	return hi, lo, bits, nil
+}
+
+func scankey6(cidr []byte) (hi, lo uint64, bits int, err error) {
+	bits = 8 * net.IPv6len
+	if p := bytes.IndexByte(cidr, '/'); p >= 0 {
+		if p == len(cidr)-1 {
+			return 0, 0, 0, ErrBadIP
+		}
+		bits = 0
+		for _, c := range cidr[p+1:] {
+			if c < '0' || c > '9' {
+				return 0, 0, 0, ErrBadIP
+			}
+			if bits = bits*10 + int(c-'0'); bits > 8*net.IPv6len {
+				return 0, 0, 0, ErrBadIP
+			}
 		}
-		return ipm.IP, ipm.Mask, nil
+		cidr = cidr[:p]
 	}
-	ip := net.ParseIP(string(cidr))
-	if ip == nil {
-		return nil, nil, ErrBadIP
+	// zone is not part of the key
+	if p := bytes.IndexByte(cidr, '%'); p >= 0 {
+		if p == len(cidr)-1 {
+			return 0, 0, 0, ErrBadIP
+		}
+		cidr = cidr[:p]
+	}
+	var ip [net.IPv6len]byte
+	if !loadip6(cidr, &ip) {
+		return 0, 0, 0, ErrBadIP
+	}
+	hi, lo = maskkey(binary.BigEndian.Uint64(ip[:]), binary.BigEndian.Uint64(ip[8:]), bits)
+	return hi, lo, bits, nil
+}
+
+// maskkey clears all bits of key loaded by loadkey after first bits.
+func maskkey(hi, lo uint64, bits int) (uint64, uint64) {
+	if bits < 64 {
+		return hi &^ (^uint64(0) >> uint(bits)), 0
+	}
+	return hi, lo &^ (^uint64(0) >> uint(bits-64))
+}
+
+func unhex(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10, true
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// loadip6 parses IPv6 address (possibly ending with embedded IPv4 part) into ip.
+func loadip6(ipstr []byte, ip *[net.IPv6len]byte) bool {
+	ellipsis := -1 // position of "::" in ip
+	if len(ipstr) >= 2 && ipstr[0] == ':' && ipstr[1] == ':' {
+		ellipsis = 0
+		ipstr = ipstr[2:]
+	}
+	i := 0
+	for i < net.IPv6len && len(ipstr) > 0 {
+		// embedded IPv4 address ends the string
+		if bytes.IndexByte(ipstr, '.') >= 0 && bytes.IndexByte(ipstr, ':') < 0 {
+			ip4, err := loadip4(ipstr)
+			if err != nil || i > net.IPv6len-net.IPv4len {
+				return false
+			}
+			binary.BigEndian.PutUint32(ip[i:], ip4)
+			i += net.IPv4len
+			ipstr = nil
+			break
+		}
+		var group, digits int
+		for ; digits < len(ipstr) && digits < 5; digits++ {
+			v, ok := unhex(ipstr[digits])
+			if !ok {
+				break
+			}
+			group = group<<4 + v
+		}
+		if digits == 0 || digits > 4 {
+			return false
+		}
+		ip[i], ip[i+1] = byte(group>>8), byte(group)
+		i += 2
+		ipstr = ipstr[digits:]
+		if len(ipstr) == 0 {
+			break
+		}
+		if ipstr[0] != ':' || len(ipstr) == 1 {
+			return false
+		}
+		ipstr = ipstr[1:]
+		if ipstr[0] == ':' {
+			if ellipsis >= 0 {
+				return false
+			}
+			ellipsis = i
+			ipstr = ipstr[1:]
+		}
+	}
+	if len(ipstr) != 0 {
+		return false
+	}
+	if i < net.IPv6len {
+		if ellipsis < 0 {
+			return false
+		}
+		n := net.IPv6len - i
+		copy(ip[ellipsis+n:], ip[ellipsis:i])
+		for j := ellipsis; j < ellipsis+n; j++ {
+			ip[j] = 0
+		}
+	} else if ellipsis >= 0 {
+		return false
 	}
-	return ip, net.IPMask{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil
+	return true
 }
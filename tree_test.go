@@ -4,7 +4,14 @@
 
 package nradix
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
 
 func TestTree(t *testing.T) {
 	tr := NewTree(0)
@@ -218,7 +225,7 @@ func TestSet(t *testing.T) {
 
 	// add covering should fail
 	err = tr.AddCIDR("1.1.1.0/24", 60)
-	if err != ErrNodeBusy {
+	if !errors.Is(err, ErrNodeBusy) {
 		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
 	}
 
@@ -353,3 +360,926 @@ func TestRegression6(t *testing.T) {
 		t.Errorf("Wrong value from /128 test, got %d, expected 12345", inf)
 	}
 }
+
+func TestSwap(t *testing.T) {
+	tr := NewTree(0)
+	old, ok, err := tr.SwapCIDR("1.1.1.0/24", 1)
+	if err != nil {
+		t.Error(err)
+	}
+	if ok || old != nil {
+		t.Errorf("Expected no previous value, got %v, %v", old, ok)
+	}
+
+	old, ok, err = tr.SwapCIDR("1.1.1.0/24", 2)
+	if err != nil {
+		t.Error(err)
+	}
+	if !ok || old.(int) != 1 {
+		t.Errorf("Expected previous value 1, got %v, %v", old, ok)
+	}
+	inf, err := tr.FindCIDR("1.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	// same node as AddCIDR would use
+	err = tr.AddCIDR("1.1.1.0/24", 3)
+	if !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+
+	_, _, err = tr.SwapCIDR("dead::/16", 4)
+	if err != nil {
+		t.Error(err)
+	}
+	inf, err = tr.FindCIDR("dead::1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 4 {
+		t.Errorf("Wrong value, expected 4, got %v", inf)
+	}
+}
+
+func TestSwapNil(t *testing.T) {
+	tr := NewTree(0)
+	tr.ExcludeCIDR("1.1.1.0/24")
+	old, ok, err := tr.SwapCIDR("1.1.1.0/24", 1)
+	if err != nil {
+		t.Error(err)
+	}
+	if ok || old != nil {
+		t.Errorf("Expected no previous value for exception, got %v, %v", old, ok)
+	}
+	tr.SetCIDRTTL("2.2.2.0/24", 2, -time.Second)
+	old, ok, err = tr.SwapCIDR("2.2.2.0/24", 3)
+	if err != nil {
+		t.Error(err)
+	}
+	if ok || old != nil {
+		t.Errorf("Expected no previous value for expired entry, got %v, %v", old, ok)
+	}
+
+	for _, fn := range []func(cidr string) error{
+		func(cidr string) error { _, _, err := tr.SwapCIDR(cidr, nil); return err },
+		func(cidr string) error { return tr.AddCIDR(cidr, nil) },
+		func(cidr string) error { return tr.SetCIDR(cidr, nil) },
+		func(cidr string) error { return tr.AddBatch([]BatchEntry{{cidr, nil}}) },
+	} {
+		if err := fn("3.3.3.0/24"); err != nil {
+			t.Error(err)
+		}
+		if err := fn("dead::/16"); err != nil {
+			t.Error(err)
+		}
+		if err := tr.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestDeleteValue(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("1.1.0.0/16", 1)
+	tr.AddCIDR("1.1.1.0/24", 2)
+
+	inf, err := tr.DeleteCIDRValue("1.1.0.0/16")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	inf, err = tr.FindCIDR("1.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+
+	inf, err = tr.DeleteCIDRValue("1.1.1.0/24")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	if tr.root.left != nil || tr.root.right != nil {
+		t.Error("Tree should have been trimmed down to the root")
+	}
+
+	_, err = tr.DeleteCIDRValue("1.1.1.0/24")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+
+	// deleting root value should not panic
+	tr.AddCIDR("0.0.0.0/0", 3)
+	inf, err = tr.DeleteCIDRValue("0.0.0.0/0")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+
+	tr.ExcludeCIDR("2.2.2.0/24")
+	tr.SetCIDRTTL("3.3.3.0/24", 4, -time.Second)
+	for _, cidr := range []string{"2.2.2.0/24", "3.3.3.0/24"} {
+		inf, err = tr.DeleteCIDRValue(cidr)
+		if !errors.Is(err, ErrNotFound) || inf != nil {
+			t.Errorf("Should have gotten ErrNotFound for %s, instead got %v, err: %v", cidr, inf, err)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tr := NewTree(0)
+	incr := func(old interface{}, exists bool) (interface{}, bool) {
+		if !exists {
+			return 1, true
+		}
+		return old.(int) + 1, true
+	}
+	for i := 0; i < 3; i++ {
+		if err := tr.UpdateCIDR("1.1.1.0/24", incr); err != nil {
+			t.Error(err)
+		}
+	}
+	inf, err := tr.FindCIDR("1.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+
+	err = tr.UpdateCIDR("1.1.1.0/24", func(old interface{}, exists bool) (interface{}, bool) {
+		return nil, false
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	inf, err = tr.FindCIDR("1.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if tr.root.left != nil || tr.root.right != nil {
+		t.Error("Tree should have been trimmed down to the root")
+	}
+
+	// not keeping missing entry should not create nodes
+	tr.UpdateCIDR("2.2.2.0/24", func(old interface{}, exists bool) (interface{}, bool) {
+		return 1, false
+	})
+	if tr.root.left != nil || tr.root.right != nil {
+		t.Error("Tree should have no nodes besides the root")
+	}
+
+	if err = tr.UpdateCIDR("bad", incr); err == nil {
+		t.Error("Should have gotten error for bad input")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	tr := NewTree(0)
+	sum := func(old, new interface{}) interface{} {
+		return old.(int) + new.(int)
+	}
+	if err := tr.UpsertCIDR("1.1.1.0/24", 5, sum); err != nil {
+		t.Error(err)
+	}
+	if err := tr.UpsertCIDR("1.1.1.0/24", 7, sum); err != nil {
+		t.Error(err)
+	}
+	inf, err := tr.FindCIDR("1.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 12 {
+		t.Errorf("Wrong value, expected 12, got %v", inf)
+	}
+
+	// expired value should not come back through merge
+	tr.SetCIDRTTL("2.2.2.0/24", 5, -time.Second)
+	tr.UpsertCIDR("2.2.2.0/24", 7, sum)
+	if inf, _ := tr.FindCIDR("2.2.2.1"); inf != 7 {
+		t.Errorf("Wrong value, expected 7, got %v", inf)
+	}
+	tr.AddCIDR("3.0.0.0/8", 1)
+	tr.ExcludeCIDR("3.3.3.0/24")
+	tr.UpdateCIDR("3.3.3.0/24", func(old interface{}, exists bool) (interface{}, bool) {
+		if exists || old != nil {
+			t.Errorf("Exception should not be reported as existing, got %v, %v", old, exists)
+		}
+		return nil, false
+	})
+	if inf, _ := tr.FindCIDR("3.3.3.1"); inf != nil {
+		t.Errorf("Exception should be kept, got %v", inf)
+	}
+
+	// node on the path of other entries is updated in place
+	tr = NewTree(0)
+	tr.SetMaxEntries(2)
+	tr.AddCIDR("10.0.1.0/24", 1)
+	tr.AddCIDR("10.0.2.0/24", 2)
+	tr.UpsertCIDR("10.0.0.0/22", 3, sum)
+	if tr.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", tr.Len())
+	}
+}
+
+func TestSetWholeRange(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("1.1.0.0/16", 1)
+	tr.AddCIDR("1.1.1.0/24", 2)
+	tr.AddCIDR("1.1.2.0/24", 3)
+	tr.AddCIDR("1.2.0.0/16", 4)
+
+	n, err := tr.SetWholeRangeCIDR("1.1.0.0/16", 5)
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 3 {
+		t.Errorf("Wrong number of entries affected, expected 3, got %d", n)
+	}
+	for _, ip := range []string{"1.1.0.1", "1.1.1.1", "1.1.2.1"} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf.(int) != 5 {
+			t.Errorf("Wrong value for %s, expected 5, got %v", ip, inf)
+		}
+	}
+	inf, err := tr.FindCIDR("1.2.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 4 {
+		t.Errorf("Wrong value, expected 4, got %v", inf)
+	}
+
+	n, err = tr.SetWholeRangeCIDR("2.0.0.0/8", 6)
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 0 {
+		t.Errorf("Wrong number of entries affected, expected 0, got %d", n)
+	}
+}
+
+func TestSetWholeRangeNil(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.2.0/24", 2)
+	tr.AddCIDR("10.1.3.0/24", 3)
+	n, err := tr.SetWholeRangeCIDR("10.0.0.0/8", nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 3 || tr.Len() != 0 {
+		t.Errorf("Wrong number of entries removed, expected 3, got %d with %d left", n, tr.Len())
+	}
+	if err := tr.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	tr.RejectHostBits(true)
+	if _, err := tr.SetWholeRangeCIDR("10.1.2.3/24", 1); !errors.Is(err, ErrHostBits) {
+		t.Errorf("Should have gotten ErrHostBits, instead got err: %v", err)
+	}
+	tr.RejectRedundant(true, nil)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.2.0/24", 2)
+	if _, err := tr.SetWholeRangeCIDR("10.1.0.0/16", 1); !errors.Is(err, ErrRedundant) {
+		t.Errorf("Should have gotten ErrRedundant, instead got err: %v", err)
+	}
+}
+
+func TestDeleteIf(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("1.1.0.0/16", 1)
+	tr.AddCIDR("1.1.1.0/24", 2)
+	tr.AddCIDR("1.1.2.0/24", 3)
+	tr.AddCIDR("dead::/16", 4)
+
+	var seen []string
+	n := tr.DeleteIf(func(prefix string, val interface{}) bool {
+		seen = append(seen, prefix)
+		return val.(int)%2 == 0
+	})
+	if n != 2 {
+		t.Errorf("Wrong number of entries removed, expected 2, got %d", n)
+	}
+	expected := []string{"1.1.0.0/16", "1.1.1.0/24", "1.1.2.0/24", "dead::/16"}
+	if len(seen) != len(expected) {
+		t.Fatalf("Wrong number of entries visited, expected %d, got %d", len(expected), len(seen))
+	}
+	for i := range expected {
+		if seen[i] != expected[i] {
+			t.Errorf("Wrong prefix, expected %s, got %s", expected[i], seen[i])
+		}
+	}
+
+	inf, err := tr.FindCIDR("1.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	inf, err = tr.FindCIDR("dead::1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+
+	n = tr.DeleteIf(func(prefix string, val interface{}) bool { return true })
+	if n != 2 {
+		t.Errorf("Wrong number of entries removed, expected 2, got %d", n)
+	}
+	if tr.root.left != nil || tr.root.right != nil || tr.root6.left != nil || tr.root6.right != nil {
+		t.Error("Tree should have been trimmed down to the root")
+	}
+}
+
+func TestAppend(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("1.1.1.0/24", "a")
+	if err := tr.AppendCIDR("1.1.1.0/24", "b"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AppendCIDR("1.1.1.0/24", "c"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AppendCIDR("1.1.0.0/16", "d"); err != nil {
+		t.Error(err)
+	}
+
+	values, err := tr.FindAllValues("1.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(values) != 3 || values[0] != "a" || values[1] != "b" || values[2] != "c" {
+		t.Errorf("Wrong values, expected [a b c], got %v", values)
+	}
+	values, err = tr.FindAllValues("1.1.2.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(values) != 1 || values[0] != "d" {
+		t.Errorf("Wrong values, expected [d], got %v", values)
+	}
+	values, err = tr.FindAllValues("1.2.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if values != nil {
+		t.Errorf("Wrong values, expected nil, got %v", values)
+	}
+
+	inf, err := tr.FindCIDR("1.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(inf.([]interface{})) != 3 {
+		t.Errorf("Wrong value, expected list of 3 values, got %v", inf)
+	}
+}
+
+func TestAddBatch(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("1.1.1.0/24", 1)
+
+	err := tr.AddBatch([]BatchEntry{{"2.2.2.0/24", 2}, {"3.3.3.0/24", 3}, {"bad", 4}})
+	if err == nil {
+		t.Error("Should have gotten error for bad input")
+	}
+	err = tr.AddBatch([]BatchEntry{{"2.2.2.0/24", 2}, {"1.1.1.0/24", 3}})
+	if !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	err = tr.AddBatch([]BatchEntry{{"2.2.2.0/24", 2}, {"2.2.2.1/24", 3}})
+	if !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	inf, err := tr.FindCIDR("2.2.2.2")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != nil {
+		t.Errorf("Failed batch should not modify tree, got %v", inf)
+	}
+
+	err = tr.AddBatch([]BatchEntry{{"2.2.2.0/24", 2}, {"dead::/16", 3}})
+	if err != nil {
+		t.Error(err)
+	}
+	inf, err = tr.FindCIDR("2.2.2.2")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+	inf, err = tr.FindCIDR("dead::1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 3 {
+		t.Errorf("Wrong value, expected 3, got %v", inf)
+	}
+}
+
+func TestLen(t *testing.T) {
+	tr := NewTree(4)
+	if tr.Len() != 0 {
+		t.Errorf("Wrong length, expected 0, got %d", tr.Len())
+	}
+	tr.AddCIDR("1.1.0.0/16", 1)
+	tr.AddCIDR("1.1.1.0/24", 2)
+	tr.AddCIDR("1.1.2.0/24", 3)
+	tr.AddCIDR("1.1.2.0/24", 4) // busy
+	tr.SetCIDR("1.1.2.0/24", 5)
+	tr.AddCIDR("dead::/16", 6)
+	if tr.Len() != 4 {
+		t.Errorf("Wrong length, expected 4, got %d", tr.Len())
+	}
+	tr.DeleteCIDR("1.1.0.0/16")
+	if tr.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", tr.Len())
+	}
+	tr.AddCIDR("1.1.0.0/16", 1)
+	tr.DeleteWholeRangeCIDR("1.1.0.0/16")
+	if tr.Len() != 1 {
+		t.Errorf("Wrong length, expected 1, got %d", tr.Len())
+	}
+	tr.SwapCIDR("2.2.2.0/24", 7)
+	tr.UpsertCIDR("2.2.2.0/24", 8, func(old, new interface{}) interface{} { return new })
+	tr.AddRange("3.3.3.1", "3.3.3.6", 9) // 4 CIDRs
+	if tr.Len() != 6 {
+		t.Errorf("Wrong length, expected 6, got %d", tr.Len())
+	}
+	tr.DeleteRange("3.3.3.0", "3.3.3.255")
+	tr.DeleteCIDRValue("2.2.2.0/24")
+	if tr.Len() != 1 {
+		t.Errorf("Wrong length, expected 1, got %d", tr.Len())
+	}
+	tr.DeleteIf(func(string, interface{}) bool { return true })
+	if tr.Len() != 0 {
+		t.Errorf("Wrong length, expected 0, got %d", tr.Len())
+	}
+	tr.AddCIDR("0.0.0.0/0", 10)
+	tr.AddCIDR("4.4.4.0/24", 11)
+	tr.DeleteWholeRangeCIDR("0.0.0.0/0")
+	if tr.Len() != 0 {
+		t.Errorf("Wrong length, expected 0, got %d", tr.Len())
+	}
+}
+
+func TestSubtreeSize(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.1.0/24", 3)
+	tr.AddCIDR("10.1.2.0/24", 4)
+	tr.AddCIDR("10.2.0.0/16", 5)
+
+	for cidr, size := range map[string]int{"10.0.0.0/8": 5, "10.1.0.0/16": 3, "10.1.0.0/20": 2, "10.1.1.0/24": 1, "10.1.1.1": 0, "11.0.0.0/8": 0, "0.0.0.0/0": 5} {
+		n, err := tr.SubtreeSize(cidr)
+		if err != nil {
+			t.Error(err)
+		}
+		if n != size {
+			t.Errorf("Wrong size of %s, expected %d, got %d", cidr, size, n)
+		}
+	}
+
+	tr.DeleteWholeRangeCIDR("10.1.0.0/16")
+	n, err := tr.SubtreeSize("10.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 2 {
+		t.Errorf("Wrong size, expected 2, got %d", n)
+	}
+	tr.DeleteCIDR("10.0.0.0/8")
+	n, err = tr.SubtreeSize("10.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 1 {
+		t.Errorf("Wrong size, expected 1, got %d", n)
+	}
+	// nodes from free list should not keep old sizes
+	tr.AddCIDR("10.3.0.0/16", 6)
+	n, err = tr.SubtreeSize("10.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 2 || tr.Len() != 2 {
+		t.Errorf("Wrong size, expected 2, got %d and length %d", n, tr.Len())
+	}
+}
+
+func TestClone(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	c := tr.Clone()
+	c.SetCIDR("10.1.0.0/16", 4)
+	c.DeleteCIDR("10.0.0.0/8")
+	tr.DeleteCIDR("dead::/16")
+
+	for ip, val := range map[string]interface{}{"10.1.1.1": 2, "10.2.1.1": 1, "dead::1": nil} {
+		inf, _ := tr.FindCIDR(ip)
+		if inf != val {
+			t.Errorf("Wrong value in original for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	for ip, val := range map[string]interface{}{"10.1.1.1": 4, "10.2.1.1": nil, "dead::1": 3} {
+		inf, _ := c.FindCIDR(ip)
+		if inf != val {
+			t.Errorf("Wrong value in clone for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Len() != 2 || c.Len() != 2 {
+		t.Errorf("Wrong length, expected 2 and 2, got %d and %d", tr.Len(), c.Len())
+	}
+	if cidrs := c.ListCIDRs(); len(cidrs) != 2 || cidrs[0] != "10.1.0.0/16" || cidrs[1] != "dead::/16" {
+		t.Errorf("Wrong prefixes in clone, got %v", cidrs)
+	}
+}
+
+func TestFamiliesSeparated(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("a00::/8", 2)
+	tr.AddCIDR("a00::/16", 3)
+
+	if size, _ := tr.SubtreeSize("10.0.0.0/8"); size != 1 {
+		t.Errorf("Wrong subtree size, expected 1, got %d", size)
+	}
+	if size, _ := tr.SubtreeSize("a00::/8"); size != 2 {
+		t.Errorf("Wrong subtree size, expected 2, got %d", size)
+	}
+	if err := tr.DeleteWholeRangeCIDR("a00::/8"); err != nil {
+		t.Error(err)
+	}
+	inf, err := tr.FindCIDR("10.0.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	if tr.Len() != 1 || tr.root6.left != nil || tr.root6.right != nil {
+		t.Errorf("IPv6 subtree should be empty, got %v", tr.ListCIDRs())
+	}
+}
+
+func TestReset(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+	tr.AddCIDR("0.0.0.0/0", 4)
+	nodes := len(tr.alloc)
+
+	tr.Reset()
+	if tr.Len() != 0 {
+		t.Errorf("Wrong length, expected 0, got %d", tr.Len())
+	}
+	inf, err := tr.FindCIDR("10.1.1.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if inf != nil {
+		t.Errorf("Wrong value, expected nil, got %v", inf)
+	}
+	if tr.root.left != nil || tr.root.right != nil || tr.root6.left != nil || tr.root6.right != nil {
+		t.Error("Tree should have been trimmed down to the roots")
+	}
+
+	tr.AddCIDR("10.0.0.0/8", 5)
+	tr.AddCIDR("10.1.0.0/16", 6)
+	tr.AddCIDR("dead::/16", 7)
+	if len(tr.alloc) != nodes {
+		t.Errorf("Nodes should be reused, %d were allocated before reset and %d after", nodes, len(tr.alloc))
+	}
+	inf, _ = tr.FindCIDR("10.1.1.1")
+	if inf.(int) != 6 || tr.Len() != 3 {
+		t.Errorf("Wrong value, expected 6 and 3 entries, got %v and %d", inf, tr.Len())
+	}
+}
+
+func TestPreallocate(t *testing.T) {
+	tr := NewTree(1000)
+	if cap(tr.alloc) != 1002 {
+		t.Errorf("Wrong number of preallocated nodes, expected 1002, got %d", cap(tr.alloc))
+	}
+	for i := 0; i < 30; i++ {
+		if err := tr.AddCIDR(fmt.Sprintf("10.%d.0.0/16", i), i); err != nil {
+			t.Error(err)
+		}
+	}
+	if cap(tr.alloc) != 1002 || len(tr.alloc) > 1002 {
+		t.Errorf("All nodes should come from preallocated slab, got slab of %d", cap(tr.alloc))
+	}
+	inf, _ := tr.FindCIDR("10.29.1.1")
+	if inf.(int) != 29 {
+		t.Errorf("Wrong value, expected 29, got %v", inf)
+	}
+	if tr := NewTree(-1); tr.Len() != 0 {
+		t.Error("Negative preallocate should create empty tree")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	tr := NewTree(0)
+	for i := 0; i < 256; i++ {
+		tr.AddCIDR(fmt.Sprintf("10.%d.0.0/16", i), i)
+	}
+	tr.AddCIDR("dead::/16", -1)
+	for i := 1; i < 256; i++ {
+		tr.DeleteCIDR(fmt.Sprintf("10.%d.0.0/16", i))
+	}
+	tr.CountHits(true)
+	tr.FindCIDR("dead::1")
+
+	if released := tr.Compact(); released <= 0 {
+		t.Errorf("Compact should release memory, got %d", released)
+	}
+	if len(tr.alloc) != cap(tr.alloc) || tr.free != nil || len(tr.alloc) != 2+16+16 {
+		t.Errorf("Nodes should be in exact slab, got %d of %d", len(tr.alloc), cap(tr.alloc))
+	}
+	if released := tr.Compact(); released != 0 {
+		t.Errorf("Compacted tree should have nothing to release, got %d", released)
+	}
+	for ip, val := range map[string]interface{}{"10.0.1.1": 0, "10.1.1.1": nil, "dead::1": -1} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if hits, _ := tr.HitsCIDR("dead::/16"); hits != 2 {
+		t.Errorf("Hits should be kept, expected 2, got %d", hits)
+	}
+	if tr.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", tr.Len())
+	}
+	tr.AddCIDR("10.1.0.0/16", 1)
+	if inf, _ := tr.FindCIDR("10.1.1.1"); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+}
+
+func TestFullLengthIPv6(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("::/0", 0)
+	tr.AddCIDR("dead:beef::1/128", 1)
+	tr.AddCIDR("dead:beef::/64", 2)
+	tr.AddCIDR("dead:beef::8000:0:0:0/65", 3)
+	for ip, val := range map[string]int{"dead:beef::1": 1, "dead:beef::2": 2, "dead:beef::8000:0:0:1": 3, "dead:beef:0:1::1": 0, "dead:beef::/64": 2, "dead:beef::1/127": 2} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %d, got %v", ip, val, inf)
+		}
+	}
+	if err := tr.AddCIDR("dead:beef::1", 4); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if err := tr.DeleteCIDR("dead:beef::1/128"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteCIDR("dead:beef::1/128"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if inf, _ := tr.FindCIDR("dead:beef::1"); inf != 2 {
+		t.Errorf("Wrong value, expected 2, got %v", inf)
+	}
+}
+
+func TestParseIPv6(t *testing.T) {
+	for _, s := range []string{"::", "::1", "1::", "1:2:3:4:5:6:7:8", "1:2:3:4:5:6:7::", "::2:3:4:5:6:7:8", "dead:BEEF::0:1", "fe80::1:2",
+		":::", "1:::2", "1::2::3", "12345::", "1:2:3:4:5:6:7:8:9", "1:2:3:4:5:6:7:8::", ":1::", "1:", "1::2:", "", "g::", "1:2:3:4:5:6:7", "0000:0:0:0:0:0:0:1",
+		"::ffff:1.2.3.4", "::1.2.3.4", "1:2:3:4:5:6:1.2.3.4", "1:2:3:4:5:6:7:1.2.3.4", "::ffff:1.2.3", "::ffff:1.2.3.4:5", "1.2.3.4::", "::ffff:1.2.3.256"} {
+		var ip [16]byte
+		ok := loadip6([]byte(s), &ip)
+		expected := net.ParseIP(s)
+		if ok != (expected != nil) {
+			t.Errorf("Wrong result of parsing %q, expected %v, got %v", s, expected != nil, ok)
+		} else if ok && !net.IP(ip[:]).Equal(expected) {
+			t.Errorf("Wrong address parsed from %q, expected %s, got %s", s, expected, net.IP(ip[:]))
+		}
+	}
+	for _, s := range []string{"dead::/16", "dead:beef::1/0", "dead:beef::1/64", "dead:beef::1/65", "::1/128", "::/129", "::/", "::/1a", "::/0128", "/64"} {
+		key, mask, err := parsecidr6([]byte(s))
+		_, ipnet, expected := net.ParseCIDR(s)
+		if (err == nil) != (expected == nil) {
+			t.Errorf("Wrong result of parsing %q, expected error %v, got %v", s, expected, err)
+		} else if err == nil && (!key.Equal(ipnet.IP) || masklen(mask) != masklen(ipnet.Mask)) {
+			t.Errorf("Wrong prefix parsed from %q, expected %s, got %s/%d", s, ipnet, key, masklen(mask))
+		}
+	}
+}
+
+func TestFindNoAlloc(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("dead::/16", 2)
+	for _, ip := range []string{"10.1.2.3", "10.1.2.0/24", "dead::1", "dead:beef::/32"} {
+		cidr := []byte(ip)
+		if allocs := testing.AllocsPerRun(100, func() { tr.FindCIDRb(cidr) }); allocs != 0 {
+			t.Errorf("FindCIDRb(%q) should not allocate, got %v allocations", ip, allocs)
+		}
+	}
+}
+
+func TestNetmaskNotation(t *testing.T) {
+	tr := NewTree(0)
+	if err := tr.AddCIDR("10.1.2.0 255.255.255.0", 1); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("10.2.0.0/255.255.0.0", 2); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("10.1.2.0/24", 3); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	for ip, val := range map[string]interface{}{"10.1.2.3": 1, "10.2.3.4": 2, "10.3.0.1": nil, "10.1.2.0 255.255.255.128": 1} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	for _, cidr := range []string{"10.0.0.0/255.0.255.0", "10.0.0.0 255.0.0", "10.0.0.0 8", "10.0.0.0/255.255.255.256"} {
+		if err := tr.AddCIDR(cidr, 4); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP for %q, instead got err: %v", cidr, err)
+		}
+	}
+	if err := tr.DeleteCIDR("10.2.0.0 255.255.0.0"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.SetCIDR("10.1.2.0 255.255.255.0", 5); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.2.3"); inf != 5 {
+		t.Errorf("Wrong value, expected 5, got %v", inf)
+	}
+}
+
+func TestIPv4Mapped(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.1.2.0/24", 1)
+	if err := tr.AddCIDR("::ffff:10.2.0.0/112", 2); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("::ffff:0a01:0200/120", 3); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	tr.AddCIDR("::/0", 4)
+
+	for ip, val := range map[string]interface{}{"10.1.2.3": 1, "::ffff:10.1.2.3": 1, "::ffff:a01:203": 1, "::ffff:10.1.2.0/120": 1,
+		"10.2.3.4": 2, "::ffff:10.2.3.4": 2, "::ffff:10.3.0.1": nil, "::10.1.2.3": 4, "::ffff:0:0/95": 4} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	for _, inf := range []interface{}{
+		tr.Find16([16]byte{10: 0xff, 11: 0xff, 12: 10, 13: 1, 14: 2, 15: 3}),
+		func() interface{} { v, _ := tr.FindIP(net.ParseIP("::ffff:10.1.2.3")); return v }(),
+		func() interface{} { v, _ := tr.FindAddr(netip.MustParseAddr("::ffff:10.1.2.3")); return v }(),
+		func() interface{} { v, _ := tr.FindPrefix(netip.MustParsePrefix("::ffff:10.1.2.0/120")); return v }(),
+		func() interface{} {
+			v, _ := tr.FindReverseName("3.0.2.0.1.0.a.0.f.f.f.f.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa")
+			return v
+		}(),
+	} {
+		if inf != 1 {
+			t.Errorf("Wrong value, expected 1, got %v", inf)
+		}
+	}
+
+	if err := tr.DeleteCIDR("10.2.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	if tr.Contains("::ffff:10.2.3.4") {
+		t.Error("::ffff:10.2.3.4 should not be contained")
+	}
+}
+
+func TestStrictParsing(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("0.0.0.0/0", 1)
+	for _, cidr := range []string{"10.0.0.0/", "10.0.0.0/33", "10.0.0.0/4294967328", "1..2.3", "1.2.3.", ".1.2.3", "1.2.3.4.5",
+		"1.2.3.4 ", "1.2.3.4/-1", "", "/8", "dead::/", "::/129", ":::", "dead::%"} {
+		if err := tr.AddCIDR(cidr, 2); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP adding %q, instead got err: %v", cidr, err)
+		}
+		if err := tr.SetCIDR(cidr, 2); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP setting %q, instead got err: %v", cidr, err)
+		}
+		if err := tr.DeleteCIDR(cidr); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP deleting %q, instead got err: %v", cidr, err)
+		}
+		if inf, err := tr.FindCIDR(cidr); !errors.Is(err, ErrBadIP) || inf != nil {
+			t.Errorf("Should have gotten ErrBadIP finding %q, instead got %v, err: %v", cidr, inf, err)
+		}
+	}
+	if tr.Len() != 1 {
+		t.Errorf("Wrong length, expected 1, got %d", tr.Len())
+	}
+}
+
+func TestWrappedErrors(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	for _, c := range []struct {
+		err      error
+		sentinel error
+		message  string
+	}{
+		{tr.AddCIDR("10.0.0.256", 2), ErrBadIP, `nradix: parse "10.0.0.256": Bad IP address or mask`},
+		{tr.AddCIDR("dead::/129", 2), ErrBadIP, `nradix: parse "dead::/129": Bad IP address or mask`},
+		{tr.AddCIDR("10.0.0.0/8", 2), ErrNodeBusy, `nradix: add "10.0.0.0/8": Node Busy`},
+		{tr.DeleteCIDR("10.0.0.0/16"), ErrNotFound, `nradix: delete "10.0.0.0/16": No Such Node`},
+		{tr.DeleteCIDR("dead::/16"), ErrNotFound, `nradix: delete "dead::/16": No Such Node`},
+		{tr.DeleteWholeRangeCIDR("11.0.0.0/8"), ErrNotFound, `nradix: delete "11.0.0.0/8": No Such Node`},
+		{deletevalue(tr, "11.0.0.0/8"), ErrNotFound, `nradix: delete "11.0.0.0/8": No Such Node`},
+		{tr.AddBatch([]BatchEntry{{"11.0.0.0/8", 2}, {"10.0.0.0/8", 2}}), ErrNodeBusy, `entry 1: nradix: add "10.0.0.0/8": Node Busy`},
+		{tr.AddRange("10.0.0.0", "10.255.255.255", 2), ErrNodeBusy, `nradix: add "10.0.0.0/8": Node Busy`},
+		{tr.AddRange("10.0.0.1", "10.0.0.0", 2), ErrBadIP, `nradix: parse "10.0.0.1-10.0.0.0": Bad IP address or mask`},
+		{tr.AddPrefix(netip.MustParsePrefix("10.0.0.0/8"), 2), ErrNodeBusy, `nradix: add "10.0.0.0/8": Node Busy`},
+		{tr.AddPrefix(netip.Prefix{}, 2), ErrBadIP, `nradix: parse "invalid Prefix": Bad IP address or mask`},
+		{tr.DeletePrefix(netip.MustParsePrefix("11.0.0.0/8")), ErrNotFound, `nradix: delete "11.0.0.0/8": No Such Node`},
+		{tr.AddIPNet(&net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}, 2), ErrNodeBusy, `nradix: add "10.0.0.0/8": Node Busy`},
+		{tr.DeleteIPNet(nil), ErrBadIP, `nradix: parse "<nil>": Bad IP address or mask`},
+		{tr.AddCIDRPriority("10.0.0.0/8", 2, 1), ErrNodeBusy, `nradix: add "10.0.0.0/8": Node Busy`},
+		{tr.AddCIDRTTL("10.0.0.0/8", 2, time.Hour), ErrNodeBusy, `nradix: add "10.0.0.0/8": Node Busy`},
+		{tr.ExcludeCIDR("10.0.0.0/8"), ErrNodeBusy, `nradix: exclude "10.0.0.0/8": Node Busy`},
+		{second(tr.ExactMatchCIDR("11.0.0.0/8")), ErrNotFound, `nradix: find "11.0.0.0/8": No Such Node`},
+		{second(tr.FindCIDRMaxLen("10.0.0.0/8", -1)), ErrBadIP, `nradix: parse "10.0.0.0/8": Bad IP address or mask`},
+		{second(tr.HitsCIDR("11.0.0.0/8")), ErrNotFound, `nradix: find "11.0.0.0/8": No Such Node`},
+		{second(tr.FindPrefix(netip.Prefix{})), ErrBadIP, `nradix: parse "invalid Prefix": Bad IP address or mask`},
+		{second(tr.FindIPNet(nil)), ErrBadIP, `nradix: parse "<nil>": Bad IP address or mask`},
+		{second(tr.FindNumeric("x")), ErrBadIP, `nradix: parse "x": Bad IP address or mask`},
+		{tr.Deaggregate("11.0.0.0/8", 9), ErrNotFound, `nradix: deaggregate "11.0.0.0/8": No Such Node`},
+		{tr.SetTag("10.0.0.0/8", "t", 1), ErrNodeBusy, `nradix: set "10.0.0.0/8": Node Busy`},
+		{tr.DeleteTag("11.0.0.0/8", "t"), ErrNotFound, `nradix: delete "11.0.0.0/8": No Such Node`},
+		{tr.Rollback(42), ErrNotFound, `nradix: rollback "42": No Such Node`},
+		{tr.Begin().DeleteCIDR("11.0.0.0/8"), ErrNotFound, `nradix: delete "11.0.0.0/8": No Such Node`},
+		{tr.Begin().AddCIDR("10.0.0.0/8", 2), ErrNodeBusy, `nradix: add "10.0.0.0/8": Node Busy`},
+		{tr.Add16([16]byte{}, 129, 2), ErrBadIP, `nradix: parse "::": Bad IP address or mask`},
+		{NewCOWTree().DeleteCIDR("10.0.0.0/8"), ErrNotFound, `nradix: delete "10.0.0.0/8": No Such Node`},
+		{NewRCUTree().DeleteCIDR("10.0.0.0/8"), ErrNotFound, `nradix: delete "10.0.0.0/8": No Such Node`},
+		{NewShardedTree(2).DeleteCIDR("10.0.0.0/8"), ErrNotFound, `nradix: delete "10.0.0.0/8": No Such Node`},
+		{NewTreeOf[int](0).DeleteCIDR("10.0.0.0/8"), ErrNotFound, `nradix: delete "10.0.0.0/8": No Such Node`},
+		{NewPatriciaTree().DeleteCIDR("10.0.0.0/8"), ErrNotFound, `nradix: delete "10.0.0.0/8": No Such Node`},
+		{NewMultiTree(0).DeleteCIDR("red", "10.0.0.0/8"), ErrNotFound, `nradix: delete "10.0.0.0/8": No Such Node`},
+		{NewZonedTree(0).DeleteCIDR("fe80::%eth0/64"), ErrNotFound, `nradix: delete "fe80::%eth0/64": No Such Node`},
+		{NewKeyTree(0).DeleteKey([]byte("ab"), 16), ErrNotFound, `nradix: delete "ab": No Such Node`},
+		{second(BuildTree([]Entry{{}}, 1)), ErrBadIP, `nradix: parse "<nil>": Bad IP address or mask`},
+	} {
+		if !errors.Is(c.err, c.sentinel) || c.err.Error() != c.message {
+			t.Errorf("Wrong error, expected %q, got %v", c.message, c.err)
+		}
+	}
+}
+
+// second returns error result of call returning value and error.
+func second[T any](_ T, err error) error {
+	return err
+}
+
+func deletevalue(tr *Tree, cidr string) error {
+	_, err := tr.DeleteCIDRValue(cidr)
+	return err
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"time"
+)
+
+// AddCIDRTTL adds value associated with IP/mask to the tree for ttl. Expired entry is treated as absent by lookups, walks and AddCIDR, but keeps its nodes (and is counted by Len) until Expire reclaims it.
+// Will return error for invalid CIDR or if live value already exists.
+func (tree *Tree) AddCIDRTTL(cidr string, val interface{}, ttl time.Duration) error {
+	return tree.AddCIDRTTLb([]byte(cidr), val, ttl)
+}
+
+func (tree *Tree) AddCIDRTTLb(cidr []byte, val interface{}, ttl time.Duration) error {
+	return tree.insertttl(cidr, val, ttl, false)
+}
+
+// SetCIDRTTL sets value associated with IP/mask in the tree for ttl, overwriting existing one.
+func (tree *Tree) SetCIDRTTL(cidr string, val interface{}, ttl time.Duration) error {
+	return tree.SetCIDRTTLb([]byte(cidr), val, ttl)
+}
+
+func (tree *Tree) SetCIDRTTLb(cidr []byte, val interface{}, ttl time.Duration) error {
+	return tree.insertttl(cidr, val, ttl, true)
+}
+
+// Expire removes all expired entries from the tree releasing their nodes, returns number of removed entries.
+func (tree *Tree) Expire() int {
+	now := time.Now().UnixNano()
+	var expired []*node
+	for _, root := range []*node{tree.root, tree.root6} {
+		expired = expiredof(root, now, expired)
+	}
+	for _, n := range expired {
+		tree.evicted(n)
+		tree.store(n, nil)
+		tree.trim(n)
+	}
+	return len(expired)
+}
+
+func (tree *Tree) insertttl(cidr []byte, val interface{}, ttl time.Duration, overwrite bool) error {
+	if err := tree.checkhost(cidr); err != nil {
+		return err
+	}
+	if err := tree.checkredundant(insertop(overwrite), cidr, val, overwrite); err != nil {
+		return err
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	node := tree.locate(key, masklen(mask))
+	if node.live() && !overwrite {
+		return wrap("add", cidr, ErrNodeBusy)
+	}
+	tree.setvalue(node, val)
+	node.expires = time.Now().Add(ttl).UnixNano()
+	tree.trim(node)
+	tree.evict()
+	return nil
+}
+
+// expiredof appends nodes under n (n included) holding values expired by now to list.
+func expiredof(n *node, now int64, list []*node) []*node {
+	if n.value != nil && n.expires != 0 && n.expires <= now {
+		list = append(list, n)
+	}
+	if n.left != nil {
+		list = expiredof(n.left, now, list)
+	}
+	if n.right != nil {
+		list = expiredof(n.right, now, list)
+	}
+	return list
+}
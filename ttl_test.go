@@ -0,0 +1,122 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTTL(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "permanent")
+	if err := tr.AddCIDRTTL("10.1.0.0/16", "short", time.Millisecond); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRTTL("10.2.0.0/16", "long", time.Hour); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRTTL("dead::/16", "short6", time.Millisecond); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDRTTL("10.1.0.0/16", "again", time.Hour); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.2.3"); inf != "short" {
+		t.Errorf("Wrong value, expected short, got %v", inf)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	for ip, val := range map[string]interface{}{"10.1.2.3": "permanent", "10.2.3.4": "long", "dead::1": nil} {
+		if inf, _ := tr.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Contains("dead::1") {
+		t.Error("Expired entry should not be contained")
+	}
+	if _, err := tr.ExactMatchCIDR("10.1.0.0/16"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if cidrs := tr.ListCIDRs(); len(cidrs) != 2 {
+		t.Errorf("Expired entries should not be walked, got %v", cidrs)
+	}
+	if tr.Len() != 4 {
+		t.Errorf("Wrong length, expected 4, got %d", tr.Len())
+	}
+
+	if removed := tr.Expire(); removed != 2 {
+		t.Errorf("Wrong number of expired entries, expected 2, got %d", removed)
+	}
+	if tr.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", tr.Len())
+	}
+	if nodes := tr.nodes(tr.root6); nodes != 1 {
+		t.Errorf("Expired nodes should be released, got %d nodes", nodes)
+	}
+
+	if err := tr.AddCIDRTTL("10.1.0.0/16", "again", time.Nanosecond); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := tr.AddCIDR("10.1.0.0/16", "replaced"); err != nil {
+		t.Errorf("Expired entry should be replaceable, got err: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if inf, _ := tr.FindCIDR("10.1.2.3"); inf != "replaced" {
+		t.Errorf("Wrong value, expected permanent replacement, got %v", inf)
+	}
+}
+
+func TestTTLMarshal(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", "a")
+	tr.AddCIDRTTL("10.1.0.0/16", "b", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	data, err := tr.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored Tree
+	if err = restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 1 {
+		t.Errorf("Wrong number of entries, expected 1, got %d", restored.Len())
+	}
+
+	data, err = tr.GobEncode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored = Tree{}
+	if err = restored.GobDecode(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Len() != 1 {
+		t.Errorf("Wrong number of entries, expected 1, got %d", restored.Len())
+	}
+}
+
+func TestTTLExpiredInsert(t *testing.T) {
+	tr := NewTree(0)
+	tr.SetCIDRTTL("10.0.0.0/8", 1, -time.Second)
+	tr.SetCIDRTTL("11.0.0.0/8", 1, -time.Second)
+	if err := tr.AddBatch([]BatchEntry{{"10.0.0.0/8", 2}}); err != nil {
+		t.Errorf("Expired entry should not block batch, got err: %v", err)
+	}
+	tx := tr.Begin()
+	tx.AddCIDR("11.0.0.0/8", 3)
+	if err := tx.Commit(); err != nil {
+		t.Errorf("Expired entry should not block transaction, got err: %v", err)
+	}
+	for ip, val := range map[string]interface{}{"10.0.0.1": 2, "11.0.0.1": 3} {
+		if inf, _ := tr.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"net"
+	"sync"
+)
+
+type txop struct {
+	key       net.IP
+	bits      int
+	value     interface{}
+	overwrite bool
+	delete    bool
+}
+
+// Tx collects modifications of the tree which are applied by Commit all at once, or not applied at all if any of them fails.
+// Tree is not changed until Commit, Tx itself is not safe for concurrent use.
+type Tx struct {
+	tree *Tree
+	mu   *sync.RWMutex
+	ops  []txop
+	// state is presence of values at prefixes touched by the transaction
+	state map[string]bool
+	done  bool
+}
+
+// Begin starts transaction on the tree.
+func (tree *Tree) Begin() *Tx {
+	return &Tx{tree: tree, state: make(map[string]bool)}
+}
+
+// Begin starts transaction on the tree, Commit applies it under write lock so readers never see part of it.
+func (st *SafeTree) Begin() *Tx {
+	return &Tx{tree: st.tree, mu: &st.mu, state: make(map[string]bool)}
+}
+
+// AddCIDR adds value associated with IP/mask to the transaction. Will return error for invalid CIDR or if value already exists in the tree or transaction.
+func (tx *Tx) AddCIDR(cidr string, val interface{}) error {
+	return tx.AddCIDRb([]byte(cidr), val)
+}
+
+func (tx *Tx) AddCIDRb(cidr []byte, val interface{}) error {
+	return tx.record(cidr, txop{value: val})
+}
+
+// SetCIDR sets value associated with IP/mask in the transaction, overwriting existing one.
+func (tx *Tx) SetCIDR(cidr string, val interface{}) error {
+	return tx.SetCIDRb([]byte(cidr), val)
+}
+
+func (tx *Tx) SetCIDRb(cidr []byte, val interface{}) error {
+	return tx.record(cidr, txop{value: val, overwrite: true})
+}
+
+// DeleteCIDR removes value associated with IP/mask in the transaction. Will return ErrNotFound if there is no such value in the tree or transaction.
+func (tx *Tx) DeleteCIDR(cidr string) error {
+	return tx.DeleteCIDRb([]byte(cidr))
+}
+
+func (tx *Tx) DeleteCIDRb(cidr []byte) error {
+	return tx.record(cidr, txop{delete: true})
+}
+
+// Commit applies all modifications of the transaction. Tree could be changed since they were recorded,
+// so all of them are checked again first and tree is left untouched if any would fail.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if tx.mu != nil {
+		tx.mu.Lock()
+		defer tx.mu.Unlock()
+	}
+	state := make(map[string]bool, len(tx.state))
+	for _, op := range tx.ops {
+		if err := tx.check(state, op); err != nil {
+			return err
+		}
+	}
+	for _, op := range tx.ops {
+		if op.delete {
+			node := tx.tree.lookup(op.key, op.bits)
+			tx.tree.setvalue(node, nil)
+			tx.tree.trim(node)
+			continue
+		}
+		node := tx.tree.locate(op.key, op.bits)
+		tx.tree.setvalue(node, op.value)
+		tx.tree.trim(node)
+	}
+	tx.tree.evict()
+	return nil
+}
+
+// Rollback drops all modifications of the transaction.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	tx.ops, tx.state = nil, nil
+	return nil
+}
+
+func (tx *Tx) record(cidr []byte, op txop) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	op.bits = masklen(mask)
+	op.key = key.Mask(mask)
+	if tx.mu != nil {
+		tx.mu.RLock()
+		defer tx.mu.RUnlock()
+	}
+	if !op.delete {
+		if err := tx.tree.checkhost(cidr); err != nil {
+			return err
+		}
+	}
+	if err := tx.check(tx.state, op); err != nil {
+		return err
+	}
+	tx.ops = append(tx.ops, op)
+	return nil
+}
+
+// check returns error if op can not be applied to the tree after modifications recorded in state, state is updated otherwise.
+func (tx *Tx) check(state map[string]bool, op txop) error {
+	id := string(append(op.key, byte(op.bits)))
+	exists, ok := state[id]
+	busy := exists
+	if !ok {
+		// expired entry could still be deleted, but it does not block inserts
+		node := tx.tree.lookup(op.key, op.bits)
+		exists = node != nil && node.value != nil
+		busy = node != nil && node.live()
+	}
+	cidr := []byte(newprefix(op.key, op.bits).String())
+	switch {
+	case op.delete && !exists:
+		return wrap("delete", cidr, ErrNotFound)
+	case !op.delete && !op.overwrite && busy:
+		return wrap("add", cidr, ErrNodeBusy)
+	case !op.delete && tx.tree.redundant != nil && tx.tree.isredundant(op.key, op.bits, op.value, op.overwrite):
+		return wrap(insertop(op.overwrite), cidr, ErrRedundant)
+	}
+	state[id] = !op.delete && op.value != nil
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTx(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+
+	tx := tr.Begin()
+	if err := tx.AddCIDR("10.2.0.0/16", 3); err != nil {
+		t.Error(err)
+	}
+	if err := tx.DeleteCIDR("10.1.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	if err := tx.SetCIDR("10.0.0.0/8", 4); err != nil {
+		t.Error(err)
+	}
+	if err := tx.AddCIDR("10.2.0.0/16", 5); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if err := tx.DeleteCIDR("10.1.0.0/16"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if err := tx.AddCIDR("10.1.0.0/16", 6); err != nil {
+		t.Error(err)
+	}
+	if err := tx.AddCIDR("10.1.0.256/16", 6); err == nil {
+		t.Error("Should have gotten error for bad CIDR")
+	}
+
+	// nothing applied before commit
+	inf, _ := tr.FindCIDR("10.2.1.1")
+	if inf.(int) != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Error(err)
+	}
+	for ip, val := range map[string]interface{}{"10.2.1.1": 3, "10.1.1.1": 6, "10.3.1.1": 4} {
+		inf, _ := tr.FindCIDR(ip)
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", tr.Len())
+	}
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("Should have gotten ErrTxDone, instead got err: %v", err)
+	}
+
+	tx = tr.Begin()
+	tx.DeleteCIDR("10.2.0.0/16")
+	if err := tx.Rollback(); err != nil {
+		t.Error(err)
+	}
+	if err := tx.AddCIDR("10.4.0.0/16", 1); err != ErrTxDone {
+		t.Errorf("Should have gotten ErrTxDone, instead got err: %v", err)
+	}
+	if tr.Len() != 3 {
+		t.Errorf("Wrong length after rollback, expected 3, got %d", tr.Len())
+	}
+}
+
+func TestTxConflict(t *testing.T) {
+	st := NewSafeTree(0)
+	st.AddCIDR("10.0.0.0/8", 1)
+
+	tx := st.Begin()
+	tx.AddCIDR("10.2.0.0/16", 2)
+	tx.DeleteCIDR("10.0.0.0/8")
+	// tree changed after operation was recorded, whole transaction fails
+	st.DeleteCIDR("10.0.0.0/8")
+	if err := tx.Commit(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if st.Len() != 0 {
+		t.Errorf("Tree should be untouched by failed commit, got %v", st.ListCIDRs())
+	}
+}
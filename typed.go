@@ -0,0 +1,254 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"iter"
+	"net"
+	"net/netip"
+)
+
+type tnode[T any] struct {
+	left, right, parent *tnode[T]
+	value               T
+	set                 bool
+}
+
+// TreeOf is Tree storing values of type T without boxing them into interfaces, so lookups need no type assertions
+// and scalar values do not allocate. Since zero T is valid value, presence of entries is reported separately. Zero value is not usable, create it with NewTreeOf.
+type TreeOf[T any] struct {
+	root, root6 *tnode[T]
+	free        *tnode[T]
+	count       int
+
+	alloc []tnode[T]
+}
+
+// NewTreeOf creates TreeOf and preallocates (if preallocate not zero) number of nodes that would be ready to fill with data.
+func NewTreeOf[T any](preallocate int) *TreeOf[T] {
+	tree := new(TreeOf[T])
+	if preallocate > 0 {
+		tree.alloc = make([]tnode[T], 0, preallocate+2)
+	}
+	tree.root = tree.newnode()
+	tree.root6 = tree.newnode()
+	return tree
+}
+
+// AddCIDR adds value associated with IP/mask to the tree. Will return error for invalid CIDR or if value already exists.
+func (tree *TreeOf[T]) AddCIDR(cidr string, val T) error {
+	return tree.AddCIDRb([]byte(cidr), val)
+}
+
+func (tree *TreeOf[T]) AddCIDRb(cidr []byte, val T) error {
+	return tree.insert(cidr, val, false)
+}
+
+// SetCIDR sets value associated with IP/mask in the tree, overwriting existing one.
+func (tree *TreeOf[T]) SetCIDR(cidr string, val T) error {
+	return tree.SetCIDRb([]byte(cidr), val)
+}
+
+func (tree *TreeOf[T]) SetCIDRb(cidr []byte, val T) error {
+	return tree.insert(cidr, val, true)
+}
+
+// DeleteCIDR removes value associated with IP/mask from the tree.
+func (tree *TreeOf[T]) DeleteCIDR(cidr string) error {
+	return tree.DeleteCIDRb([]byte(cidr))
+}
+
+func (tree *TreeOf[T]) DeleteCIDRb(cidr []byte) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	bits := masklen(mask)
+	n := tree.rootof(key)
+	for d := 0; n != nil && d < bits; d++ {
+		n = n.child(key, d)
+	}
+	if n == nil || !n.set {
+		return wrap("delete", cidr, ErrNotFound)
+	}
+	var zero T
+	n.value, n.set = zero, false
+	tree.count--
+	for n.parent != nil && !n.set && n.left == nil && n.right == nil {
+		if n.parent.right == n {
+			n.parent.right = nil
+		} else {
+			n.parent.left = nil
+		}
+		// reserve this node for future use
+		n.right = tree.free
+		tree.free = n
+		n = n.parent
+	}
+	return nil
+}
+
+// FindCIDR traverses tree to proper Node and returns previously saved information in longest covered IP, zero T if there is none.
+func (tree *TreeOf[T]) FindCIDR(cidr string) (T, error) {
+	val, _, err := tree.GetCIDRb([]byte(cidr))
+	return val, err
+}
+
+func (tree *TreeOf[T]) FindCIDRb(cidr []byte) (T, error) {
+	val, _, err := tree.GetCIDRb(cidr)
+	return val, err
+}
+
+// GetCIDR works like FindCIDR but also reports whether any stored prefix covers IP/mask.
+func (tree *TreeOf[T]) GetCIDR(cidr string) (T, bool, error) {
+	return tree.GetCIDRb([]byte(cidr))
+}
+
+func (tree *TreeOf[T]) GetCIDRb(cidr []byte) (T, bool, error) {
+	var zero T
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return zero, false, err
+	}
+	var found *tnode[T]
+	bits := masklen(mask)
+	n := tree.rootof(key)
+	for d := 0; n != nil; d++ {
+		if n.set {
+			found = n
+		}
+		if d == bits {
+			break
+		}
+		n = n.child(key, d)
+	}
+	if found == nil {
+		return zero, false, nil
+	}
+	return found.value, true, nil
+}
+
+// Len returns number of entries stored in the tree.
+func (tree *TreeOf[T]) Len() int {
+	return tree.count
+}
+
+// Walk calls fn for every prefix stored in the tree in the same order as Tree.Walk.
+// Walk stops and returns the error if fn returns one, except for SkipSubtree and Stop.
+func (tree *TreeOf[T]) Walk(fn func(prefix *net.IPNet, val T) error) error {
+	err := tree.walk(func(key net.IP, bits int, n *tnode[T]) error {
+		return fn(newentry(key, bits, nil).Prefix, n.value)
+	})
+	if err == Stop {
+		return nil
+	}
+	return err
+}
+
+// All returns iterator over all prefixes stored in the tree in the same order as Walk.
+func (tree *TreeOf[T]) All() iter.Seq2[netip.Prefix, T] {
+	return func(yield func(netip.Prefix, T) bool) {
+		tree.walk(func(key net.IP, bits int, n *tnode[T]) error {
+			if !yield(newprefix(key, bits), n.value) {
+				return Stop
+			}
+			return nil
+		})
+	}
+}
+
+func (tree *TreeOf[T]) walk(fn func(key net.IP, bits int, n *tnode[T]) error) error {
+	if err := tree.root.walk(make(net.IP, net.IPv4len), 0, fn); err != nil {
+		return err
+	}
+	return tree.root6.walk(make(net.IP, net.IPv6len), 0, fn)
+}
+
+func (tree *TreeOf[T]) insert(cidr []byte, val T, overwrite bool) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	bits := masklen(mask)
+	n := tree.rootof(key)
+	for d := 0; d < bits; d++ {
+		next := n.child(key, d)
+		if next == nil {
+			next = tree.newnode()
+			next.parent = n
+			if bitset(key, d) {
+				n.right = next
+			} else {
+				n.left = next
+			}
+		}
+		n = next
+	}
+	if n.set && !overwrite {
+		return wrap("add", cidr, ErrNodeBusy)
+	}
+	if !n.set {
+		tree.count++
+	}
+	n.value, n.set = val, true
+	return nil
+}
+
+func (tree *TreeOf[T]) rootof(key net.IP) *tnode[T] {
+	if len(key) == net.IPv4len {
+		return tree.root
+	}
+	return tree.root6
+}
+
+func (tree *TreeOf[T]) newnode() (p *tnode[T]) {
+	if tree.free != nil {
+		p = tree.free
+		tree.free = tree.free.right
+		*p = tnode[T]{}
+		return p
+	}
+
+	ln := len(tree.alloc)
+	if ln == cap(tree.alloc) {
+		// filled one row, make bigger one
+		tree.alloc = make([]tnode[T], ln+200)[:1]
+		ln = 0
+	} else {
+		tree.alloc = tree.alloc[:ln+1]
+	}
+	return &(tree.alloc[ln])
+}
+
+func (n *tnode[T]) child(key net.IP, d int) *tnode[T] {
+	if bitset(key, d) {
+		return n.right
+	}
+	return n.left
+}
+
+func (n *tnode[T]) walk(key net.IP, d int, fn func(key net.IP, bits int, n *tnode[T]) error) error {
+	if n.set {
+		if err := fn(key, d, n); err == SkipSubtree {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if n.left != nil {
+		if err := n.left.walk(key, d+1, fn); err != nil {
+			return err
+		}
+	}
+	if n.right != nil {
+		key[d>>3] |= startbyte >> uint(d&7)
+		err := n.right.walk(key, d+1, fn)
+		key[d>>3] &^= startbyte >> uint(d&7)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
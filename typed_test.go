@@ -0,0 +1,81 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestTreeOf(t *testing.T) {
+	tr := NewTreeOf[uint32](0)
+	if err := tr.AddCIDR("10.0.0.0/8", 0); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("10.1.0.0/16", 65001); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("dead::/16", 65002); err != nil {
+		t.Error(err)
+	}
+	if err := tr.AddCIDR("10.0.0.0/8", 1); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+
+	asn, err := tr.FindCIDR("10.1.2.3")
+	if err != nil {
+		t.Error(err)
+	}
+	if asn != 65001 {
+		t.Errorf("Wrong value, expected 65001, got %d", asn)
+	}
+	// zero value is stored value too
+	asn, ok, err := tr.GetCIDR("10.2.0.1")
+	if err != nil {
+		t.Error(err)
+	}
+	if !ok || asn != 0 {
+		t.Errorf("Wrong value, expected stored 0, got %d, %v", asn, ok)
+	}
+	if _, ok, _ = tr.GetCIDR("11.0.0.1"); ok {
+		t.Error("11.0.0.1 should not be found")
+	}
+	if _, _, err = tr.GetCIDR("10.0.0.256"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+
+	if err := tr.SetCIDR("dead::/16", 65003); err != nil {
+		t.Error(err)
+	}
+	var prefixes []string
+	var sum uint32
+	tr.Walk(func(prefix *net.IPNet, val uint32) error {
+		prefixes = append(prefixes, prefix.String())
+		sum += val
+		return nil
+	})
+	if len(prefixes) != 3 || prefixes[2] != "dead::/16" || sum != 65001+65003 {
+		t.Errorf("Wrong walk, got %v with sum %d", prefixes, sum)
+	}
+	if tr.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", tr.Len())
+	}
+
+	if err := tr.DeleteCIDR("10.1.0.0/16"); err != nil {
+		t.Error(err)
+	}
+	if err := tr.DeleteCIDR("10.1.0.0/16"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	for p, val := range tr.All() {
+		if p.String() == "10.0.0.0/8" && val != 0 {
+			t.Errorf("Wrong value for %s, expected 0, got %d", p, val)
+		}
+	}
+	if tr.Len() != 2 || tr.root.left == nil || tr.root.left.left == nil {
+		t.Errorf("Wrong state after deletion, got %d entries", tr.Len())
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"fmt"
+)
+
+// Validate walks the whole tree and checks its internal invariants: parent and child links agree, subtree sizes are right, no prefix is longer than address,
+// empty nodes are trimmed and no node is both linked into the tree and kept in the free list. Returns error wrapping ErrCorrupt with description of the first problem found.
+// It is meant for debugging, healthy tree never fails it.
+func (tree *Tree) Validate() error {
+	free := make(map[*node]bool)
+	for n := tree.free; n != nil; n = n.right {
+		if free[n] {
+			return fmt.Errorf("%w: free list is looped", ErrCorrupt)
+		}
+		free[n] = true
+	}
+	seen := make(map[*node]bool)
+	for _, root := range []struct {
+		node *node
+		bits int
+	}{{tree.root, 32}, {tree.root6, 128}} {
+		if root.node == nil {
+			return fmt.Errorf("%w: root is missing", ErrCorrupt)
+		}
+		if root.node.parent != nil {
+			return fmt.Errorf("%w: root has parent", ErrCorrupt)
+		}
+		if err := tree.validate(root.node, 0, root.bits, free, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks subtree of n located at depth d, max is the longest prefix allowed.
+func (tree *Tree) validate(n *node, d, max int, free, seen map[*node]bool) error {
+	if seen[n] {
+		return fmt.Errorf("%w: node linked twice at %s", ErrCorrupt, tree.prefixof(n))
+	}
+	seen[n] = true
+	if free[n] {
+		return fmt.Errorf("%w: free node linked at %s", ErrCorrupt, tree.prefixof(n))
+	}
+	if d > max {
+		return fmt.Errorf("%w: prefix longer than %d bits at %s", ErrCorrupt, max, tree.prefixof(n))
+	}
+	if n.parent != nil && n.value == nil && n.left == nil && n.right == nil {
+		return fmt.Errorf("%w: empty leaf at %s", ErrCorrupt, tree.prefixof(n))
+	}
+	var size int32
+	if n.value != nil {
+		size++
+	}
+	for _, child := range []*node{n.left, n.right} {
+		if child == nil {
+			continue
+		}
+		if child.parent != n {
+			return fmt.Errorf("%w: wrong parent link below %s", ErrCorrupt, tree.prefixof(n))
+		}
+		if err := tree.validate(child, d+1, max, free, seen); err != nil {
+			return err
+		}
+		size += child.size
+	}
+	if n.size != size {
+		return fmt.Errorf("%w: size %d instead of %d at %s", ErrCorrupt, n.size, size, tree.prefixof(n))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tr := NewTree(0)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		v4 := fmt.Sprintf("10.%d.%d.0/%d", r.Intn(4), r.Intn(256), 16+r.Intn(9))
+		v6 := fmt.Sprintf("dead:%x::/%d", r.Intn(64), 20+r.Intn(13))
+		switch r.Intn(4) {
+		case 0:
+			tr.DeleteCIDR(v4)
+			tr.DeleteWholeRangeCIDR(v6)
+		case 1:
+			tr.DeleteWholeRangeCIDR(v4)
+		default:
+			tr.AddCIDR(v4, i)
+			tr.SetCIDR(v6, i)
+		}
+		if err := tr.Validate(); err != nil {
+			t.Fatalf("Validate failed after %d operations: %v", i, err)
+		}
+	}
+
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.lookup(net.IP{10, 0, 0, 0}, 8).size++
+	if err := tr.Validate(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Should have gotten ErrCorrupt, instead got err: %v", err)
+	}
+	tr.lookup(net.IP{10, 0, 0, 0}, 8).size--
+	if err := tr.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	node := tr.lookup(net.IP{10, 0, 0, 0}, 8)
+	node.parent = tr.root
+	if err := tr.Validate(); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("Should have gotten ErrCorrupt, instead got err: %v", err)
+	}
+}
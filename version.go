@@ -0,0 +1,89 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"strconv"
+)
+
+// DefaultCheckpoints is number of checkpoints kept by the tree unless SetMaxCheckpoints was called.
+const DefaultCheckpoints = 8
+
+type versions struct {
+	last  int
+	max   int
+	saved []version
+}
+
+type version struct {
+	id   int
+	tree *Tree
+}
+
+// Checkpoint saves copy of current tree contents and returns its id for Rollback. Only the newest checkpoints are kept, see SetMaxCheckpoints.
+// Saving copies all nodes (values are shared), so it costs the same as Clone.
+func (tree *Tree) Checkpoint() int {
+	v := tree.history()
+	v.last++
+	v.saved = append(v.saved, version{id: v.last, tree: tree.Clone()})
+	v.trim()
+	return v.last
+}
+
+// Rollback restores tree contents saved by Checkpoint with given id, checkpoints made after it are dropped. The checkpoint itself is kept, so it could be restored again.
+// Returns ErrNotFound if checkpoint is unknown or was already dropped. Hooks are not called for entries changed by rollback.
+func (tree *Tree) Rollback(id int) error {
+	v := tree.versions
+	if v == nil {
+		return wrap("rollback", strconv.AppendInt(nil, int64(id), 10), ErrNotFound)
+	}
+	for i, saved := range v.saved {
+		if saved.id != id {
+			continue
+		}
+		tree.adopt(saved.tree.Clone())
+		v.saved = v.saved[:i+1]
+		return nil
+	}
+	return wrap("rollback", strconv.AppendInt(nil, int64(id), 10), ErrNotFound)
+}
+
+// SetMaxCheckpoints sets number of checkpoints kept by the tree, oldest ones are dropped first. Zero restores DefaultCheckpoints.
+func (tree *Tree) SetMaxCheckpoints(max int) {
+	v := tree.history()
+	v.max = max
+	v.trim()
+}
+
+// Checkpoints returns ids of checkpoints which could be restored, from the oldest to the newest.
+func (tree *Tree) Checkpoints() []int {
+	if tree.versions == nil {
+		return nil
+	}
+	ids := make([]int, len(tree.versions.saved))
+	for i, saved := range tree.versions.saved {
+		ids[i] = saved.id
+	}
+	return ids
+}
+
+func (tree *Tree) history() *versions {
+	if tree.versions == nil {
+		tree.versions = &versions{}
+	}
+	return tree.versions
+}
+
+// trim drops oldest checkpoints above the limit.
+func (v *versions) trim() {
+	max := v.max
+	if max <= 0 {
+		max = DefaultCheckpoints
+	}
+	if extra := len(v.saved) - max; extra > 0 {
+		clear(v.saved[:extra])
+		v.saved = v.saved[extra:]
+	}
+}
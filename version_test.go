@@ -0,0 +1,62 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRollback(t *testing.T) {
+	tr := NewTree(0)
+	if err := tr.Rollback(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("dead::/16", 2)
+	good := tr.Checkpoint()
+
+	tr.DeleteCIDR("10.0.0.0/8")
+	tr.SetCIDR("dead::/16", "bad")
+	tr.AddCIDR("10.1.0.0/16", "bad")
+	bad := tr.Checkpoint()
+
+	if err := tr.Rollback(good); err != nil {
+		t.Error(err)
+	}
+	for ip, val := range map[string]interface{}{"10.1.2.3": 1, "dead::1": 2} {
+		if inf, _ := tr.FindCIDR(ip); inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+	if tr.Len() != 2 {
+		t.Errorf("Wrong length, expected 2, got %d", tr.Len())
+	}
+	if err := tr.Rollback(bad); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Checkpoints after rollback should be dropped, instead got err: %v", err)
+	}
+
+	// checkpoint stays intact after rollback and following changes
+	tr.SetCIDR("10.0.0.0/8", 3)
+	tr.Compact()
+	if err := tr.Rollback(good); err != nil {
+		t.Error(err)
+	}
+	if inf, _ := tr.FindCIDR("10.1.2.3"); inf != 1 {
+		t.Errorf("Wrong value, expected 1, got %v", inf)
+	}
+
+	tr.SetMaxCheckpoints(2)
+	for i := 0; i < 3; i++ {
+		tr.Checkpoint()
+	}
+	if ids := fmt.Sprint(tr.Checkpoints()); ids != "[4 5]" {
+		t.Errorf("Wrong checkpoints, expected [4 5], got %s", ids)
+	}
+	if err := tr.Rollback(good); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+}
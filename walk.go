@@ -0,0 +1,222 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net"
+	"net/netip"
+)
+
+var (
+	// SkipSubtree can be returned by walk callback to skip all prefixes under the current one.
+	SkipSubtree = errors.New("Skip Subtree")
+	// Stop can be returned by walk callback to stop walking without an error.
+	Stop = errors.New("Stop Walk")
+)
+
+// Walk calls fn for every prefix stored in the tree in depth-first order, IPv4 prefixes first, lower addresses and shorter prefixes first.
+// Walk stops and returns the error if fn returns one, except for SkipSubtree and Stop.
+func (tree *Tree) Walk(fn func(prefix *net.IPNet, val interface{}) error) error {
+	err := tree.walk(func(key net.IP, bits int, n *node) error {
+		e := newentry(key, bits, n.value)
+		return fn(e.Prefix, e.Value)
+	})
+	if err == Stop {
+		return nil
+	}
+	return err
+}
+
+// All returns iterator over all prefixes stored in the tree in the same order as Walk.
+func (tree *Tree) All() iter.Seq2[netip.Prefix, interface{}] {
+	return func(yield func(netip.Prefix, interface{}) bool) {
+		tree.walk(func(key net.IP, bits int, n *node) error {
+			if !yield(newprefix(key, bits), n.value) {
+				return Stop
+			}
+			return nil
+		})
+	}
+}
+
+// SnapshotAll records all prefixes stored in the tree at the moment of the call and returns iterator over that record.
+// Only the call itself needs protection from concurrent modifications, returned iterator could be consumed while tree changes.
+func (tree *Tree) SnapshotAll() iter.Seq2[netip.Prefix, interface{}] {
+	var (
+		prefixes []netip.Prefix
+		values   []interface{}
+	)
+	tree.walk(func(key net.IP, bits int, n *node) error {
+		prefixes = append(prefixes, newprefix(key, bits))
+		values = append(values, n.value)
+		return nil
+	})
+	return func(yield func(netip.Prefix, interface{}) bool) {
+		for i, p := range prefixes {
+			if !yield(p, values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Sorted returns iterator over all prefixes stored in the tree ordered by network address and then by prefix length, all IPv4 prefixes come before IPv6 ones.
+// It is the same order as Walk uses since families are kept in separate subtrees.
+func (tree *Tree) Sorted() iter.Seq2[netip.Prefix, interface{}] {
+	return tree.All()
+}
+
+// Stream sends all entries stored in the tree to returned channel in the same order as Walk, channel is closed when walk is done or ctx is cancelled.
+// Tree must not be modified until channel is closed.
+func (tree *Tree) Stream(ctx context.Context) <-chan Entry {
+	ch := make(chan Entry)
+	go func() {
+		defer close(ch)
+		tree.walk(func(key net.IP, bits int, n *node) error {
+			select {
+			case ch <- newentry(key, bits, n.value):
+				return nil
+			case <-ctx.Done():
+				return Stop
+			}
+		})
+	}()
+	return ch
+}
+
+// ListCIDRs returns all prefixes stored in the tree as CIDR strings in the same order as Walk.
+func (tree *Tree) ListCIDRs() []string {
+	var cidrs []string
+	for p := range tree.All() {
+		cidrs = append(cidrs, p.String())
+	}
+	return cidrs
+}
+
+// ListPrefixes returns all prefixes stored in the tree in the same order as Walk.
+func (tree *Tree) ListPrefixes() []netip.Prefix {
+	var prefixes []netip.Prefix
+	for p := range tree.All() {
+		prefixes = append(prefixes, p)
+	}
+	return prefixes
+}
+
+// Keys returns all prefixes stored in the tree, it is the same as ListPrefixes.
+func (tree *Tree) Keys() []netip.Prefix {
+	return tree.ListPrefixes()
+}
+
+// Values returns all values stored in the tree in the same order as Keys.
+func (tree *Tree) Values() []interface{} {
+	var values []interface{}
+	for _, v := range tree.All() {
+		values = append(values, v)
+	}
+	return values
+}
+
+// WalkUnderCIDR calls fn for every stored prefix inside IP/mask (including the exact one) in depth-first order.
+// Walk stops and returns the error if fn returns one, except for SkipSubtree and Stop.
+func (tree *Tree) WalkUnderCIDR(cidr string, fn func(prefix *net.IPNet, val interface{}) error) error {
+	return tree.WalkUnderCIDRb([]byte(cidr), fn)
+}
+
+func (tree *Tree) WalkUnderCIDRb(cidr []byte, fn func(prefix *net.IPNet, val interface{}) error) error {
+	key, mask, err := parsecidr(cidr)
+	if err != nil {
+		return err
+	}
+	bits := masklen(mask)
+	start := tree.lookup(key, bits)
+	if start == nil {
+		return nil
+	}
+	key = key.Mask(mask)
+	err = walk(start, key, bits, func(key net.IP, bits int, n *node) error {
+		e := newentry(key, bits, n.value)
+		return fn(e.Prefix, e.Value)
+	})
+	if err == Stop {
+		return nil
+	}
+	return err
+}
+
+// DescendantsCIDR returns all stored prefixes inside IP/mask (including the exact one).
+func (tree *Tree) DescendantsCIDR(cidr string) ([]Entry, error) {
+	return tree.DescendantsCIDRb([]byte(cidr))
+}
+
+func (tree *Tree) DescendantsCIDRb(cidr []byte) ([]Entry, error) {
+	var entries []Entry
+	err := tree.WalkUnderCIDRb(cidr, func(prefix *net.IPNet, val interface{}) error {
+		bits, _ := prefix.Mask.Size()
+		entries = append(entries, Entry{Prefix: prefix, Bits: bits, Value: val})
+		return nil
+	})
+	return entries, err
+}
+
+// walk visits every node holding a value in the tree, passing IPv4 key for values stored by IPv4 CIDRs.
+func (tree *Tree) walk(fn func(key net.IP, bits int, n *node) error) error {
+	if err := walk(tree.root, make(net.IP, net.IPv4len), 0, fn); err != nil {
+		return err
+	}
+	return walk(tree.root6, make(net.IP, net.IPv6len), 0, fn)
+}
+
+// walklive is walk which visits exception entries too, for callers which keep them.
+func (tree *Tree) walklive(fn func(key net.IP, bits int, n *node) error) error {
+	if err := walkif(tree.root, make(net.IP, net.IPv4len), 0, (*node).live, fn); err != nil {
+		return err
+	}
+	return walkif(tree.root6, make(net.IP, net.IPv6len), 0, (*node).live, fn)
+}
+
+// walk visits every node holding a value under n (n included) in depth-first order, lower addresses first.
+// Key keeps path to n and is modified in place while descending, d is depth of n.
+// Nodes under the one for which fn returned SkipSubtree are not visited.
+func walk(n *node, key net.IP, d int, fn func(key net.IP, bits int, n *node) error) error {
+	return walkif(n, key, d, (*node).held, fn)
+}
+
+// walkif is walk visiting nodes for which visit returns true.
+func walkif(n *node, key net.IP, d int, visit func(n *node) bool, fn func(key net.IP, bits int, n *node) error) error {
+	if visit(n) {
+		if err := fn(key, d, n); err == SkipSubtree {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if d == len(key)*8 {
+		return nil
+	}
+	bit := startbyte >> uint(d&7)
+	if n.left != nil {
+		if err := walkif(n.left, key, d+1, visit, fn); err != nil {
+			return err
+		}
+	}
+	if n.right != nil {
+		key[d>>3] |= bit
+		err := walkif(n.right, key, d+1, visit, fn)
+		key[d>>3] &^= bit
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newprefix converts walked key and its depth into netip.Prefix.
+func newprefix(key net.IP, bits int) netip.Prefix {
+	addr, _ := netip.AddrFromSlice(key)
+	return netip.PrefixFrom(addr, bits)
+}
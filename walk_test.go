@@ -0,0 +1,325 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestWalkUnder(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.2.0/24", 3)
+	tr.AddCIDR("10.1.128.0/24", 4)
+	tr.AddCIDR("10.2.0.0/16", 5)
+
+	entries, err := tr.DescendantsCIDR("10.1.0.0/16")
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"10.1.0.0/16", "10.1.2.0/24", "10.1.128.0/24"}
+	if len(entries) != len(expected) {
+		t.Fatalf("Wrong number of entries, expected %d, got %d", len(expected), len(entries))
+	}
+	for i, e := range entries {
+		if e.Prefix.String() != expected[i] || e.Value.(int) != i+2 {
+			t.Errorf("Wrong entry, expected %s => %d, got %s => %v", expected[i], i+2, e.Prefix, e.Value)
+		}
+	}
+
+	// host bits in query are ignored
+	entries, err = tr.DescendantsCIDR("10.1.77.1/16")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Wrong number of entries, expected 3, got %d", len(entries))
+	}
+
+	entries, err = tr.DescendantsCIDR("11.0.0.0/8")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no entries, got %v", entries)
+	}
+
+	stop := errors.New("stop")
+	var n int
+	err = tr.WalkUnderCIDR("10.0.0.0/8", func(prefix *net.IPNet, val interface{}) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Errorf("Walk should have stopped on first entry, got %v after %d", err, n)
+	}
+
+	tr.AddCIDR("dead::/16", 6)
+	tr.AddCIDR("dead:beef::/32", 7)
+	entries, err = tr.DescendantsCIDR("dead::/16")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(entries) != 2 || entries[1].Prefix.String() != "dead:beef::/32" {
+		t.Errorf("Wrong entries, got %v", entries)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 4)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("192.168.0.0/16", 3)
+
+	var prefixes []string
+	var values []int
+	err := tr.Walk(func(prefix *net.IPNet, val interface{}) error {
+		prefixes = append(prefixes, prefix.String())
+		values = append(values, val.(int))
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "192.168.0.0/16", "dead::/16"}
+	if len(prefixes) != len(expected) {
+		t.Fatalf("Wrong prefixes, expected %v, got %v", expected, prefixes)
+	}
+	for i := range expected {
+		if prefixes[i] != expected[i] || values[i] != i+1 {
+			t.Errorf("Wrong entry, expected %s => %d, got %s => %d", expected[i], i+1, prefixes[i], values[i])
+		}
+	}
+
+	stop := errors.New("stop")
+	err = tr.Walk(func(prefix *net.IPNet, val interface{}) error {
+		if val.(int) == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Should have gotten stop error, instead got err: %v", err)
+	}
+
+	err = NewTree(0).Walk(func(prefix *net.IPNet, val interface{}) error {
+		t.Error("Empty tree should not have entries")
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAll(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	var prefixes []string
+	for p, v := range tr.All() {
+		prefixes = append(prefixes, p.String())
+		if v.(int) != len(prefixes) {
+			t.Errorf("Wrong value for %s, expected %d, got %v", p, len(prefixes), v)
+		}
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "dead::/16"}
+	if len(prefixes) != len(expected) {
+		t.Fatalf("Wrong prefixes, expected %v, got %v", expected, prefixes)
+	}
+	for i := range expected {
+		if prefixes[i] != expected[i] {
+			t.Errorf("Wrong prefix, expected %s, got %s", expected[i], prefixes[i])
+		}
+	}
+
+	var n int
+	for range tr.All() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Iteration should have stopped after break, got %d", n)
+	}
+}
+
+func TestList(t *testing.T) {
+	tr := NewTree(0)
+	if cidrs := tr.ListCIDRs(); len(cidrs) != 0 {
+		t.Errorf("Empty tree should have no CIDRs, got %v", cidrs)
+	}
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.2.3", 2)
+	tr.AddCIDR("2001:db8::/32", 3)
+
+	expected := []string{"10.0.0.0/8", "10.1.2.3/32", "2001:db8::/32"}
+	cidrs := tr.ListCIDRs()
+	prefixes := tr.ListPrefixes()
+	if len(cidrs) != len(expected) || len(prefixes) != len(expected) {
+		t.Fatalf("Wrong CIDRs, expected %v, got %v and %v", expected, cidrs, prefixes)
+	}
+	for i := range expected {
+		if cidrs[i] != expected[i] || prefixes[i].String() != expected[i] {
+			t.Errorf("Wrong CIDR, expected %s, got %s and %s", expected[i], cidrs[i], prefixes[i])
+		}
+	}
+}
+
+func TestSorted(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("dead::/16", 1)
+	tr.AddCIDR("192.168.0.0/16", 2)
+	tr.AddCIDR("2001:db8::/32", 3)
+	tr.AddCIDR("10.0.0.0/8", 4)
+	tr.AddCIDR("10.0.0.0/16", 5)
+
+	var prefixes []string
+	for p := range tr.Sorted() {
+		prefixes = append(prefixes, p.String())
+	}
+	expected := []string{"10.0.0.0/8", "10.0.0.0/16", "192.168.0.0/16", "2001:db8::/32", "dead::/16"}
+	if len(prefixes) != len(expected) {
+		t.Fatalf("Wrong prefixes, expected %v, got %v", expected, prefixes)
+	}
+	for i := range expected {
+		if prefixes[i] != expected[i] {
+			t.Errorf("Wrong prefix, expected %s, got %s", expected[i], prefixes[i])
+		}
+	}
+
+	var n int
+	for range tr.Sorted() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Iteration should have stopped after break, got %d", n)
+	}
+}
+
+func TestWalkSkip(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.1.1.0/24", 3)
+	tr.AddCIDR("10.2.0.0/16", 4)
+	tr.AddCIDR("11.0.0.0/8", 5)
+
+	var prefixes []string
+	err := tr.Walk(func(prefix *net.IPNet, val interface{}) error {
+		prefixes = append(prefixes, prefix.String())
+		if val.(int) == 2 {
+			return SkipSubtree
+		}
+		if val.(int) == 4 {
+			return Stop
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16"}
+	if len(prefixes) != len(expected) {
+		t.Fatalf("Wrong prefixes, expected %v, got %v", expected, prefixes)
+	}
+	for i := range expected {
+		if prefixes[i] != expected[i] {
+			t.Errorf("Wrong prefix, expected %s, got %s", expected[i], prefixes[i])
+		}
+	}
+
+	var n int
+	err = tr.WalkUnderCIDR("10.0.0.0/8", func(prefix *net.IPNet, val interface{}) error {
+		n++
+		return SkipSubtree
+	})
+	if err != nil {
+		t.Error(err)
+	}
+	if n != 1 {
+		t.Errorf("Only the first prefix should be visited, got %d", n)
+	}
+}
+
+func TestStream(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	var entries []Entry
+	for e := range tr.Stream(context.Background()) {
+		entries = append(entries, e)
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "dead::/16"}
+	if len(entries) != len(expected) {
+		t.Fatalf("Wrong entries, expected %v, got %v", expected, entries)
+	}
+	for i, e := range entries {
+		if e.Prefix.String() != expected[i] || e.Value.(int) != i+1 {
+			t.Errorf("Wrong entry, expected %s => %d, got %s => %v", expected[i], i+1, e.Prefix, e.Value)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := tr.Stream(ctx)
+	<-ch
+	cancel()
+	for range ch {
+		// at most one more entry could have been sent before cancellation was noticed
+	}
+}
+
+func TestSnapshotAll(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("10.2.0.0/16", 3)
+
+	var prefixes []string
+	for p, v := range tr.SnapshotAll() {
+		prefixes = append(prefixes, p.String())
+		if v.(int) == 1 {
+			// modifications during iteration do not affect it
+			tr.DeleteWholeRangeCIDR("10.0.0.0/8")
+			tr.AddCIDR("11.0.0.0/8", 4)
+		}
+	}
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "10.2.0.0/16"}
+	if len(prefixes) != len(expected) {
+		t.Fatalf("Wrong prefixes, expected %v, got %v", expected, prefixes)
+	}
+	for i := range expected {
+		if prefixes[i] != expected[i] {
+			t.Errorf("Wrong prefix, expected %s, got %s", expected[i], prefixes[i])
+		}
+	}
+}
+
+func TestKeysValues(t *testing.T) {
+	tr := NewTree(0)
+	tr.AddCIDR("10.0.0.0/8", 1)
+	tr.AddCIDR("10.1.0.0/16", 2)
+	tr.AddCIDR("dead::/16", 3)
+
+	keys := tr.Keys()
+	values := tr.Values()
+	expected := []string{"10.0.0.0/8", "10.1.0.0/16", "dead::/16"}
+	if len(keys) != len(expected) || len(values) != len(expected) {
+		t.Fatalf("Wrong keys or values, got %v and %v", keys, values)
+	}
+	for i := range expected {
+		if keys[i].String() != expected[i] || values[i].(int) != i+1 {
+			t.Errorf("Wrong entry, expected %s => %d, got %s => %v", expected[i], i+1, keys[i], values[i])
+		}
+	}
+}
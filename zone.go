@@ -0,0 +1,119 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"bytes"
+)
+
+// ZonedTree is radix tree keeping IPv6 entries with zone ("fe80::%eth0/64") separately per zone, for link-local ACLs.
+// Lookups with zone check entries of that zone first and fall back to entries added without zone. Plain Tree ignores zones.
+type ZonedTree struct {
+	tree  *Tree
+	zones map[string]*Tree
+}
+
+// NewZonedTree creates ZonedTree, preallocate has the same meaning as for NewTree.
+func NewZonedTree(preallocate int) *ZonedTree {
+	return &ZonedTree{tree: NewTree(preallocate), zones: make(map[string]*Tree)}
+}
+
+// AddCIDR adds value associated with IP/mask (and zone if present) to the tree. Will return error for invalid CIDR or if value already exists.
+func (zt *ZonedTree) AddCIDR(cidr string, val interface{}) error {
+	return zt.AddCIDRb([]byte(cidr), val)
+}
+
+func (zt *ZonedTree) AddCIDRb(cidr []byte, val interface{}) error {
+	tree := zt.zonetree(cidr, true)
+	err := tree.AddCIDRb(cidr, val)
+	zt.release(tree, cidr)
+	return err
+}
+
+// SetCIDR sets value associated with IP/mask (and zone if present) in the tree, overwriting existing one.
+func (zt *ZonedTree) SetCIDR(cidr string, val interface{}) error {
+	return zt.SetCIDRb([]byte(cidr), val)
+}
+
+func (zt *ZonedTree) SetCIDRb(cidr []byte, val interface{}) error {
+	tree := zt.zonetree(cidr, true)
+	err := tree.SetCIDRb(cidr, val)
+	zt.release(tree, cidr)
+	return err
+}
+
+// DeleteCIDR removes value associated with IP/mask (and zone if present) from the tree.
+func (zt *ZonedTree) DeleteCIDR(cidr string) error {
+	return zt.DeleteCIDRb([]byte(cidr))
+}
+
+func (zt *ZonedTree) DeleteCIDRb(cidr []byte) error {
+	tree := zt.zonetree(cidr, false)
+	if tree == nil {
+		if _, _, _, err := parsekey6(cidr); err != nil {
+			return err
+		}
+		return wrap("delete", cidr, ErrNotFound)
+	}
+	err := tree.DeleteCIDRb(cidr)
+	zt.release(tree, cidr)
+	return err
+}
+
+// FindCIDR traverses entries of zone given in IP/mask and then entries without zone, returning information saved in longest covering prefix of the first one that has any.
+func (zt *ZonedTree) FindCIDR(cidr string) (interface{}, error) {
+	return zt.FindCIDRb([]byte(cidr))
+}
+
+func (zt *ZonedTree) FindCIDRb(cidr []byte) (interface{}, error) {
+	if tree := zt.zonetree(cidr, false); tree != nil && tree != zt.tree {
+		val, err := tree.FindCIDRb(cidr)
+		if err != nil || val != nil {
+			return val, err
+		}
+	}
+	return zt.tree.FindCIDRb(cidr)
+}
+
+// Len returns number of entries stored in the tree across all zones.
+func (zt *ZonedTree) Len() int {
+	n := zt.tree.Len()
+	for _, tree := range zt.zones {
+		n += tree.Len()
+	}
+	return n
+}
+
+// zonetree returns tree holding entries of zone given in cidr, creating it if asked to. Returns nil for unknown zone otherwise.
+func (zt *ZonedTree) zonetree(cidr []byte, create bool) *Tree {
+	zone := zoneof(cidr)
+	if len(zone) == 0 {
+		return zt.tree
+	}
+	tree := zt.zones[string(zone)]
+	if tree == nil && create {
+		tree = NewTree(0)
+		zt.zones[string(zone)] = tree
+	}
+	return tree
+}
+
+// release drops tree of zone given in cidr once it holds no entries.
+func (zt *ZonedTree) release(tree *Tree, cidr []byte) {
+	if tree != zt.tree && tree.Len() == 0 {
+		delete(zt.zones, string(zoneof(cidr)))
+	}
+}
+
+// zoneof returns zone part of IPv6 cidr (between '%' and '/' or end) or nil if there is none.
+func zoneof(cidr []byte) []byte {
+	if p := bytes.IndexByte(cidr, '/'); p >= 0 {
+		cidr = cidr[:p]
+	}
+	if p := bytes.IndexByte(cidr, '%'); p >= 0 {
+		return cidr[p+1:]
+	}
+	return nil
+}
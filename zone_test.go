@@ -0,0 +1,80 @@
+// Copyright (C) 2015 Alex Sergeyev
+// This project is licensed under the terms of the MIT license.
+// Read LICENSE file for information for all notices and permissions.
+
+package nradix
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestZoneStripped(t *testing.T) {
+	tr := NewTree(0)
+	if err := tr.AddCIDR("fe80::%eth0/64", 1); err != nil {
+		t.Error(err)
+	}
+	for _, ip := range []string{"fe80::1%eth0", "fe80::1%eth1", "fe80::1%eth0.100", "fe80::1"} {
+		inf, err := tr.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != 1 {
+			t.Errorf("Wrong value for %s, expected 1, got %v", ip, inf)
+		}
+	}
+	for _, ip := range []string{"fe80::1%", "fe80::1%/64", "10.0.0.1%eth0"} {
+		if _, err := tr.FindCIDR(ip); !errors.Is(err, ErrBadIP) {
+			t.Errorf("Should have gotten ErrBadIP for %q, instead got err: %v", ip, err)
+		}
+	}
+}
+
+func TestZonedTree(t *testing.T) {
+	zt := NewZonedTree(0)
+	if err := zt.AddCIDR("fe80::/10", "any"); err != nil {
+		t.Error(err)
+	}
+	if err := zt.AddCIDR("fe80::%eth0/64", "eth0"); err != nil {
+		t.Error(err)
+	}
+	if err := zt.AddCIDR("fe80::1%eth1.100", "vlan"); err != nil {
+		t.Error(err)
+	}
+	if err := zt.AddCIDR("fe80::%eth0/64", "again"); !errors.Is(err, ErrNodeBusy) {
+		t.Errorf("Should have gotten ErrNodeBusy, instead got err: %v", err)
+	}
+	if err := zt.AddCIDR("fe80::zz%eth2", "bad"); !errors.Is(err, ErrBadIP) {
+		t.Errorf("Should have gotten ErrBadIP, instead got err: %v", err)
+	}
+	if len(zt.zones) != 2 {
+		t.Errorf("Wrong number of zones, expected 2, got %d", len(zt.zones))
+	}
+
+	for ip, val := range map[string]interface{}{"fe80::1%eth0": "eth0", "fe80::1%eth1.100": "vlan", "fe80::2%eth1.100": "any",
+		"fe80::1%eth2": "any", "fe80::1": "any", "dead::1%eth0": nil} {
+		inf, err := zt.FindCIDR(ip)
+		if err != nil {
+			t.Error(err)
+		}
+		if inf != val {
+			t.Errorf("Wrong value for %s, expected %v, got %v", ip, val, inf)
+		}
+	}
+
+	if zt.Len() != 3 {
+		t.Errorf("Wrong length, expected 3, got %d", zt.Len())
+	}
+	if err := zt.DeleteCIDR("fe80::%eth0/64"); err != nil {
+		t.Error(err)
+	}
+	if err := zt.DeleteCIDR("fe80::%eth0/64"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Should have gotten ErrNotFound, instead got err: %v", err)
+	}
+	if len(zt.zones) != 1 {
+		t.Errorf("Wrong number of zones, expected 1, got %d", len(zt.zones))
+	}
+	if inf, _ := zt.FindCIDR("fe80::1%eth0"); inf != "any" {
+		t.Errorf("Wrong value, expected any, got %v", inf)
+	}
+}